@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/internal/renew"
+	"github.com/lukegriffith/SSHTrust/internal/sshagent"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+var agentCmd = &cobra.Command{
+	Use:   "agent",
+	Short: "Keep the profile's identities signed, renewing certificates before they expire",
+	Long: `Keep the profile's identities signed, renewing certificates before they expire.
+
+Identities are configured on the profile in the config file:
+
+  profiles:
+      prod:
+          server: https://sshtrust.example.com
+          default_ca: prodca
+          identities:
+              - path: ~/.ssh/id_ed25519
+                principals: [alice]
+                ttl_minutes: 60
+                add_to_agent: true`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fraction, _ := cmd.Flags().GetFloat64("renew-at")
+		maxBackoff, _ := cmd.Flags().GetDuration("max-backoff")
+		if fraction <= 0 || fraction >= 1 {
+			log.Fatal("--renew-at must be between 0 and 1")
+		}
+
+		profileName, profile := client.ActiveProfile()
+		if len(profile.Identities) == 0 {
+			log.Fatalf("Profile '%s' has no identities configured", profileName)
+		}
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+
+		logger := log.New(os.Stderr, "sshtrust agent: ", log.LstdFlags)
+		renewer := renew.New(logger)
+		renewer.Fraction = fraction
+		renewer.MaxBackoff = maxBackoff
+
+		targets := []renew.Target{}
+		for _, id := range profile.Identities {
+			target, err := identityTarget(apiClient, profile, id)
+			if err != nil {
+				log.Fatalf("Error configuring identity %s: %v", id.Path, err)
+			}
+			targets = append(targets, target)
+		}
+
+		ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		var wg sync.WaitGroup
+		for _, target := range targets {
+			wg.Add(1)
+			go func(target renew.Target) {
+				defer wg.Done()
+				_ = renewer.Run(ctx, target)
+			}(target)
+		}
+		wg.Wait()
+		logger.Println("stopped")
+	},
+}
+
+// identityTarget builds the renewal target for a configured identity
+func identityTarget(apiClient *sshtrust.Client, profile client.Profile, id client.Identity) (renew.Target, error) {
+	path, err := identity.Expand(id.Path)
+	if err != nil {
+		return renew.Target{}, err
+	}
+	caID := id.CA
+	if caID == "" {
+		caID = profile.DefaultCA
+	}
+	if caID == "" {
+		return renew.Target{}, errors.New("no CA configured and the profile has no default CA")
+	}
+	if len(id.Principals) == 0 {
+		return renew.Target{}, errors.New("no principals configured")
+	}
+	ttl := id.TTLMinutes
+	if ttl == 0 {
+		ttl = 60
+	}
+
+	// Load the private key once up front, the daemon can not prompt for a
+	// passphrase on every renewal.
+	var privateKey interface{}
+	if id.AddToAgent {
+		privateKey, err = identity.LoadPrivateKey(path, readPassphrase(path))
+		if err != nil {
+			return renew.Target{}, err
+		}
+	}
+
+	var current *ssh.Certificate
+	return renew.Target{
+		Name: path,
+		Current: func() (*ssh.Certificate, error) {
+			c, err := identity.ReadCertificate(path)
+			if err != nil {
+				return nil, err
+			}
+			// Only keep a certificate ssh would use, as the ssh command does
+			if err := checkCertificate(context.Background(), apiClient, caID, path, c, id.Principals, 0); err != nil {
+				return nil, err
+			}
+			current = c
+			// Make sure a certificate from a previous run is in the agent
+			if privateKey != nil {
+				if err := refreshAgent(privateKey, nil, c); err != nil {
+					return nil, err
+				}
+			}
+			return c, nil
+		},
+		Renew: func(ctx context.Context) (*ssh.Certificate, error) {
+			publicKey, err := identity.ReadPublicKey(path)
+			if err != nil {
+				return nil, err
+			}
+			signed, err := apiClient.Sign(ctx, caID, cert.SignRequest{
+				PublicKey:  publicKey,
+				Principals: id.Principals,
+				TTLMinutes: ttl,
+			})
+			if err != nil {
+				return nil, err
+			}
+			c, err := identity.ParseCertificate([]byte(signed.SignedKey))
+			if err != nil {
+				return nil, err
+			}
+			if err := identity.WriteCertificate(path, []byte(signed.SignedKey)); err != nil {
+				return nil, err
+			}
+			if privateKey != nil {
+				if err := refreshAgent(privateKey, current, c); err != nil {
+					return nil, err
+				}
+			}
+			current = c
+			return c, nil
+		},
+	}, nil
+}
+
+// refreshAgent replaces the previous certificate in ssh-agent with the new one
+func refreshAgent(privateKey interface{}, previous, next *ssh.Certificate) error {
+	sshAgent, conn, err := sshagent.Connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if previous != nil {
+		// The previous certificate may already have expired out of the agent
+		_ = sshAgent.Remove(previous)
+	}
+	if err := sshagent.AddCertificate(sshAgent, privateKey, next, time.Now()); err != nil {
+		return fmt.Errorf("could not refresh ssh-agent: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	agentCmd.Flags().Float64("renew-at", renew.DefaultFraction, "Fraction of the certificate lifetime after which it is renewed")
+	agentCmd.Flags().Duration("max-backoff", renew.DefaultMaxBackoff, "Longest wait between retries when the server is unavailable")
+	rootCmd.AddCommand(agentCmd)
+}
@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Work with the server's audit log",
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/spf13/cobra"
+)
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify <file>",
+	Short: "Check an audit log's hash chain has not been edited or had events removed",
+	Long: `Check every event in an audit log links to the one before it, using the
+server's audit key. Events cut from the end of the log can only be noticed by
+comparing the last hash with one recorded earlier, so keep the printed hash
+somewhere safe.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		keyFile, _ := cmd.Flags().GetString("key-file")
+		key, err := readTokenFile(keyFile)
+		if err != nil {
+			log.Fatalf("Error reading audit key: %v", err)
+		}
+		file, err := os.Open(args[0])
+		if err != nil {
+			log.Fatalf("Error opening audit log: %v", err)
+		}
+		defer file.Close()
+		count, last, err := audit.Verify(file, []byte(key))
+		if err != nil {
+			log.Fatalf("Audit log failed verification after %d events: %v", count, err)
+		}
+		fmt.Printf("%d events verified, last hash %s\n", count, last)
+	},
+}
+
+func init() {
+	auditVerifyCmd.Flags().String("key-file", "", "File holding the server's audit key")
+	_ = auditVerifyCmd.MarkFlagRequired("key-file")
+	auditCmd.AddCommand(auditVerifyCmd)
+}
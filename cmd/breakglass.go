@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var breakGlassCmd = &cobra.Command{
+	Use:   "breakglass",
+	Short: "Issue emergency certificates without approval, and review them after the incident",
+}
+
+func init() {
+	rootCmd.AddCommand(breakGlassCmd)
+}
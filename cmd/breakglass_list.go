@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var breakGlassListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List break-glass certificates for post-incident review",
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		sinceFlag, _ := cmd.Flags().GetDuration("since")
+
+		var since time.Time
+		if sinceFlag > 0 {
+			since = time.Now().Add(-sinceFlag)
+		}
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		issued, err := apiClient.ListBreakGlass(cmd.Context(), caID, since)
+		if err != nil {
+			log.Fatalf("Error retrieving break-glass certificates: %v", err)
+		}
+		if len(issued) == 0 {
+			fmt.Println("No break-glass certificates.")
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Issued", "CA", "Requester", "Principals", "TTL", "Ticket", "Reason", "Serial"})
+		for _, request := range issued {
+			table.Append([]string{
+				request.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+				request.CA,
+				request.Requester,
+				strings.Join(request.Request.Principals, ","),
+				fmt.Sprintf("%dm", request.Request.TTLMinutes),
+				request.Ticket,
+				request.Reason,
+				fmt.Sprintf("%d", request.Serial),
+			})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	breakGlassListCmd.Flags().StringP("name", "n", "", "Only certificates from this CA")
+	breakGlassListCmd.Flags().Duration("since", 0, "Only certificates issued within this long, e.g. 72h")
+	breakGlassCmd.AddCommand(breakGlassListCmd)
+}
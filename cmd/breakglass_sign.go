@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var breakGlassSignCmd = &cobra.Command{
+	Use:   "sign",
+	Short: "Sign a public key in an emergency, skipping the CA's approval rules",
+	Long: `Sign a public key without waiting for approvers, e.g. during an incident.
+A reason and ticket reference are required. The certificate is valid for at
+most an hour, its key ID starts with BREAK-GLASS and it is listed by
+"sshtrust breakglass list" for the post-incident review.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		publicKey, _ := cmd.Flags().GetString("public_key")
+		identityFile, _ := cmd.Flags().GetString("identity")
+		principals, _ := cmd.Flags().GetString("principals")
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		reason, _ := cmd.Flags().GetString("reason")
+		ticket, _ := cmd.Flags().GetString("ticket")
+
+		if caID == "" {
+			_, profile := client.ActiveProfile()
+			caID = profile.DefaultCA
+		}
+		if caID == "" {
+			log.Fatal("CA name is required, pass --name or set a default CA on the profile")
+		}
+		if identityFile != "" {
+			var err error
+			identityFile, err = identity.Expand(identityFile)
+			if err != nil {
+				log.Fatalf("Error resolving identity: %v", err)
+			}
+			publicKey, err = identity.ReadPublicKey(identityFile)
+			if err != nil {
+				log.Fatalf("Error reading identity: %v", err)
+			}
+		}
+		if publicKey == "" {
+			log.Fatal("A public key is required, pass --public_key or --identity")
+		}
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		request, err := apiClient.BreakGlassSign(cmd.Context(), caID, cert.BreakGlassRequest{
+			SignRequest: cert.SignRequest{
+				PublicKey:  publicKey,
+				Principals: strings.Split(principals, ","),
+				TTLMinutes: ttl,
+			},
+			Reason: reason,
+			Ticket: ticket,
+		})
+		if err != nil {
+			log.Fatalf("Error signing public key: %v", err)
+		}
+		log.Printf("Break-glass certificate %d issued under %s, it will be reviewed", request.Serial, request.Ticket)
+		writeRequestCertificate(request, identityFile)
+	},
+}
+
+func init() {
+	breakGlassSignCmd.Flags().StringP("name", "n", "", "Name of the CA (defaults to the profile's default CA)")
+	breakGlassSignCmd.Flags().StringP("public_key", "k", "", "Public key to be signed")
+	breakGlassSignCmd.Flags().StringP("identity", "i", "", "Identity file, signs <identity>.pub and writes <identity>-cert.pub")
+	breakGlassSignCmd.Flags().StringP("principals", "p", "", "Comma-separated list of principals for the certificate")
+	breakGlassSignCmd.Flags().Int("ttl", 0, fmt.Sprintf("Time to live for the certificate in minutes, at most %d (defaults to the most the CA allows)", cert.BreakGlassMaxTTLMinutes))
+	breakGlassSignCmd.Flags().String("reason", "", "Why the approval rules are being bypassed")
+	breakGlassSignCmd.Flags().String("ticket", "", "Incident or ticket reference")
+	_ = breakGlassSignCmd.MarkFlagRequired("principals")
+	_ = breakGlassSignCmd.MarkFlagRequired("reason")
+	_ = breakGlassSignCmd.MarkFlagRequired("ticket")
+	breakGlassSignCmd.MarkFlagsMutuallyExclusive("public_key", "identity")
+	breakGlassCmd.AddCommand(breakGlassSignCmd)
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/lukegriffith/SSHTrust/internal/client"
 	"log"
@@ -16,14 +17,20 @@ var caGetCmd = &cobra.Command{
 		// Extract the CA ID from the command arguments
 		id := args[0]
 
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+
 		// Call the client library to retrieve the CA's public key
-		ca, err := client.GetCA(id)
+		ca, err := apiClient.GetCA(cmd.Context(), id)
 		if err != nil {
 			log.Fatalf("Error retrieving CA public key: %v", err)
 		}
 
-		// Display the public key
-		fmt.Println(ca)
+		// Display the CA as JSON
+		out, _ := json.MarshalIndent(ca, "", "  ")
+		fmt.Println(string(out))
 	},
 }
 
@@ -14,8 +14,13 @@ var caListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List all Certificate Authorities",
 	Run: func(cmd *cobra.Command, args []string) {
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+
 		// Call the client library to fetch the list of CAs
-		cas, err := client.ListCAs()
+		cas, err := apiClient.ListCAs(cmd.Context())
 		if err != nil {
 			log.Fatalf("Error retrieving CA list: %v", err)
 		}
@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/lukegriffith/SSHTrust/internal/client"
@@ -20,22 +21,95 @@ var caNewCmd = &cobra.Command{
 		keyType, _ := cmd.Flags().GetString("type")
 		principals, _ := cmd.Flags().GetString("validPrincipals")
 		ttl, _ := cmd.Flags().GetInt("ttl")
+		hostTTL, _ := cmd.Flags().GetInt("max-host-ttl")
+		tags, _ := cmd.Flags().GetStringSlice("tags")
+		kind, _ := cmd.Flags().GetString("kind")
+		hostPatterns, _ := cmd.Flags().GetStringSlice("host-patterns")
+		accountFlags, _ := cmd.Flags().GetStringArray("account")
+		signatureAlgorithm, _ := cmd.Flags().GetString("signature-algorithm")
+		allowedKeyTypes, _ := cmd.Flags().GetStringSlice("allowed-key-types")
+		minBitsFlags, _ := cmd.Flags().GetStringArray("min-bits")
+		requireSecurityKey, _ := cmd.Flags().GetBool("require-security-key")
+		requireVerification, _ := cmd.Flags().GetBool("require-user-verification")
+		backdate, _ := cmd.Flags().GetInt("backdate")
+		maxFutureStart, _ := cmd.Flags().GetInt("max-future-start")
+		approvePrincipals, _ := cmd.Flags().GetStringSlice("approve-principals")
+		approveTTLOver, _ := cmd.Flags().GetInt("approve-ttl-over")
+		approvals, _ := cmd.Flags().GetInt("approvals")
+		approvers, _ := cmd.Flags().GetStringSlice("approvers")
+		responders, _ := cmd.Flags().GetStringSlice("break-glass-responders")
 
 		// Basic validation
 		if name == "" {
 			log.Fatal("CA name is required")
 		}
+		resolvedType, bits := resolveKeyType(keyType, bits, cmd.Flags().Changed("bits"))
+		var accounts map[string][]string
+		for _, flag := range accountFlags {
+			account, principals, ok := strings.Cut(flag, "=")
+			if !ok || account == "" {
+				log.Fatalf("Invalid --account %q, expected account=principal,...", flag)
+			}
+			if accounts == nil {
+				accounts = map[string][]string{}
+			}
+			accounts[account] = append(accounts[account], strings.Split(principals, ",")...)
+		}
+		var keyPolicy *cert.KeyPolicy
+		if len(allowedKeyTypes) > 0 || len(minBitsFlags) > 0 || requireSecurityKey || requireVerification {
+			keyPolicy = &cert.KeyPolicy{RequireSecurityKey: requireSecurityKey, RequireUserVerification: requireVerification}
+			for _, keyType := range allowedKeyTypes {
+				keyPolicy.AllowedTypes = append(keyPolicy.AllowedTypes, cert.KeyType(keyType))
+			}
+			for _, flag := range minBitsFlags {
+				keyType, value, ok := strings.Cut(flag, "=")
+				minBits, err := strconv.Atoi(value)
+				if !ok || err != nil {
+					log.Fatalf("Invalid --min-bits %q, expected type=bits", flag)
+				}
+				if keyPolicy.MinBits == nil {
+					keyPolicy.MinBits = map[cert.KeyType]int{}
+				}
+				keyPolicy.MinBits[cert.KeyType(keyType)] = minBits
+			}
+		}
+		var approvalRules []cert.ApprovalRule
+		if len(approvePrincipals) > 0 || approveTTLOver > 0 {
+			if len(approvers) == 0 {
+				log.Fatal("--approvers is required with --approve-principals and --approve-ttl-over")
+			}
+			approvalRules = []cert.ApprovalRule{{
+				Principals:     approvePrincipals,
+				TTLOverMinutes: approveTTLOver,
+				Approvals:      approvals,
+				Approvers:      approvers,
+			}}
+		}
 		body := cert.CaRequest{
 			CommonCa: cert.CommonCa{
-				Name:            name,
-				Bits:            bits,
-				Type:            cert.KeyType(keyType),
-				ValidPrincipals: strings.Split(principals, ","),
-				MaxTTLMinutes:   ttl,
+				Name:                  name,
+				Bits:                  bits,
+				Type:                  resolvedType,
+				ValidPrincipals:       strings.Split(principals, ","),
+				MaxTTLMinutes:         ttl,
+				MaxHostTTLMinutes:     hostTTL,
+				Tags:                  tags,
+				Kind:                  cert.CAKind(kind),
+				HostPatterns:          hostPatterns,
+				Accounts:              accounts,
+				SignatureAlgorithm:    signatureAlgorithm,
+				KeyPolicy:             keyPolicy,
+				BackdateSeconds:       backdate,
+				MaxFutureStartMinutes: maxFutureStart,
+				ApprovalRules:         approvalRules,
+				BreakGlassResponders:  responders,
 			},
 		}
-		log.Println(body)
-		err := client.CreateCA(body)
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		_, err = apiClient.CreateCA(cmd.Context(), body)
 		if err != nil {
 			log.Fatalf("Failed to create CA: %v", err)
 		}
@@ -44,13 +118,50 @@ var caNewCmd = &cobra.Command{
 	},
 }
 
+// resolveKeyType maps ssh-keygen's -t ecdsa -b <curve> to an ECDSA key type.
+// The RSA default bits are dropped for other key types, where the key type
+// sets the size.
+func resolveKeyType(keyType string, bits int, bitsSet bool) (cert.KeyType, int) {
+	if keyType == "ecdsa" {
+		if !bitsSet {
+			bits = 256
+		}
+		ecdsaType, err := cert.ECDSAKeyType(bits)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return ecdsaType, bits
+	}
+	if cert.KeyType(keyType) != cert.RSAKey && !bitsSet {
+		bits = 0
+	}
+	return cert.KeyType(keyType), bits
+}
+
 func init() {
 	// Add flags to the new CA command
 	caNewCmd.Flags().StringP("name", "n", "", "Name of the CA (required)")
-	caNewCmd.Flags().IntP("bits", "b", 2048, "Key size in bits (optional)")
-	caNewCmd.Flags().StringP("type", "t", "ssh-rsa", "Key type (optional, ssh-rsa, ssh-ed25519)")
+	caNewCmd.Flags().IntP("bits", "b", 2048, "Key size in bits, for ecdsa the curve size (optional)")
+	caNewCmd.Flags().StringP("type", "t", "ssh-rsa", "Key type (optional, ssh-rsa, ssh-ed25519, ecdsa-sha2-nistp256, ecdsa-sha2-nistp384, ecdsa-sha2-nistp521, or ecdsa with --bits)")
 	caNewCmd.Flags().StringP("validPrincipals", "p", "", "comma separated principals (required)")
 	caNewCmd.Flags().Int("ttl", 60, "Maximim TTL in minutes the CA permits")
+	caNewCmd.Flags().StringSlice("tags", nil, "comma separated tags used to select the CA in trust bundles")
+	caNewCmd.Flags().String("kind", "user", "Kind of certificates the CA signs (user, host)")
+	caNewCmd.Flags().Int("max-host-ttl", 0, "Maximum TTL in minutes of host certificates, defaults to --ttl (host CAs only)")
+	caNewCmd.Flags().StringSlice("host-patterns", nil, "comma separated known_hosts patterns a host CA is trusted for, e.g. *.example.com")
+	caNewCmd.Flags().StringArray("account", nil, "local account and the principals that may log in to it, e.g. root=alice,bob (repeatable)")
+	caNewCmd.Flags().Int("backdate", 0, "Seconds certificates start before they are signed, for hosts with slow clocks (at most 3600)")
+	caNewCmd.Flags().Int("max-future-start", 0, "Furthest ahead in minutes a requested validity window may start (defaults to a week)")
+	caNewCmd.Flags().StringSlice("approve-principals", nil, "comma separated principals whose certificates need approval, see sshtrust request")
+	caNewCmd.Flags().Int("approve-ttl-over", 0, "Certificates valid for longer than this many minutes need approval")
+	caNewCmd.Flags().Int("approvals", 1, "Approvals certificates needing approval wait for")
+	caNewCmd.Flags().StringSlice("approvers", nil, "comma separated users who may approve, required with approval rules")
+	caNewCmd.Flags().StringSlice("break-glass-responders", nil, "comma separated users who may sign break-glass certificates, nobody when not set")
+	caNewCmd.Flags().StringSlice("allowed-key-types", nil, "comma separated user key types the CA signs, e.g. sk-ssh-ed25519@openssh.com (user CAs only)")
+	caNewCmd.Flags().StringArray("min-bits", nil, "minimum user key length for a key type, e.g. ssh-rsa=3072 (repeatable)")
+	caNewCmd.Flags().Bool("require-security-key", false, "only sign security key backed (sk-*) user keys")
+	caNewCmd.Flags().Bool("require-user-verification", false, "only sign security keys, with certificates requiring a PIN or biometric check (verify-required)")
+	caNewCmd.Flags().String("signature-algorithm", "", "Algorithm the CA signs with, for ssh-rsa one of rsa-sha2-512, rsa-sha2-256 (optional, defaults to the strongest)")
 
 	_ = signCmd.MarkFlagRequired("name")
 	_ = signCmd.MarkFlagRequired("principals")
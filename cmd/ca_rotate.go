@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var caRotateCmd = &cobra.Command{
+	Use:   "rotate [id]",
+	Short: "Rotate the key of a Certificate Authority",
+	Long: `Rotate the key of a Certificate Authority.
+
+The previous key stays in the trust bundles as a retiring key, so hosts keep
+accepting certificates it signed. Once hosts have picked up the new key and
+the old certificates have expired, run again with --complete to drop it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		id := args[0]
+		complete, _ := cmd.Flags().GetBool("complete")
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		if complete {
+			if _, err := apiClient.CompleteRotation(cmd.Context(), id); err != nil {
+				log.Fatalf("Error completing rotation: %v", err)
+			}
+			fmt.Printf("Rotation of CA '%s' completed, retiring keys are no longer trusted\n", id)
+			return
+		}
+		ca, err := apiClient.RotateCA(cmd.Context(), id)
+		if err != nil {
+			log.Fatalf("Error rotating CA: %v", err)
+		}
+		fmt.Printf("CA '%s' rotated, new public key:\n%s", id, ca.PublicKey)
+	},
+}
+
+func init() {
+	caRotateCmd.Flags().Bool("complete", false, "Stop trusting the CA's retiring keys")
+	caCmd.AddCommand(caRotateCmd)
+}
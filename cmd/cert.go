@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var certCmd = &cobra.Command{
+	Use:   "cert",
+	Short: "Work with signed certificates",
+}
+
+func init() {
+	rootCmd.AddCommand(certCmd)
+}
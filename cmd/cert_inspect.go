@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var certInspectCmd = &cobra.Command{
+	Use:   "inspect [file|-]",
+	Short: "Show the contents of a certificate",
+	Long: `Show the contents of a certificate read from a file, or stdin when the
+file is - or omitted. The server is asked which of its CAs signed the
+certificate unless --offline is passed.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		offline, _ := cmd.Flags().GetBool("offline")
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		var data []byte
+		var err error
+		if len(args) == 0 || args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			log.Fatalf("Error reading certificate: %v", err)
+		}
+
+		var info *cert.InspectResponse
+		if offline {
+			parsed, err := cert.ParseCertificate(data)
+			if err != nil {
+				log.Fatalf("Error parsing certificate: %v", err)
+			}
+			info = cert.Inspect(parsed)
+		} else {
+			apiClient, err := client.New()
+			if err != nil {
+				log.Fatalf("Error creating client: %v", err)
+			}
+			info, err = apiClient.InspectCertificate(cmd.Context(), string(data))
+			if err != nil {
+				log.Fatalf("Error inspecting certificate: %v", err)
+			}
+		}
+
+		if asJSON {
+			out, _ := json.MarshalIndent(info, "", "  ")
+			fmt.Println(string(out))
+			return
+		}
+		printCertificate(info, offline)
+	},
+}
+
+// printCertificate displays the certificate in the style of ssh-keygen -L
+func printCertificate(info *cert.InspectResponse, offline bool) {
+	validBefore := "forever"
+	if info.ValidBefore != nil {
+		validBefore = info.ValidBefore.Local().Format(time.RFC3339)
+	}
+	fmt.Printf("Type: %s %s certificate\n", info.KeyType, info.Type)
+	fmt.Printf("Public key: %s\n", info.PublicKeyFingerprint)
+	fmt.Printf("Signing CA: %s (using %s)\n", info.SigningCAFingerprint, info.SignatureAlgorithm)
+	switch {
+	case offline:
+	case info.MatchedCA != "":
+		fmt.Printf("Matched CA: %s\n", info.MatchedCA)
+	default:
+		fmt.Println("Matched CA: none, the signer is not a CA on this server")
+	}
+	fmt.Printf("Key ID: %q\n", info.KeyID)
+	fmt.Printf("Serial: %d\n", info.Serial)
+	fmt.Printf("Valid: from %s to %s\n", info.ValidAfter.Local().Format(time.RFC3339), validBefore)
+	fmt.Printf("Principals: %s\n", strings.Join(info.Principals, ", "))
+	fmt.Printf("Critical Options: %s\n", formatOptions(info.CriticalOptions))
+	fmt.Printf("Extensions: %s\n", formatOptions(info.Extensions))
+}
+
+// formatOptions renders certificate options in a stable order
+func formatOptions(options map[string]string) string {
+	if len(options) == 0 {
+		return "(none)"
+	}
+	names := []string{}
+	for name, value := range options {
+		if value != "" {
+			name = fmt.Sprintf("%s %s", name, value)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+func init() {
+	certInspectCmd.Flags().Bool("offline", false, "Parse the certificate locally without asking the server")
+	certInspectCmd.Flags().Bool("json", false, "Print the result as JSON")
+	certCmd.AddCommand(certInspectCmd)
+}
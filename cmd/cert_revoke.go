@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var certRevokeCmd = &cobra.Command{
+	Use:   "revoke [file]",
+	Short: "Revoke a certificate by serial number or from its file",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		serial, _ := cmd.Flags().GetUint64("serial")
+
+		if (len(args) == 1) == cmd.Flags().Changed("serial") {
+			log.Fatal("Pass either a certificate file or --serial")
+		}
+		if len(args) == 1 {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				log.Fatalf("Error reading certificate: %v", err)
+			}
+			parsed, err := cert.ParseCertificate(data)
+			if err != nil {
+				log.Fatalf("Error parsing certificate: %v", err)
+			}
+			serial = parsed.Serial
+		}
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		if err := apiClient.Revoke(cmd.Context(), caID, serial); err != nil {
+			log.Fatalf("Error revoking certificate: %v", err)
+		}
+		fmt.Printf("Certificate %d revoked for CA '%s'\n", serial, caID)
+	},
+}
+
+func init() {
+	certRevokeCmd.Flags().StringP("name", "n", "", "Name of the CA that issued the certificate (required)")
+	certRevokeCmd.Flags().Uint64("serial", 0, "Serial number of the certificate")
+	_ = certRevokeCmd.MarkFlagRequired("name")
+	certCmd.AddCommand(certRevokeCmd)
+}
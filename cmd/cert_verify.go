@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var certVerifyCmd = &cobra.Command{
+	Use:   "verify [file|-]",
+	Short: "Check a certificate is accepted by a CA for a principal",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		principal, _ := cmd.Flags().GetString("principal")
+
+		var data []byte
+		var err error
+		if len(args) == 0 || args[0] == "-" {
+			data, err = io.ReadAll(os.Stdin)
+		} else {
+			data, err = os.ReadFile(args[0])
+		}
+		if err != nil {
+			log.Fatalf("Error reading certificate: %v", err)
+		}
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		result, err := apiClient.Verify(cmd.Context(), caID, cert.VerifyRequest{
+			Certificate: string(data),
+			Principal:   principal,
+		})
+		if err != nil {
+			log.Fatalf("Error verifying certificate: %v", err)
+		}
+		if !result.Valid {
+			fmt.Printf("Certificate %d rejected: %s\n", result.Serial, result.Reason)
+			os.Exit(1)
+		}
+		fmt.Printf("Certificate %d is valid for %s\n", result.Serial, principal)
+	},
+}
+
+func init() {
+	certVerifyCmd.Flags().StringP("name", "n", "", "Name of the CA (required)")
+	certVerifyCmd.Flags().StringP("principal", "p", "", "Principal the certificate is used to log in as (required)")
+	_ = certVerifyCmd.MarkFlagRequired("name")
+	_ = certVerifyCmd.MarkFlagRequired("principal")
+	certCmd.AddCommand(certVerifyCmd)
+}
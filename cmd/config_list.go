@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"log"
+	"os"
+	"sort"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List configured profiles",
+	Run: func(cmd *cobra.Command, args []string) {
+		config, err := loadConfig()
+		if err != nil {
+			log.Fatalf("Error loading config: %v", err)
+		}
+		current, _, err := config.Resolve("")
+		if err != nil {
+			log.Fatalf("Error resolving current profile: %v", err)
+		}
+
+		names := []string{}
+		for name := range config.Profiles {
+			names = append(names, name)
+		}
+		if _, ok := config.Profiles[client.DefaultProfileName]; !ok {
+			names = append(names, client.DefaultProfileName)
+		}
+		sort.Strings(names)
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Current", "Name", "Server", "CA Bundle", "Default CA"})
+		for _, name := range names {
+			_, profile, _ := config.Resolve(name)
+			marker := ""
+			if name == current {
+				marker = "*"
+			}
+			table.Append([]string{marker, name, profile.Server, profile.CABundle, profile.DefaultCA})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var configSetCmd = &cobra.Command{
+	Use:   "set-profile [name]",
+	Short: "Create or update a named profile",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		config, err := loadConfig()
+		if err != nil {
+			log.Fatalf("Error loading config: %v", err)
+		}
+
+		// Only overwrite the fields that were passed
+		profile := config.Profiles[name]
+		if cmd.Flags().Changed("server") {
+			profile.Server, _ = cmd.Flags().GetString("server")
+		}
+		if cmd.Flags().Changed("ca-bundle") {
+			profile.CABundle, _ = cmd.Flags().GetString("ca-bundle")
+		}
+		if cmd.Flags().Changed("default-ca") {
+			profile.DefaultCA, _ = cmd.Flags().GetString("default-ca")
+		}
+		config.Profiles[name] = profile
+
+		if use, _ := cmd.Flags().GetBool("use"); use {
+			config.CurrentProfile = name
+		}
+
+		path, err := client.DefaultConfigPath()
+		if err != nil {
+			log.Fatalf("Error locating config: %v", err)
+		}
+		if err := config.Save(path); err != nil {
+			log.Fatalf("Error saving config: %v", err)
+		}
+		fmt.Printf("Profile '%s' saved to %s\n", name, path)
+	},
+}
+
+func init() {
+	configSetCmd.Flags().String("ca-bundle", "", "PEM file used to verify the server's TLS certificate")
+	configSetCmd.Flags().String("default-ca", "", "CA used when a command is not given one")
+	configSetCmd.Flags().Bool("use", false, "Make this the current profile")
+	configCmd.AddCommand(configSetCmd)
+}
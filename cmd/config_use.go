@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/spf13/cobra"
+)
+
+var configUseCmd = &cobra.Command{
+	Use:   "use-profile [name]",
+	Short: "Set the profile used when --profile is not passed",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		config, err := loadConfig()
+		if err != nil {
+			log.Fatalf("Error loading config: %v", err)
+		}
+		if _, ok := config.Profiles[name]; !ok && name != client.DefaultProfileName {
+			log.Fatalf("Profile '%s' not found", name)
+		}
+		config.CurrentProfile = name
+
+		path, err := client.DefaultConfigPath()
+		if err != nil {
+			log.Fatalf("Error locating config: %v", err)
+		}
+		if err := config.Save(path); err != nil {
+			log.Fatalf("Error saving config: %v", err)
+		}
+		fmt.Printf("Using profile '%s'\n", name)
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configUseCmd)
+}
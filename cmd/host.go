@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var hostCmd = &cobra.Command{
+	Use:   "host",
+	Short: "Enroll hosts and configure their sshd to trust SSHTrust",
+}
+
+func init() {
+	rootCmd.AddCommand(hostCmd)
+}
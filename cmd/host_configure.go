@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/hostconfig"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var hostConfigureCmd = &cobra.Command{
+	Use:   "configure",
+	Short: "Write the CA bundle, KRL, principals and sshd drop-in for this host",
+	Long: `Write the CA bundle, KRL, principals and sshd drop-in for this host.
+
+Files are only rewritten when they differ from what the server expects. With
+--check nothing is written, the drift is reported and the command exits 1
+when there is any, including an sshd_config that does not Include the
+drop-in. sshd needs reloading after changes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		cas, _ := cmd.Flags().GetStringSlice("ca")
+		check, _ := cmd.Flags().GetBool("check")
+		configDir, _ := cmd.Flags().GetString("config-dir")
+		dropIn, _ := cmd.Flags().GetString("drop-in")
+		principals, _ := cmd.Flags().GetString("principals")
+		accounts, _ := cmd.Flags().GetStringSlice("accounts")
+		commandUser, _ := cmd.Flags().GetString("principals-command-user")
+		cacheDir, _ := cmd.Flags().GetString("cache-dir")
+		tokenFile, _ := cmd.Flags().GetString("principals-token-file")
+		sshdConfig, _ := cmd.Flags().GetString("sshd-config")
+		hostCertificates, _ := cmd.Flags().GetStringSlice("host-certificate")
+
+		opts := hostconfig.Options{
+			Filter:                sshtrust.TrustFilter{Tag: tag, CAs: cas},
+			ConfigDir:             configDir,
+			DropInPath:            dropIn,
+			Principals:            principals,
+			Accounts:              accounts,
+			PrincipalsCommandUser: commandUser,
+			HostCertificates:      hostCertificates,
+		}
+		if principals == "none" {
+			opts.Principals = ""
+		}
+		if principals == hostconfig.PrincipalsCommand {
+			opts.PrincipalsCommand = principalsCommandLine(tag, cacheDir, tokenFile)
+			opts.CacheDir = cacheDir
+		}
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		files, err := hostconfig.Plan(cmd.Context(), apiClient, opts)
+		if err != nil {
+			log.Fatalf("Error rendering host configuration: %v", err)
+		}
+
+		included, includeErr := hostconfig.CheckInclude(sshdConfig, dropIn)
+		if check {
+			if includeErr != nil {
+				log.Fatalf("Error checking sshd config: %v", includeErr)
+			}
+			drift, err := hostconfig.Check(files)
+			if err != nil {
+				log.Fatalf("Error checking host configuration: %v", err)
+			}
+			drift = append(drift, included...)
+			for _, d := range drift {
+				fmt.Println(d)
+			}
+			if len(drift) > 0 {
+				os.Exit(1)
+			}
+			fmt.Println("Host configuration is up to date")
+			return
+		}
+
+		changed, err := hostconfig.Apply(files)
+		for _, path := range changed {
+			fmt.Printf("Wrote %s\n", path)
+		}
+		if err != nil {
+			log.Fatalf("Error writing host configuration: %v", err)
+		}
+		if includeErr != nil {
+			fmt.Printf("Warning: %v\n", includeErr)
+		}
+		for _, d := range included {
+			fmt.Printf("Warning: %s, sshd will not read the drop-in\n", d)
+		}
+		if len(changed) == 0 {
+			fmt.Println("Host configuration is up to date")
+		} else {
+			fmt.Println("Reload sshd to apply the changes")
+		}
+	},
+}
+
+// principalsCommandLine is the AuthorizedPrincipalsCommand running this
+// binary against the current server.
+func principalsCommandLine(tag, cacheDir, tokenFile string) string {
+	binary, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Error finding the sshtrust binary: %v", err)
+	}
+	_, profile := client.ActiveProfile()
+	parts := []string{binary, "principals-command", "--server", profile.Server, "--cache-dir", cacheDir}
+	if tokenFile != "" {
+		parts = append(parts, "--token-file", tokenFile)
+	}
+	if tag != "" {
+		parts = append(parts, "--tag", tag)
+	}
+	return strings.Join(append(parts, "%u"), " ")
+}
+
+func init() {
+	hostConfigureCmd.Flags().String("tag", "", "Only trust CAs with this tag, also the host tag used for principals")
+	hostConfigureCmd.Flags().StringSlice("ca", nil, "Only trust these CAs, comma separated")
+	hostConfigureCmd.Flags().Bool("check", false, "Report drift from the server's configuration without writing anything")
+	hostConfigureCmd.Flags().String("config-dir", hostconfig.DefaultConfigDir, "Directory for the CA bundle, KRL and principals files")
+	hostConfigureCmd.Flags().String("drop-in", hostconfig.DefaultDropInPath, "sshd_config.d drop-in to write")
+	hostConfigureCmd.Flags().String("sshd-config", hostconfig.DefaultSSHDConfig, "sshd_config that has to Include the drop-in")
+	hostConfigureCmd.Flags().String("principals", hostconfig.PrincipalsCommand, "How sshd finds principals: command, file, or none")
+	hostConfigureCmd.Flags().StringSlice("accounts", nil, "Accounts to write principals files for, with --principals file")
+	hostConfigureCmd.Flags().String("principals-command-user", "nobody", "User sshd runs the principals command as")
+	hostConfigureCmd.Flags().String("cache-dir", DefaultCacheDir, "Cache directory for the principals command, created for its user")
+	hostConfigureCmd.Flags().String("principals-token-file", "", "File on this host holding the server's principals token, for the principals command")
+	hostConfigureCmd.Flags().StringSlice("host-certificate", nil, "Host certificates for sshd to present, comma separated")
+	hostCmd.AddCommand(hostConfigureCmd)
+}
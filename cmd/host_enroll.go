@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/hostconfig"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var hostEnrollCmd = &cobra.Command{
+	Use:   "enroll",
+	Short: "Enroll this host with a join token and install its host certificates",
+	Long: `Enroll this host with a join token and install its host certificates.
+
+Every ssh_host_*_key.pub in the key directory is signed, and the certificates
+are written next to the keys as ssh_host_*_key-cert.pub. The token is read from
+--token-file, --token or SSHTRUST_JOIN_TOKEN, in that order.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		token, _ := cmd.Flags().GetString("token")
+		tokenFile, _ := cmd.Flags().GetString("token-file")
+		hostnames, _ := cmd.Flags().GetStringSlice("hostname")
+		keyDir, _ := cmd.Flags().GetString("key-dir")
+
+		if tokenFile != "" {
+			data, err := os.ReadFile(tokenFile)
+			if err != nil {
+				log.Fatalf("Error reading join token: %v", err)
+			}
+			token = strings.TrimSpace(string(data))
+		}
+		if token == "" {
+			token = os.Getenv("SSHTRUST_JOIN_TOKEN")
+		}
+		if token == "" {
+			log.Fatal("A join token is required, pass --token-file or --token")
+		}
+		if len(hostnames) == 0 {
+			hostname, err := os.Hostname()
+			if err != nil {
+				log.Fatalf("Error reading host name: %v", err)
+			}
+			hostnames = []string{hostname}
+		}
+
+		hostKeys, err := hostconfig.HostKeys(keyDir)
+		if err != nil {
+			log.Fatalf("Error reading host keys: %v", err)
+		}
+		body := cert.EnrollRequest{Token: token, Hostnames: hostnames}
+		for _, key := range hostKeys {
+			body.HostKeys = append(body.HostKeys, string(key.PublicKey))
+		}
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		enrolled, err := apiClient.Enroll(cmd.Context(), body)
+		if err != nil {
+			log.Fatalf("Error enrolling host: %v", err)
+		}
+		if len(enrolled.Certificates) != len(hostKeys) {
+			log.Fatalf("Error enrolling host: server returned %d certificates for %d host keys", len(enrolled.Certificates), len(hostKeys))
+		}
+
+		files := []hostconfig.File{}
+		for i, key := range hostKeys {
+			files = append(files, hostconfig.File{Path: key.CertificatePath(), Data: []byte(enrolled.Certificates[i]), Mode: 0644})
+		}
+		changed, err := hostconfig.Apply(files)
+		for _, path := range changed {
+			fmt.Printf("Wrote %s\n", path)
+		}
+		if err != nil {
+			log.Fatalf("Error installing host certificates: %v", err)
+		}
+		fmt.Printf("Enrolled %s with %s\n", enrolled.Host.Name, enrolled.Host.CA)
+		fmt.Println("Add the certificates to sshd with host configure --host-certificate and reload sshd")
+	},
+}
+
+func init() {
+	hostEnrollCmd.Flags().String("token", "", "Join token issued with host token")
+	hostEnrollCmd.Flags().String("token-file", "", "File to read the join token from")
+	hostEnrollCmd.Flags().StringSlice("hostname", nil, "Host names to certify, defaults to the system host name")
+	hostEnrollCmd.Flags().String("key-dir", hostconfig.DefaultHostKeyDir, "Directory holding sshd's host keys")
+	hostCmd.AddCommand(hostEnrollCmd)
+}
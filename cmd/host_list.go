@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var hostListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List enrolled hosts and when their certificates expire",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		hosts, err := apiClient.ListHosts(cmd.Context())
+		if err != nil {
+			log.Fatalf("Error retrieving host list: %v", err)
+		}
+		if len(hosts) == 0 {
+			fmt.Println("No hosts enrolled.")
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"Name", "Host names", "CA", "Key", "Fingerprint", "Expires"})
+		for _, host := range hosts {
+			for _, key := range host.Keys {
+				table.Append([]string{
+					host.Name,
+					strings.Join(host.Hostnames, ","),
+					host.CA,
+					key.Type,
+					key.Fingerprint,
+					key.ValidBefore.Local().Format("2006-01-02 15:04:05"),
+				})
+			}
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	hostCmd.AddCommand(hostListCmd)
+}
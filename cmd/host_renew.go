@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/hostconfig"
+	"github.com/lukegriffith/SSHTrust/internal/renew"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+var hostRenewCmd = &cobra.Command{
+	Use:   "renew",
+	Short: "Renew this host's certificates before they expire",
+	Long: `Renew this host's certificates before they expire.
+
+Each host key with a certificate from host enroll is renewed once --renew-at of
+its lifetime has passed, proving possession of the host key instead of logging
+in. Renewed certificates are written atomically and sshd is sent a SIGHUP to
+pick them up. Run it from cron or a systemd timer.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		keyDir, _ := cmd.Flags().GetString("key-dir")
+		fraction, _ := cmd.Flags().GetFloat64("renew-at")
+		force, _ := cmd.Flags().GetBool("force")
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		pidFile, _ := cmd.Flags().GetString("sshd-pid-file")
+		noReload, _ := cmd.Flags().GetBool("no-reload")
+
+		hostKeys, err := hostconfig.HostKeys(keyDir)
+		if err != nil {
+			log.Fatalf("Error reading host keys: %v", err)
+		}
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+
+		files := []hostconfig.File{}
+		enrolled, failed := 0, 0
+		for _, key := range hostKeys {
+			certPath := key.CertificatePath()
+			current, err := readCertificate(certPath)
+			if os.IsNotExist(err) {
+				continue
+			}
+			if err != nil {
+				log.Printf("%s: %v", certPath, err)
+				failed++
+				continue
+			}
+			enrolled++
+			if renewAt := renew.RenewAt(current, fraction); !force && time.Now().Before(renewAt) {
+				fmt.Printf("%s is valid until %s, renewing after %s\n", certPath,
+					time.Unix(int64(current.ValidBefore), 0).Format(time.RFC3339), renewAt.Format(time.RFC3339))
+				continue
+			}
+
+			privateKey, err := os.ReadFile(key.PrivateKeyPath())
+			if err != nil {
+				log.Fatalf("Error reading host key: %v", err)
+			}
+			signer, err := ssh.ParsePrivateKey(privateKey)
+			if err != nil {
+				log.Fatalf("Error parsing host key %s: %v", key.PrivateKeyPath(), err)
+			}
+			// The registry names hosts by the first host name they enrolled with
+			request, err := cert.NewRenewHostRequest(signer, current.ValidPrincipals[0], ttl, time.Now())
+			if err != nil {
+				log.Fatalf("Error signing renewal request: %v", err)
+			}
+			renewed, err := apiClient.RenewHost(cmd.Context(), *request)
+			if err != nil {
+				log.Printf("%s: %v", certPath, err)
+				failed++
+				continue
+			}
+			files = append(files, hostconfig.File{Path: certPath, Data: []byte(renewed.Certificate), Mode: 0644})
+		}
+		if enrolled == 0 && failed == 0 {
+			log.Fatalf("No host certificates found in %s, run host enroll first", keyDir)
+		}
+
+		changed, err := hostconfig.Apply(files)
+		for _, path := range changed {
+			fmt.Printf("Renewed %s\n", path)
+		}
+		if err != nil {
+			log.Fatalf("Error writing host certificates: %v", err)
+		}
+		if len(changed) > 0 && !noReload {
+			if err := hostconfig.ReloadSSHD(pidFile); err != nil {
+				log.Fatalf("Error reloading sshd: %v", err)
+			}
+			fmt.Println("Reloaded sshd")
+		}
+		if failed > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+// readCertificate reads a certificate written by host enroll or renew
+func readCertificate(path string) (*ssh.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := cert.ParseCertificate(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.ValidPrincipals) == 0 {
+		return nil, fmt.Errorf("certificate has no host names")
+	}
+	return parsed, nil
+}
+
+func init() {
+	hostRenewCmd.Flags().String("key-dir", hostconfig.DefaultHostKeyDir, "Directory holding sshd's host keys and certificates")
+	hostRenewCmd.Flags().Float64("renew-at", renew.DefaultFraction, "Fraction of a certificate's lifetime after which it is renewed")
+	hostRenewCmd.Flags().Bool("force", false, "Renew certificates even when they are not due")
+	hostRenewCmd.Flags().Int("ttl", 0, "TTL of the renewed certificates in minutes, defaults to the TTL the host enrolled with")
+	hostRenewCmd.Flags().String("sshd-pid-file", hostconfig.DefaultSSHDPidFile, "sshd pid file, sshd is sent a SIGHUP after renewing")
+	hostRenewCmd.Flags().Bool("no-reload", false, "Do not reload sshd after renewing")
+	hostCmd.AddCommand(hostRenewCmd)
+}
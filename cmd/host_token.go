@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/handlers"
+	"github.com/spf13/cobra"
+)
+
+var hostTokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Issue a one time join token for a new host to enroll with",
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		hostnames, _ := cmd.Flags().GetStringSlice("hostname")
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		expires, _ := cmd.Flags().GetDuration("expires")
+		reenroll, _ := cmd.Flags().GetBool("reenroll")
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		token, err := apiClient.CreateJoinToken(cmd.Context(), cert.JoinTokenRequest{
+			CA:               caID,
+			Hostnames:        hostnames,
+			TTLMinutes:       ttl,
+			ExpiresInMinutes: int(expires.Minutes()),
+			Reenroll:         reenroll,
+		})
+		if err != nil {
+			log.Fatalf("Error issuing join token: %v", err)
+		}
+		fmt.Println(token.Token)
+		log.Printf("Join token %s for %s expires at %s", token.ID, token.CA, token.ExpiresAt.Local().Format("2006-01-02 15:04:05"))
+	},
+}
+
+func init() {
+	hostTokenCmd.Flags().StringP("name", "n", "", "Name of the host CA")
+	hostTokenCmd.Flags().StringSlice("hostname", nil, "Host names the token allows, comma separated")
+	hostTokenCmd.Flags().Int("ttl", 1440, "TTL of the host certificates in minutes")
+	hostTokenCmd.Flags().Duration("expires", handlers.DefaultJoinTokenExpiry, "How long the token can be used for")
+	hostTokenCmd.Flags().Bool("reenroll", false, "Let a host already enrolled under the first host name enroll again, replacing its keys")
+	_ = hostTokenCmd.MarkFlagRequired("name")
+	_ = hostTokenCmd.MarkFlagRequired("hostname")
+	hostCmd.AddCommand(hostTokenCmd)
+}
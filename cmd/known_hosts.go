@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/internal/knownhosts"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var knownHostsCmd = &cobra.Command{
+	Use:   "known-hosts",
+	Short: "Print or merge @cert-authority lines for the host CAs",
+	Long: `Print or merge @cert-authority lines for the host CAs.
+
+With --merge the lines are written to a block in the known_hosts file owned
+by the current profile, replacing the block from a previous run and leaving
+the rest of the file untouched.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		cas, _ := cmd.Flags().GetStringSlice("ca")
+		merge, _ := cmd.Flags().GetBool("merge")
+		file, _ := cmd.Flags().GetString("file")
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		lines, err := apiClient.KnownHosts(cmd.Context(), sshtrust.TrustFilter{Tag: tag, CAs: cas})
+		if err != nil {
+			log.Fatalf("Error retrieving known hosts: %v", err)
+		}
+		if !merge {
+			fmt.Print(string(lines))
+			return
+		}
+
+		file, err = identity.Expand(file)
+		if err != nil {
+			log.Fatalf("Error resolving known_hosts file: %v", err)
+		}
+		profileName, _ := client.ActiveProfile()
+		changed, err := knownhosts.MergeFile(file, "sshtrust "+profileName, lines)
+		if err != nil {
+			log.Fatalf("Error updating %s: %v", file, err)
+		}
+		if changed {
+			fmt.Printf("Updated %s\n", file)
+		} else {
+			fmt.Printf("%s is up to date\n", file)
+		}
+	},
+}
+
+func init() {
+	knownHostsCmd.Flags().String("tag", "", "Only include CAs with this tag")
+	knownHostsCmd.Flags().StringSlice("ca", nil, "Only include these CAs, comma separated")
+	knownHostsCmd.Flags().Bool("merge", false, "Merge the lines into the known_hosts file")
+	knownHostsCmd.Flags().StringP("file", "f", "~/.ssh/known_hosts", "known_hosts file to merge into")
+	rootCmd.AddCommand(knownHostsCmd)
+}
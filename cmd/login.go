@@ -45,7 +45,7 @@ var loginCmd = &cobra.Command{
 			fmt.Println() // Move to the next line after password input
 		}
 
-		err = client.Login(auth.User{
+		err = client.Login(cmd.Context(), auth.User{
 			Username: userName,
 			Password: password,
 		})
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/filecache"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+// DefaultCacheDir holds responses cached by host integrations run by sshd
+const DefaultCacheDir = "/var/cache/sshtrust"
+
+var principalsCommandCmd = &cobra.Command{
+	Use:   "principals-command user",
+	Short: "Print the principals allowed to log in as a local user, for sshd",
+	Long: `Print the principals allowed to log in as a local user, for sshd.
+
+Configure sshd with
+
+  AuthorizedPrincipalsCommand /usr/local/bin/sshtrust principals-command --token-file /etc/ssh/sshtrust/principals.token --tag prod %u
+  AuthorizedPrincipalsCommandUser nobody
+
+The token file holds the server's principals token and has to be readable by
+the command user.
+
+The last answer for each user is cached, and used while the server can not
+be reached for up to --max-cache-age.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		account := args[0]
+		tags, _ := cmd.Flags().GetStringSlice("tag")
+		cacheDir, _ := cmd.Flags().GetString("cache-dir")
+		maxAge, _ := cmd.Flags().GetDuration("max-cache-age")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		tokenFile, _ := cmd.Flags().GetString("token-file")
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		if tokenFile != "" {
+			token, err := readTokenFile(tokenFile)
+			if err != nil {
+				log.Fatalf("Error reading principals token: %v", err)
+			}
+			apiClient.TokenSource = sshtrust.StaticToken(token)
+		}
+		// sshd waits on this command, so give up on the server quickly
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		defer cancel()
+
+		cache := filecache.New(filepath.Join(cacheDir, "principals"), maxAge)
+		principals, cached, err := cache.Fetch(account, func() ([]byte, error) {
+			return apiClient.Principals(ctx, account, tags)
+		})
+		if err != nil {
+			log.Fatalf("Error retrieving principals for %s: %v", account, err)
+		}
+		if cached {
+			fmt.Fprintf(os.Stderr, "sshtrust: server unavailable, using cached principals for %s\n", account)
+		}
+		fmt.Print(string(principals))
+	},
+}
+
+func init() {
+	principalsCommandCmd.Flags().StringSlice("tag", nil, "Tags of this host, comma separated")
+	principalsCommandCmd.Flags().String("cache-dir", DefaultCacheDir, "Directory to cache answers in")
+	principalsCommandCmd.Flags().Duration("max-cache-age", 24*time.Hour, "Longest a cached answer is used while the server is unavailable")
+	principalsCommandCmd.Flags().String("token-file", "", "File holding the server's principals token, instead of a login")
+	principalsCommandCmd.Flags().Duration("timeout", 5*time.Second, "How long to wait for the server")
+	rootCmd.AddCommand(principalsCommandCmd)
+}
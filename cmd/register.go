@@ -45,7 +45,7 @@ var registerCmd = &cobra.Command{
 			fmt.Println() // Move to the next line after password input
 		}
 
-		err = client.Register(auth.User{
+		err = client.Register(cmd.Context(), auth.User{
 			Username: userName,
 			Password: password,
 		})
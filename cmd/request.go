@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var requestCmd = &cobra.Command{
+	Use:   "request",
+	Short: "Request certificates that need approval, and approve or deny requests",
+}
+
+func init() {
+	rootCmd.AddCommand(requestCmd)
+}
@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var requestGetCmd = &cobra.Command{
+	Use:   "get <id>",
+	Short: "Show a signing request, and fetch its certificate once approved",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		identityFile, _ := cmd.Flags().GetString("identity")
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		request, err := apiClient.GetSigningRequest(cmd.Context(), args[0])
+		if err != nil {
+			log.Fatalf("Error retrieving signing request: %v", err)
+		}
+		switch request.Status {
+		case cert.RequestPending:
+			fmt.Printf("Request %s is waiting for approval, %d of %d\n", request.ID, len(request.Approvals), request.ApprovalsRequired)
+		case cert.RequestDenied:
+			fmt.Printf("Request %s was denied by %s: %s\n", request.ID, request.Denial.By, request.Denial.Comment)
+		default:
+			if identityFile != "" {
+				identityFile, err = identity.Expand(identityFile)
+				if err != nil {
+					log.Fatalf("Error resolving identity: %v", err)
+				}
+			}
+			writeRequestCertificate(request, identityFile)
+		}
+	},
+}
+
+func init() {
+	requestGetCmd.Flags().StringP("identity", "i", "", "Identity file the request was for, writes <identity>-cert.pub once approved")
+	requestCmd.AddCommand(requestGetCmd)
+}
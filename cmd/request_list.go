@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var requestListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List signing requests, by default those waiting for approval",
+	Run: func(cmd *cobra.Command, args []string) {
+		status, _ := cmd.Flags().GetString("status")
+		caID, _ := cmd.Flags().GetString("name")
+		if status == "all" {
+			status = ""
+		}
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		requests, err := apiClient.ListSigningRequests(cmd.Context(), cert.SigningRequestStatus(status), caID)
+		if err != nil {
+			log.Fatalf("Error retrieving signing requests: %v", err)
+		}
+		if len(requests) == 0 {
+			fmt.Println("No signing requests.")
+			return
+		}
+
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"ID", "CA", "Requester", "Principals", "TTL", "Reason", "Approvals", "Status", "Created"})
+		for _, request := range requests {
+			table.Append([]string{
+				request.ID,
+				request.CA,
+				request.Requester,
+				strings.Join(request.Request.Principals, ","),
+				fmt.Sprintf("%dm", request.Request.TTLMinutes),
+				request.Reason,
+				fmt.Sprintf("%d/%d", len(request.Approvals), request.ApprovalsRequired),
+				string(request.Status),
+				request.CreatedAt.Local().Format("2006-01-02 15:04:05"),
+			})
+		}
+		table.Render()
+	},
+}
+
+func init() {
+	requestListCmd.Flags().String("status", string(cert.RequestPending), "Only requests with this status: pending, approved, denied or all")
+	requestListCmd.Flags().StringP("name", "n", "", "Only requests to this CA")
+	requestCmd.AddCommand(requestListCmd)
+}
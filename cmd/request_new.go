@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var requestNewCmd = &cobra.Command{
+	Use:   "new",
+	Short: "Request a certificate, waiting for approval when the CA's rules need it",
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		publicKey, _ := cmd.Flags().GetString("public_key")
+		identityFile, _ := cmd.Flags().GetString("identity")
+		principals, _ := cmd.Flags().GetString("principals")
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		reason, _ := cmd.Flags().GetString("reason")
+
+		if caID == "" {
+			_, profile := client.ActiveProfile()
+			caID = profile.DefaultCA
+		}
+		if caID == "" {
+			log.Fatal("CA name is required, pass --name or set a default CA on the profile")
+		}
+		if identityFile != "" {
+			var err error
+			identityFile, err = identity.Expand(identityFile)
+			if err != nil {
+				log.Fatalf("Error resolving identity: %v", err)
+			}
+			publicKey, err = identity.ReadPublicKey(identityFile)
+			if err != nil {
+				log.Fatalf("Error reading identity: %v", err)
+			}
+		}
+		if publicKey == "" {
+			log.Fatal("A public key is required, pass --public_key or --identity")
+		}
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		request, err := apiClient.CreateSigningRequest(cmd.Context(), caID, cert.CreateSigningRequest{
+			SignRequest: cert.SignRequest{
+				PublicKey:  publicKey,
+				Principals: strings.Split(principals, ","),
+				TTLMinutes: ttl,
+			},
+			Reason: reason,
+		})
+		if err != nil {
+			log.Fatalf("Error requesting certificate: %v", err)
+		}
+		if request.Status == cert.RequestPending {
+			fmt.Printf("Request %s is waiting for %d approval(s)\n", request.ID, request.ApprovalsRequired)
+			fmt.Printf("Once approved, fetch the certificate with: sshtrust request get %s\n", request.ID)
+			return
+		}
+		writeRequestCertificate(request, identityFile)
+	},
+}
+
+// writeRequestCertificate writes an approved request's certificate next to
+// the identity, or prints it without one
+func writeRequestCertificate(request *cert.SigningRequest, identityFile string) {
+	if identityFile == "" {
+		fmt.Print(request.SignedKey)
+		return
+	}
+	if err := identity.WriteCertificate(identityFile, []byte(request.SignedKey)); err != nil {
+		log.Fatalf("Error writing certificate: %v", err)
+	}
+	fmt.Printf("Certificate written to %s\n", identity.CertificatePath(identityFile))
+}
+
+func init() {
+	requestNewCmd.Flags().StringP("name", "n", "", "Name of the CA (defaults to the profile's default CA)")
+	requestNewCmd.Flags().StringP("public_key", "k", "", "Public key to be signed")
+	requestNewCmd.Flags().StringP("identity", "i", "", "Identity file, signs <identity>.pub and writes <identity>-cert.pub if no approval is needed")
+	requestNewCmd.Flags().StringP("principals", "p", "", "Comma-separated list of principals for the certificate")
+	requestNewCmd.Flags().Int("ttl", 60, "Time to live for the certificate in minutes")
+	requestNewCmd.Flags().String("reason", "", "Why the certificate is needed, shown to approvers")
+	_ = requestNewCmd.MarkFlagRequired("principals")
+	requestNewCmd.MarkFlagsMutuallyExclusive("public_key", "identity")
+	requestCmd.AddCommand(requestNewCmd)
+}
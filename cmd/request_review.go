@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+)
+
+var requestApproveCmd = &cobra.Command{
+	Use:   "approve <id>",
+	Short: "Approve a signing request, signing it once it has all its approvals",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		comment, _ := cmd.Flags().GetString("comment")
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		request, err := apiClient.ApproveSigningRequest(cmd.Context(), args[0], comment)
+		if err != nil {
+			log.Fatalf("Error approving signing request: %v", err)
+		}
+		if request.Status == cert.RequestApproved {
+			fmt.Printf("Request %s approved and signed, serial %d\n", request.ID, request.Serial)
+			return
+		}
+		fmt.Printf("Request %s approved, %d of %d approvals\n", request.ID, len(request.Approvals), request.ApprovalsRequired)
+	},
+}
+
+var requestDenyCmd = &cobra.Command{
+	Use:   "deny <id>",
+	Short: "Deny a signing request",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		comment, _ := cmd.Flags().GetString("comment")
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		request, err := apiClient.DenySigningRequest(cmd.Context(), args[0], comment)
+		if err != nil {
+			log.Fatalf("Error denying signing request: %v", err)
+		}
+		fmt.Printf("Request %s denied\n", request.ID)
+	},
+}
+
+func init() {
+	requestApproveCmd.Flags().String("comment", "", "Comment recorded with the approval")
+	requestDenyCmd.Flags().String("comment", "", "Why the request is denied, shown to the requester")
+	requestCmd.AddCommand(requestApproveCmd)
+	requestCmd.AddCommand(requestDenyCmd)
+}
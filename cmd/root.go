@@ -4,12 +4,41 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/lukegriffith/SSHTrust/internal/client"
 	"github.com/spf13/cobra"
 )
 
 var rootCmd = &cobra.Command{
 	Use:   "sshtrust",
 	Short: "CLI for managing SSH Certificate Authorities and starting the CA server",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		profile, _ := cmd.Flags().GetString("profile")
+		server, _ := cmd.Flags().GetString("server")
+
+		config, err := loadConfig()
+		if err != nil {
+			return err
+		}
+		name, p, err := config.Resolve(profile)
+		if err != nil {
+			return err
+		}
+		// --server overrides the server of the selected profile
+		if server != "" {
+			p.Server = server
+		}
+		client.UseProfile(name, p)
+		return nil
+	},
+}
+
+// loadConfig loads the client config from its default location
+func loadConfig() (*client.Config, error) {
+	path, err := client.DefaultConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	return client.LoadConfig(path)
 }
 
 func Execute() {
@@ -18,3 +47,8 @@ func Execute() {
 		os.Exit(1)
 	}
 }
+
+func init() {
+	rootCmd.PersistentFlags().String("server", "", "SSHTrust server URL, overrides the profile's server")
+	rootCmd.PersistentFlags().String("profile", "", "Client profile to use from the config file")
+}
@@ -1,7 +1,15 @@
 package cmd
 
 import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/lukegriffith/SSHTrust/internal/server"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/webhook"
 	"github.com/spf13/cobra"
 )
 
@@ -10,7 +18,61 @@ var serveCmd = &cobra.Command{
 	Short: "Start the server",
 	Run: func(cmd *cobra.Command, args []string) {
 		noAuth, _ := cmd.Flags().GetBool("no-auth")
-		e := server.SetupServer(noAuth)
+		auditLog, _ := cmd.Flags().GetString("audit-log")
+		auditKeyFile, _ := cmd.Flags().GetString("audit-key-file")
+		webhooksFile, _ := cmd.Flags().GetString("webhooks")
+		webhookQueue, _ := cmd.Flags().GetString("webhook-queue")
+		principalsTokenFile, _ := cmd.Flags().GetString("principals-token-file")
+		metricsTokenFile, _ := cmd.Flags().GetString("metrics-token-file")
+
+		var options []server.Option
+		if auditLog != "" {
+			if auditKeyFile == "" {
+				log.Fatal("--audit-key-file is required with --audit-log")
+			}
+			key, err := readTokenFile(auditKeyFile)
+			if err != nil {
+				log.Fatalf("Error reading audit key: %v", err)
+			}
+			logger, err := audit.Open(auditLog, []byte(key))
+			if err != nil {
+				log.Fatalf("Error opening audit log: %v", err)
+			}
+			defer logger.Close()
+			options = append(options, server.WithAuditLog(logger))
+		}
+		if webhooksFile != "" {
+			hooks, err := webhook.LoadHooks(webhooksFile)
+			if err != nil {
+				log.Fatalf("Error loading webhooks: %v", err)
+			}
+			var queue webhook.Queue = webhook.NewMemoryQueue()
+			if webhookQueue != "" {
+				if queue, err = webhook.NewFileQueue(webhookQueue); err != nil {
+					log.Fatalf("Error opening webhook queue: %v", err)
+				}
+			} else {
+				log.Println("No --webhook-queue set, undelivered webhook events are lost on restart")
+			}
+			dispatcher := webhook.NewDispatcher(hooks, queue)
+			go dispatcher.Run(cmd.Context(), 5*time.Second)
+			options = append(options, server.WithWebhooks(dispatcher))
+		}
+		if principalsTokenFile != "" {
+			token, err := readTokenFile(principalsTokenFile)
+			if err != nil {
+				log.Fatalf("Error reading principals token: %v", err)
+			}
+			options = append(options, server.WithPrincipalsToken(token))
+		}
+		if metricsTokenFile != "" {
+			token, err := readTokenFile(metricsTokenFile)
+			if err != nil {
+				log.Fatalf("Error reading metrics token: %v", err)
+			}
+			options = append(options, server.WithMetricsToken(token))
+		}
+		e := server.SetupServer(noAuth, options...)
 		e.Logger.Printf("SSHTrust Started on %s", server.Port)
 		if noAuth {
 			e.Logger.Printf("No auth enabled %t", noAuth)
@@ -22,8 +84,31 @@ var serveCmd = &cobra.Command{
 	},
 }
 
+// minTokenLength keeps tokens read from files long enough not to be guessed
+const minTokenLength = 32
+
+// readTokenFile reads a shared token from path, such as one made with
+// openssl rand -hex 32
+func readTokenFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	token := strings.TrimSpace(string(data))
+	if len(token) < minTokenLength {
+		return "", fmt.Errorf("token in %s is shorter than %d characters", path, minTokenLength)
+	}
+	return token, nil
+}
+
 func init() {
 	serveCmd.Flags().Bool("no-auth", false, "Enable user auth")
+	serveCmd.Flags().String("audit-log", "", "Append security relevant events to this file as hash chained JSON lines, - for stdout")
+	serveCmd.Flags().String("audit-key-file", "", "File holding the key the audit log's hashes are made with, kept where the log's readers can not write")
+	serveCmd.Flags().String("webhooks", "", "YAML file of webhooks to send events to, with their secrets and event types")
+	serveCmd.Flags().String("webhook-queue", "", "Directory to queue webhook deliveries in, so they survive a restart")
+	serveCmd.Flags().String("principals-token-file", "", "File holding the token hosts send to fetch principals without a login")
+	serveCmd.Flags().String("metrics-token-file", "", "File holding the token Prometheus sends to scrape /metrics without a login")
 	rootCmd.AddCommand(serveCmd)
 
 }
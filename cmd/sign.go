@@ -3,10 +3,17 @@ package cmd
 import (
 	"fmt"
 	"log"
+	"os"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/lukegriffith/SSHTrust/internal/client" // Update with the correct import path
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/internal/sshagent"
 	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/term"
 
 	"github.com/spf13/cobra"
 )
@@ -18,36 +25,135 @@ var signCmd = &cobra.Command{
 		// Extract the CA ID and public key from the command arguments
 		caID, _ := cmd.Flags().GetString("name")
 		publicKey, _ := cmd.Flags().GetString("public_key")
+		identityFile, _ := cmd.Flags().GetString("identity")
+		addToAgent, _ := cmd.Flags().GetBool("add-to-agent")
 		principals, _ := cmd.Flags().GetString("principals")
 		ttl, _ := cmd.Flags().GetInt("ttl")
+		validAfter, _ := cmd.Flags().GetString("valid-after")
+		validBefore, _ := cmd.Flags().GetString("valid-before")
+
+		// Fall back to the profile's default CA
+		if caID == "" {
+			_, profile := client.ActiveProfile()
+			caID = profile.DefaultCA
+		}
+		if caID == "" {
+			log.Fatal("CA name is required, pass --name or set a default CA on the profile")
+		}
+		if addToAgent && identityFile == "" {
+			log.Fatal("--add-to-agent requires --identity")
+		}
+
+		// Read the public key from the identity when one is given
+		if identityFile != "" {
+			var err error
+			identityFile, err = identity.Expand(identityFile)
+			if err != nil {
+				log.Fatalf("Error resolving identity: %v", err)
+			}
+			publicKey, err = identity.ReadPublicKey(identityFile)
+			if err != nil {
+				log.Fatalf("Error reading identity: %v", err)
+			}
+		}
+		if publicKey == "" {
+			log.Fatal("A public key is required, pass --public_key or --identity")
+		}
 
 		body := cert.SignRequest{
 			PublicKey:  publicKey,
 			Principals: strings.Split(principals, ","),
 			TTLMinutes: ttl,
 		}
+		if validAfter != "" {
+			start, err := time.Parse(time.RFC3339, validAfter)
+			if err != nil {
+				log.Fatalf("Invalid --valid-after, expected RFC 3339 e.g. 2024-06-01T22:00:00Z: %v", err)
+			}
+			body.ValidAfter = &start
+		}
+		if validBefore != "" {
+			end, err := time.Parse(time.RFC3339, validBefore)
+			if err != nil {
+				log.Fatalf("Invalid --valid-before, expected RFC 3339 e.g. 2024-06-02T02:00:00Z: %v", err)
+			}
+			body.ValidBefore = &end
+			body.TTLMinutes = 0
+		}
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+
 		// Call the client library to sign the public key
-		signedKey, err := client.SignPublicKey(caID, body)
+		signedKey, err := apiClient.Sign(cmd.Context(), caID, body)
 		if err != nil {
 			log.Fatalf("Error signing public key: %v", err)
 		}
 
-		// Display the signed certificate
-		fmt.Print(signedKey.SignedKey)
+		if identityFile == "" {
+			// Display the signed certificate
+			fmt.Print(signedKey.SignedKey)
+			return
+		}
+
+		if err := identity.WriteCertificate(identityFile, []byte(signedKey.SignedKey)); err != nil {
+			log.Fatalf("Error writing certificate: %v", err)
+		}
+		fmt.Printf("Certificate written to %s\n", identity.CertificatePath(identityFile))
+
+		if addToAgent {
+			signedCert, err := identity.ParseCertificate([]byte(signedKey.SignedKey))
+			if err != nil {
+				log.Fatalf("Error parsing certificate: %v", err)
+			}
+			privateKey, err := identity.LoadPrivateKey(identityFile, readPassphrase(identityFile))
+			if err != nil {
+				log.Fatalf("Error loading private key: %v", err)
+			}
+			if err := addCertificateToAgent(privateKey, signedCert); err != nil {
+				log.Fatalf("Error adding certificate to ssh-agent: %v", err)
+			}
+			fmt.Println("Certificate added to ssh-agent")
+		}
 	},
 }
 
+// readPassphrase returns a prompt for the passphrase of an encrypted identity
+func readPassphrase(identityFile string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		fmt.Fprintf(os.Stderr, "Enter passphrase for %s: ", identityFile)
+		passphrase, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Fprintln(os.Stderr)
+		return passphrase, err
+	}
+}
+
+// addCertificateToAgent loads the key and certificate into the running ssh-agent
+func addCertificateToAgent(privateKey interface{}, signedCert *ssh.Certificate) error {
+	sshAgent, conn, err := sshagent.Connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return sshagent.AddCertificate(sshAgent, privateKey, signedCert, time.Now())
+}
+
 func init() {
 	// Add flags
-	signCmd.Flags().StringP("name", "n", "", "Name of the CA")
+	signCmd.Flags().StringP("name", "n", "", "Name of the CA (defaults to the profile's default CA)")
 	signCmd.Flags().StringP("public_key", "k", "", "Public key to be signed")
+	signCmd.Flags().StringP("identity", "i", "", "Identity file, signs <identity>.pub and writes <identity>-cert.pub")
+	signCmd.Flags().Bool("add-to-agent", false, "Add the identity and certificate to ssh-agent, requires --identity")
 	signCmd.Flags().StringP("principals", "p", "", "Comma-separated list of principals for the certificate")
 	signCmd.Flags().Int("ttl", 60, "Time to live for the certificate in minutes")
+	signCmd.Flags().String("valid-after", "", "Start of the certificate's validity (RFC 3339), e.g. a maintenance window, defaults to now")
+	signCmd.Flags().String("valid-before", "", "End of the certificate's validity (RFC 3339), instead of --ttl")
 
 	// Optionally, mark flags as required
-	_ = signCmd.MarkFlagRequired("name")
-	_ = signCmd.MarkFlagRequired("public_key")
 	_ = signCmd.MarkFlagRequired("principals")
+	signCmd.MarkFlagsMutuallyExclusive("public_key", "identity")
+	signCmd.MarkFlagsMutuallyExclusive("ttl", "valid-before")
 	// Register the sign command under the root command
 	rootCmd.AddCommand(signCmd)
 }
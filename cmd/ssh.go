@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"os/user"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+// certificateMargin is how long an existing certificate must remain valid
+// for it to be reused rather than re-signed.
+const certificateMargin = time.Minute
+
+var sshCmd = &cobra.Command{
+	Use:   "ssh [user@]host [command...]",
+	Short: "SSH to a host, signing a certificate first if needed",
+	Long: `SSH to a host, signing a certificate first if needed.
+
+The identity's certificate is reused when it is valid for the login
+principal, otherwise the identity is signed by the CA before running ssh.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		identityFile, _ := cmd.Flags().GetString("identity")
+		principal, _ := cmd.Flags().GetString("principal")
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		port, _ := cmd.Flags().GetString("port")
+		options, _ := cmd.Flags().GetStringArray("option")
+		sshBinary, _ := cmd.Flags().GetString("ssh")
+
+		destination := args[0]
+
+		// Fall back to the profile's default CA
+		if caID == "" {
+			_, profile := client.ActiveProfile()
+			caID = profile.DefaultCA
+		}
+		if caID == "" {
+			log.Fatal("CA name is required, pass --name or set a default CA on the profile")
+		}
+
+		// The login principal is the remote user, as ssh would pick it
+		if principal == "" {
+			if i := strings.LastIndex(destination, "@"); i >= 0 {
+				principal = destination[:i]
+			} else {
+				current, err := user.Current()
+				if err != nil {
+					log.Fatalf("Error finding current user, pass --principal: %v", err)
+				}
+				principal = current.Username
+			}
+		}
+
+		identityFile, err := identity.Expand(identityFile)
+		if err != nil {
+			log.Fatalf("Error resolving identity: %v", err)
+		}
+
+		if err := checkIdentity(cmd, caID, identityFile, principal); err != nil {
+			fmt.Fprintf(os.Stderr, "Signing %s for %s: %v\n", identityFile, principal, err)
+			if err := signIdentity(cmd, caID, identityFile, principal, ttl); err != nil {
+				log.Fatalf("Error signing identity: %v", err)
+			}
+		}
+
+		sshPath, err := exec.LookPath(sshBinary)
+		if err != nil {
+			log.Fatalf("Error finding ssh: %v", err)
+		}
+		sshArgs := []string{
+			sshBinary,
+			"-i", identityFile,
+			"-o", "CertificateFile=" + identity.CertificatePath(identityFile),
+		}
+		if port != "" {
+			sshArgs = append(sshArgs, "-p", port)
+		}
+		for _, option := range options {
+			sshArgs = append(sshArgs, "-o", option)
+		}
+		sshArgs = append(sshArgs, args...)
+
+		// Replace this process with ssh so the terminal and exit code pass
+		// straight through.
+		if err := syscall.Exec(sshPath, sshArgs, os.Environ()); err != nil {
+			log.Fatalf("Error running ssh: %v", err)
+		}
+	},
+}
+
+// checkIdentity returns an error unless the identity's certificate can be
+// reused for principal: signed by the CA for the identity's own key
+func checkIdentity(cmd *cobra.Command, caID, identityFile, principal string) error {
+	existing, err := identity.ReadCertificate(identityFile)
+	if err != nil {
+		return err
+	}
+	apiClient, err := client.New()
+	if err != nil {
+		return err
+	}
+	return checkCertificate(cmd.Context(), apiClient, caID, identityFile, existing, []string{principal}, certificateMargin)
+}
+
+// checkCertificate returns an error unless c is for the identity's key,
+// signed by the CA and valid for each of principals for at least margin
+func checkCertificate(ctx context.Context, apiClient *sshtrust.Client, caID, identityFile string, c *ssh.Certificate, principals []string, margin time.Duration) error {
+	publicKey, err := identity.ReadPublicKey(identityFile)
+	if err != nil {
+		return err
+	}
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		return err
+	}
+	ca, err := apiClient.GetCA(ctx, caID)
+	if err != nil {
+		return err
+	}
+	authorities, err := ca.TrustedKeys()
+	if err != nil {
+		return err
+	}
+	for _, principal := range principals {
+		if err := identity.CheckCertificate(c, key, authorities, principal, time.Now(), margin); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signIdentity signs the identity for principal and writes its certificate
+func signIdentity(cmd *cobra.Command, caID, identityFile, principal string, ttl int) error {
+	publicKey, err := identity.ReadPublicKey(identityFile)
+	if err != nil {
+		return err
+	}
+	apiClient, err := client.New()
+	if err != nil {
+		return err
+	}
+	signedKey, err := apiClient.Sign(cmd.Context(), caID, cert.SignRequest{
+		PublicKey:  publicKey,
+		Principals: []string{principal},
+		TTLMinutes: ttl,
+	})
+	if err != nil {
+		return err
+	}
+	return identity.WriteCertificate(identityFile, []byte(signedKey.SignedKey))
+}
+
+func init() {
+	sshCmd.Flags().StringP("name", "n", "", "Name of the CA (defaults to the profile's default CA)")
+	sshCmd.Flags().StringP("identity", "i", "~/.ssh/id_ed25519", "Identity file to sign and log in with")
+	sshCmd.Flags().String("principal", "", "Principal to sign for (defaults to the remote user)")
+	sshCmd.Flags().Int("ttl", 60, "Time to live for a newly signed certificate in minutes")
+	sshCmd.Flags().StringP("port", "p", "", "Port to connect to on the remote host")
+	sshCmd.Flags().StringArrayP("option", "o", nil, "Option passed to ssh as -o, may be repeated")
+	sshCmd.Flags().String("ssh", "ssh", "ssh binary to run")
+	// Everything after the destination belongs to the remote command
+	sshCmd.Flags().SetInterspersed(false)
+	rootCmd.AddCommand(sshCmd)
+}
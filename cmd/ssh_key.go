@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/lukegriffith/SSHTrust/internal/identity"
+	"github.com/lukegriffith/SSHTrust/internal/sshagent"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+)
+
+var sshKeyCmd = &cobra.Command{
+	Use:   "ssh-key",
+	Short: "Generate a new key pair in ssh-agent and have it signed by a Certificate Authority",
+	Long: `Generate a new key pair and have it signed by a Certificate Authority.
+
+The private key only ever lives in ssh-agent, and is removed from the agent
+when the certificate expires. It is never written to disk.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		caID, _ := cmd.Flags().GetString("name")
+		principals, _ := cmd.Flags().GetString("principals")
+		ttl, _ := cmd.Flags().GetInt("ttl")
+		keyType, _ := cmd.Flags().GetString("type")
+		bits, _ := cmd.Flags().GetInt("bits")
+
+		// Fall back to the profile's default CA
+		if caID == "" {
+			_, profile := client.ActiveProfile()
+			caID = profile.DefaultCA
+		}
+		if caID == "" {
+			log.Fatal("CA name is required, pass --name or set a default CA on the profile")
+		}
+
+		// Connect to the agent up front so a key is never signed without
+		// somewhere to put it.
+		sshAgent, conn, err := sshagent.Connect()
+		if err != nil {
+			log.Fatalf("Error connecting to ssh-agent: %v", err)
+		}
+		defer conn.Close()
+
+		resolvedType, bits := resolveKeyType(keyType, bits, cmd.Flags().Changed("bits"))
+		privateKey, signer, err := cert.GenerateSSHKeyPair(resolvedType, bits)
+		if err != nil {
+			log.Fatalf("Error generating key: %v", err)
+		}
+
+		body := cert.SignRequest{
+			PublicKey:  string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+			Principals: strings.Split(principals, ","),
+			TTLMinutes: ttl,
+		}
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		signedKey, err := apiClient.Sign(cmd.Context(), caID, body)
+		if err != nil {
+			log.Fatalf("Error signing public key: %v", err)
+		}
+		signedCert, err := identity.ParseCertificate([]byte(signedKey.SignedKey))
+		if err != nil {
+			log.Fatalf("Error parsing certificate: %v", err)
+		}
+
+		if err := sshagent.AddCertificate(sshAgent, privateKey, signedCert, time.Now()); err != nil {
+			log.Fatalf("Error adding certificate to ssh-agent: %v", err)
+		}
+		fmt.Printf("Ephemeral key added to ssh-agent, expires %s\n",
+			time.Unix(int64(signedCert.ValidBefore), 0).Local().Format(time.RFC3339))
+	},
+}
+
+func init() {
+	sshKeyCmd.Flags().StringP("name", "n", "", "Name of the CA (defaults to the profile's default CA)")
+	sshKeyCmd.Flags().StringP("principals", "p", "", "Comma-separated list of principals for the certificate")
+	sshKeyCmd.Flags().Int("ttl", 60, "Time to live for the certificate in minutes")
+	sshKeyCmd.Flags().StringP("type", "t", "ssh-ed25519", "Key type (ssh-rsa, ssh-ed25519, ecdsa-sha2-nistp256, ecdsa-sha2-nistp384, ecdsa-sha2-nistp521, or ecdsa with --bits)")
+	sshKeyCmd.Flags().IntP("bits", "b", 3072, "Key size in bits for RSA keys")
+
+	_ = sshKeyCmd.MarkFlagRequired("principals")
+	rootCmd.AddCommand(sshKeyCmd)
+}
@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var trustCmd = &cobra.Command{
+	Use:   "trust",
+	Short: "Fetch trust bundles for configuring hosts",
+}
+
+func init() {
+	rootCmd.AddCommand(trustCmd)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/spf13/cobra"
+)
+
+var trustUserCAKeysCmd = &cobra.Command{
+	Use:   "user-ca-keys",
+	Short: "Print a TrustedUserCAKeys file for sshd",
+	Run: func(cmd *cobra.Command, args []string) {
+		tag, _ := cmd.Flags().GetString("tag")
+		cas, _ := cmd.Flags().GetStringSlice("ca")
+		output, _ := cmd.Flags().GetString("output")
+
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		bundle, err := apiClient.UserCAKeys(cmd.Context(), sshtrust.TrustFilter{Tag: tag, CAs: cas})
+		if err != nil {
+			log.Fatalf("Error retrieving user CA keys: %v", err)
+		}
+		if output == "" {
+			fmt.Print(string(bundle))
+			return
+		}
+		if err := os.WriteFile(output, bundle, 0644); err != nil {
+			log.Fatalf("Error writing %s: %v", output, err)
+		}
+	},
+}
+
+func init() {
+	trustUserCAKeysCmd.Flags().String("tag", "", "Only include CAs with this tag")
+	trustUserCAKeysCmd.Flags().StringSlice("ca", nil, "Only include these CAs, comma separated")
+	trustUserCAKeysCmd.Flags().StringP("output", "o", "", "Write the file here instead of stdout")
+	trustCmd.AddCommand(trustUserCAKeysCmd)
+}
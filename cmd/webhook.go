@@ -0,0 +1,14 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+var webhookCmd = &cobra.Command{
+	Use:   "webhook",
+	Short: "Work with the server's webhooks",
+}
+
+func init() {
+	rootCmd.AddCommand(webhookCmd)
+}
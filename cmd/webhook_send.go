@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/lukegriffith/SSHTrust/internal/client"
+	"github.com/olekukonko/tablewriter"
+	"github.com/spf13/cobra"
+)
+
+var webhookTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Send a test event to every webhook and show how each receiver answered",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiClient, err := client.New()
+		if err != nil {
+			log.Fatalf("Error creating client: %v", err)
+		}
+		results, err := apiClient.TestWebhooks(cmd.Context())
+		if err != nil {
+			log.Fatalf("Error testing webhooks: %v", err)
+		}
+		if len(results) == 0 {
+			fmt.Println("No webhooks configured.")
+			return
+		}
+
+		failed := false
+		table := tablewriter.NewWriter(os.Stdout)
+		table.SetHeader([]string{"URL", "Status", "Result"})
+		for _, result := range results {
+			outcome := "delivered"
+			if result.Error != "" {
+				outcome, failed = result.Error, true
+			}
+			status := ""
+			if result.Status != 0 {
+				status = fmt.Sprintf("%d", result.Status)
+			}
+			table.Append([]string{result.URL, status, outcome})
+		}
+		table.Render()
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	webhookCmd.AddCommand(webhookTestCmd)
+}
@@ -119,7 +119,7 @@ const docTemplate = `{
         },
         "/CA/{id}/Sign": {
             "post": {
-                "description": "Use the specified CA to sign a provided public key and return the signed key.",
+                "description": "Use the specified CA to sign a provided public key and return the signed key. Host CAs sign host certificates, with the principals as host names. User keys have to pass the CA's key policy. Certificates start now, backdated by the CA's allowance, unless valid_after schedules them; valid_before can replace ttl_minutes. The window is bounded by the CA's max TTL and max future start.",
                 "consumes": [
                     "application/json"
                 ],
@@ -156,7 +156,13 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "Invalid request or failed to parse public key",
+                        "description": "Invalid validity window",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Signing request needs approval",
                         "schema": {
                             "$ref": "#/definitions/handlers.ErrorResponse"
                         }
@@ -175,74 +181,1688 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "cert.CaRequest": {
+        },
+        "/CA/{id}/breakglass": {
+            "post": {
+                "description": "Sign a user key without waiting for the CA's approval rules, e.g. during an incident. Only the CA's break-glass responders may, nobody when it has none. A reason and ticket reference are mandatory, the certificate is valid for at most an hour and its key ID starts with BREAK-GLASS. The key policy and valid principals still apply. Every break-glass certificate is logged as a warning and kept for the post-incident review listing.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CAs"
+                ],
+                "summary": "Sign a public key in an emergency",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Public key to be signed, the reason and ticket reference",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.BreakGlassRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/cert.SigningRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Missing reason or ticket, invalid request or failed to parse public key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not a break-glass responder for the CA",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to sign public key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/CA/{id}/krl": {
+            "get": {
+                "description": "Get the certificates revoked by a CA in the OpenSSH KRL format, for use with sshd's RevokedKeys.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "CAs"
+                ],
+                "summary": "Get a CA's key revocation list",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OpenSSH KRL",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to build KRL",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/CA/{id}/requests": {
+            "post": {
+                "description": "Submit a signing request to the CA. Requests matching one of the CA's approval rules wait for approval and are signed once enough approvers have approved them, other requests are signed straight away. The request is checked as it would be by Sign.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Requests"
+                ],
+                "summary": "Request a certificate that needs approval",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Public key to be signed and the reason for the request",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.CreateSigningRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/cert.SigningRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or failed to parse public key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to sign public key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/CA/{id}/revoke": {
+            "post": {
+                "description": "Revoke a certificate by serial number, it will fail verification from then on.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CAs"
+                ],
+                "summary": "Revoke a certificate",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Certificate to revoke",
+                        "name": "serial",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.RevokeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessageResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to revoke certificate",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/CA/{id}/rotate": {
+            "post": {
+                "description": "Generate a new key for the CA. The previous key is kept as a retiring key, still trusted by the trust bundles and verification until the rotation is completed.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CAs"
+                ],
+                "summary": "Rotate a SSH Certificate Authority's key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The CA with its new key",
+                        "schema": {
+                            "$ref": "#/definitions/cert.CaResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/CA/{id}/rotate/complete": {
+            "post": {
+                "description": "Drop the CA's retiring keys once hosts trust the new key and certificates from the old key have expired.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CAs"
+                ],
+                "summary": "Complete a SSH Certificate Authority's key rotation",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "The CA without retiring keys",
+                        "schema": {
+                            "$ref": "#/definitions/cert.CaResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/CA/{id}/verify": {
+            "post": {
+                "description": "Check a user certificate was signed by the CA, is valid now for the principal, is permitted by the CA's principal list and has not been revoked. Certificates with critical options the caller does not list in supported_critical_options, such as verify-required, are rejected.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CAs"
+                ],
+                "summary": "Verify a certificate with a specific CA",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "CA ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Certificate and login principal",
+                        "name": "certificate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.VerifyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "valid is false with a reason when the certificate is rejected",
+                        "schema": {
+                            "$ref": "#/definitions/cert.VerifyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or failed to parse certificate",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/breakglass": {
+            "get": {
+                "description": "List the certificates issued with break-glass signing, oldest first, with who asked for them, why and under which ticket.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "CAs"
+                ],
+                "summary": "List break-glass certificates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only certificates from this CA",
+                        "name": "ca",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only certificates issued at or after this time, RFC3339",
+                        "name": "since",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/cert.SigningRequest"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid since time",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list break-glass certificates",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/certs/inspect": {
+            "post": {
+                "description": "Parse a SSH certificate and report its contents, including whether it was signed by a CA in the store.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Certificates"
+                ],
+                "summary": "Inspect a certificate",
+                "parameters": [
+                    {
+                        "description": "Certificate to inspect",
+                        "name": "certificate",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.InspectRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cert.InspectResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or failed to parse certificate",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts": {
+            "get": {
+                "description": "List the hosts in the registry, with their host keys and when their certificates expire.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "List enrolled hosts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/cert.Host"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/enroll": {
+            "post": {
+                "description": "Exchange a join token for host certificates for each of the host's keys, and record the host in the registry. Authenticated by the join token rather than a login, each token can only be used once. A host already in the registry is only replaced with a reenroll token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Enroll a host",
+                "parameters": [
+                    {
+                        "description": "Join token, host names and host keys",
+                        "name": "enrollment",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.EnrollRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/cert.EnrollResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Invalid request or failed to parse public key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired join token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Host names not allowed by the join token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Host already enrolled",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to sign host key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/renew": {
+            "post": {
+                "description": "Sign a new certificate for an enrolled host key. Authenticated by proof of possession, a signature by the host key over the host name, key and a timestamp within five minutes of the server's clock. The TTL defaults to the one the host enrolled with, bounded by the CA's max host TTL. Hosts whose certificate has expired, or whose host names the CA no longer allows, have to enroll again.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Renew a host certificate",
+                "parameters": [
+                    {
+                        "description": "Host key and proof of possession",
+                        "name": "renewal",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.RenewHostRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/cert.RenewHostResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Requested principals not in valid principal list",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Invalid or expired proof of possession",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Host key not enrolled, or certificate revoked or expired",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Host not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to sign host key",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/tokens": {
+            "post": {
+                "description": "Issue a one time token a host uses to enroll with a host CA, for at least one of its host names. The token is only returned once, the server keeps a hash of it. Hosts already in the registry can only enroll again with a reenroll token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Issue a host join token",
+                "parameters": [
+                    {
+                        "description": "Host CA and host names the token allows",
+                        "name": "token",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/cert.JoinTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/cert.JoinTokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Requested principals not in valid principal list",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to issue join token",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/hosts/{name}": {
+            "get": {
+                "description": "Retrieve a host from the registry by the first host name it enrolled with.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Hosts"
+                ],
+                "summary": "Get an enrolled host",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Host name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cert.Host"
+                        }
+                    },
+                    "404": {
+                        "description": "Host not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/requests": {
+            "get": {
+                "description": "List signing requests oldest first, e.g. the pending requests waiting for approval.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Requests"
+                ],
+                "summary": "List signing requests",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only requests with this status: pending, approved or denied",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Only requests to this CA",
+                        "name": "ca",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/cert.SigningRequest"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to list signing requests",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/requests/{id}": {
+            "get": {
+                "description": "Retrieve a signing request, with its certificate once it is approved.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Requests"
+                ],
+                "summary": "Get a signing request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cert.SigningRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Signing request not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/requests/{id}/approve": {
+            "post": {
+                "description": "Approve a pending signing request. Approvers need a login, can not approve their own requests and are limited by the CA's approval rules. The request is signed once it has all the approvals it needs, checked against the CA as it is then.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Requests"
+                ],
+                "summary": "Approve a signing request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Comment on the approval",
+                        "name": "review",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/cert.ReviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cert.SigningRequest"
+                        }
+                    },
+                    "400": {
+                        "description": "Request can no longer be signed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed to review the request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Signing request not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Request already reviewed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/requests/{id}/deny": {
+            "post": {
+                "description": "Deny a pending signing request, it will not be signed. Anyone who may approve the request may deny it.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Requests"
+                ],
+                "summary": "Deny a signing request",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Request ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Why the request is denied",
+                        "name": "review",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/cert.ReviewRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cert.SigningRequest"
+                        }
+                    },
+                    "403": {
+                        "description": "Not allowed to review the request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Signing request not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Request already reviewed",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/trust/known_hosts": {
+            "get": {
+                "description": "Get a @cert-authority line for each selected host CA and its host patterns, including retiring keys during a rotation, ready to add to known_hosts. Responses carry an ETag, send it back in If-None-Match to get a 304 when nothing changed.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Trust"
+                ],
+                "summary": "Get known_hosts lines for host CAs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only include CAs with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only include these CAs, comma separated or repeated",
+                        "name": "ca",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "known_hosts lines",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/trust/krl": {
+            "get": {
+                "description": "Get the certificates revoked by the selected user CAs, including under retiring keys, as a single OpenSSH KRL for sshd's RevokedKeys. Responses carry an ETag, send it back in If-None-Match to get a 304 when nothing changed.",
+                "produces": [
+                    "application/octet-stream"
+                ],
+                "tags": [
+                    "Trust"
+                ],
+                "summary": "Get a KRL for the selected user CAs",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only include CAs with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only include these CAs, comma separated or repeated",
+                        "name": "ca",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OpenSSH KRL",
+                        "schema": {
+                            "type": "file"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to build KRL",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/trust/principals": {
+            "get": {
+                "description": "Get the principals that may log in to a local account on a host, one per line, for sshd's AuthorizedPrincipalsCommand. User CAs sharing any of the host's tags are consulted, all user CAs when no tags are given. A CA's accounts map principals to accounts, without one each valid principal maps to the account of the same name. Needs a login, or the server's principals token as the bearer token.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Trust"
+                ],
+                "summary": "Get the principals allowed to log in to a local account",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Local account being logged in to",
+                        "name": "user",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Tags of the host, comma separated or repeated",
+                        "name": "tag",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Principals, one per line",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "400": {
+                        "description": "Invalid request",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Missing or invalid login or principals token"
+                    }
+                }
+            }
+        },
+        "/trust/user-ca-keys": {
+            "get": {
+                "description": "Get the public keys of the selected user CAs, including retiring keys during a rotation, as a file ready for sshd's TrustedUserCAKeys. Responses carry an ETag, send it back in If-None-Match to get a 304 when nothing changed.",
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Trust"
+                ],
+                "summary": "Get a TrustedUserCAKeys bundle",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only include CAs with this tag",
+                        "name": "tag",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "Only include these CAs, comma separated or repeated",
+                        "name": "ca",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "TrustedUserCAKeys file",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "304": {
+                        "description": "Not modified"
+                    },
+                    "404": {
+                        "description": "CA not found",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/test": {
+            "post": {
+                "description": "Send a webhook.test event to every configured webhook straight away, without retrying, and report how each receiver answered.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhooks"
+                ],
+                "summary": "Test the webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/webhook.Result"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Failed to send test event",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "cert.ApprovalRule": {
+            "type": "object",
+            "properties": {
+                "approvals": {
+                    "description": "Approvals needed, defaults to one",
+                    "type": "integer"
+                },
+                "approvers": {
+                    "description": "Users who may approve, required. Requesters never approve their own\nrequests, so a rule needs more approvers than approvals to be usable\nby its approvers too.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "principals": {
+                    "description": "Requests for any of these principals need approval",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ttl_over_minutes": {
+                    "description": "Requests for certificates valid longer than this need approval",
+                    "type": "integer"
+                }
+            }
+        },
+        "cert.BreakGlassRequest": {
+            "type": "object",
+            "properties": {
+                "principals": {
+                    "description": "List of valid principals, usernames",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "public_key": {
+                    "description": "Public key material to be signed",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Why the rules are being bypassed, mandatory",
+                    "type": "string"
+                },
+                "ticket": {
+                    "description": "Incident or ticket reference, mandatory",
+                    "type": "string"
+                },
+                "ttl_minutes": {
+                    "description": "How long the certificate is valid for, required unless valid_before is set",
+                    "type": "integer"
+                },
+                "valid_after": {
+                    "description": "Start of the certificate's validity, e.g. a maintenance window.\nDefaults to now, less the CA's backdate allowance.",
+                    "type": "string"
+                },
+                "valid_before": {
+                    "description": "End of the certificate's validity, instead of ttl_minutes",
+                    "type": "string"
+                }
+            }
+        },
+        "cert.CAKind": {
+            "type": "string",
+            "enum": [
+                "user",
+                "host"
+            ],
+            "x-enum-varnames": [
+                "UserCA",
+                "HostCA"
+            ]
+        },
+        "cert.CaRequest": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "description": "Local accounts and the principals that may log in to them. Without it\neach valid principal may log in to the account of the same name.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "approval_rules": {
+                    "description": "Requests matching any rule are queued for approval instead of signed",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cert.ApprovalRule"
+                    }
+                },
+                "backdate_seconds": {
+                    "description": "Seconds certificates are valid before they are signed, for hosts whose\nclocks are behind. At most MaxBackdateSeconds.",
+                    "type": "integer"
+                },
+                "bits": {
+                    "description": "Key length",
+                    "type": "integer"
+                },
+                "break_glass_responders": {
+                    "description": "Users who may sign break-glass certificates, nobody when empty. Only\nfor user CAs.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "host_patterns": {
+                    "description": "Host patterns a host CA is trusted for in known_hosts, e.g. *.example.com",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "key_policy": {
+                    "description": "Restricts the user keys the CA signs, only for user CAs",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.KeyPolicy"
+                        }
+                    ]
+                },
+                "kind": {
+                    "description": "Whether the CA signs user or host certificates, defaults to user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.CAKind"
+                        }
+                    ]
+                },
+                "max_future_start_minutes": {
+                    "description": "Furthest ahead in minutes a requested validity window may start,\ndefaults to DefaultMaxFutureStartMinutes",
+                    "type": "integer"
+                },
+                "max_host_ttl_minutes": {
+                    "description": "Maximum TTL host certificates can be signed for, renewed host\ncertificates usually outlive user certificates. Defaults to\nMaxTTLMinutes, only for host CAs.",
+                    "type": "integer"
+                },
+                "max_ttl_minutes": {
+                    "description": "Maximum TTL certs can be signed for",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of CA",
+                    "type": "string"
+                },
+                "signature_algorithm": {
+                    "description": "Algorithm certificates are signed with. RSA CAs sign with rsa-sha2-512\nby default or rsa-sha2-256, other CAs with their key type.",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Tags used to select CAs, e.g. for host trust bundles",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "description": "Type of ca: ssh-rsa, ssh-ed25519, ecdsa-sha2-nistp256,\necdsa-sha2-nistp384 or ecdsa-sha2-nistp521",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.KeyType"
+                        }
+                    ]
+                },
+                "valid_principals": {
+                    "description": "List of Valid Principals",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "cert.CaResponse": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "description": "Local accounts and the principals that may log in to them. Without it\neach valid principal may log in to the account of the same name.",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "approval_rules": {
+                    "description": "Requests matching any rule are queued for approval instead of signed",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cert.ApprovalRule"
+                    }
+                },
+                "backdate_seconds": {
+                    "description": "Seconds certificates are valid before they are signed, for hosts whose\nclocks are behind. At most MaxBackdateSeconds.",
+                    "type": "integer"
+                },
+                "bits": {
+                    "description": "Key length",
+                    "type": "integer"
+                },
+                "break_glass_responders": {
+                    "description": "Users who may sign break-glass certificates, nobody when empty. Only\nfor user CAs.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "host_patterns": {
+                    "description": "Host patterns a host CA is trusted for in known_hosts, e.g. *.example.com",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "key_policy": {
+                    "description": "Restricts the user keys the CA signs, only for user CAs",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.KeyPolicy"
+                        }
+                    ]
+                },
+                "kind": {
+                    "description": "Whether the CA signs user or host certificates, defaults to user",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.CAKind"
+                        }
+                    ]
+                },
+                "max_future_start_minutes": {
+                    "description": "Furthest ahead in minutes a requested validity window may start,\ndefaults to DefaultMaxFutureStartMinutes",
+                    "type": "integer"
+                },
+                "max_host_ttl_minutes": {
+                    "description": "Maximum TTL host certificates can be signed for, renewed host\ncertificates usually outlive user certificates. Defaults to\nMaxTTLMinutes, only for host CAs.",
+                    "type": "integer"
+                },
+                "max_ttl_minutes": {
+                    "description": "Maximum TTL certs can be signed for",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Name of CA",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "CA Public Key",
+                    "type": "string"
+                },
+                "retiring_public_keys": {
+                    "description": "Previous public keys still trusted while the CA is being rotated",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "signature_algorithm": {
+                    "description": "Algorithm certificates are signed with. RSA CAs sign with rsa-sha2-512\nby default or rsa-sha2-256, other CAs with their key type.",
+                    "type": "string"
+                },
+                "tags": {
+                    "description": "Tags used to select CAs, e.g. for host trust bundles",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "type": {
+                    "description": "Type of ca: ssh-rsa, ssh-ed25519, ecdsa-sha2-nistp256,\necdsa-sha2-nistp384 or ecdsa-sha2-nistp521",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.KeyType"
+                        }
+                    ]
+                },
+                "valid_principals": {
+                    "description": "List of Valid Principals",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "cert.CreateSigningRequest": {
+            "type": "object",
+            "properties": {
+                "principals": {
+                    "description": "List of valid principals, usernames",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "public_key": {
+                    "description": "Public key material to be signed",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Why the certificate is needed, shown to approvers",
+                    "type": "string"
+                },
+                "ttl_minutes": {
+                    "description": "How long the certificate is valid for, required unless valid_before is set",
+                    "type": "integer"
+                },
+                "valid_after": {
+                    "description": "Start of the certificate's validity, e.g. a maintenance window.\nDefaults to now, less the CA's backdate allowance.",
+                    "type": "string"
+                },
+                "valid_before": {
+                    "description": "End of the certificate's validity, instead of ttl_minutes",
+                    "type": "string"
+                }
+            }
+        },
+        "cert.EnrollRequest": {
+            "type": "object",
+            "properties": {
+                "host_keys": {
+                    "description": "Host public keys in authorized key format, as in ssh_host_*_key.pub",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "hostnames": {
+                    "description": "Host names to certify, the first names the host in the registry",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "token": {
+                    "description": "Join token issued by an admin",
+                    "type": "string"
+                }
+            }
+        },
+        "cert.EnrollResponse": {
+            "type": "object",
+            "properties": {
+                "certificates": {
+                    "description": "Signed host certificates, in the order of the request's host keys",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "host": {
+                    "description": "The host as recorded in the registry",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.Host"
+                        }
+                    ]
+                }
+            }
+        },
+        "cert.Host": {
+            "type": "object",
+            "properties": {
+                "ca": {
+                    "description": "Host CA the host's certificates were issued by",
+                    "type": "string"
+                },
+                "enrolled_at": {
+                    "type": "string"
+                },
+                "hostnames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cert.HostKey"
+                    }
+                },
+                "name": {
+                    "description": "First host name the host enrolled with",
+                    "type": "string"
+                },
+                "token_id": {
+                    "description": "Join token the host enrolled with",
+                    "type": "string"
+                },
+                "ttl_minutes": {
+                    "description": "Lifetime of the host's certificates, kept on renewal",
+                    "type": "integer"
+                }
+            }
+        },
+        "cert.HostKey": {
+            "type": "object",
+            "properties": {
+                "fingerprint": {
+                    "type": "string"
+                },
+                "public_key": {
+                    "type": "string"
+                },
+                "serial": {
+                    "type": "integer"
+                },
+                "type": {
+                    "type": "string"
+                },
+                "valid_before": {
+                    "type": "string"
+                }
+            }
+        },
+        "cert.InspectRequest": {
             "type": "object",
             "properties": {
-                "bits": {
-                    "description": "Key length",
-                    "type": "integer"
+                "certificate": {
+                    "description": "Certificate in authorized key format, as written to *-cert.pub",
+                    "type": "string"
+                }
+            }
+        },
+        "cert.InspectResponse": {
+            "type": "object",
+            "properties": {
+                "critical_options": {
+                    "description": "Certificate critical options",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "max_ttl_minutes": {
-                    "description": "Maximum TTL certs can be signed for",
-                    "type": "integer"
+                "extensions": {
+                    "description": "Certificate extensions",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
                 },
-                "name": {
-                    "description": "Name of CA",
+                "key_id": {
+                    "description": "Key ID",
                     "type": "string"
                 },
-                "type": {
-                    "description": "Type of ca, rsa, ed25519",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/cert.KeyType"
-                        }
-                    ]
+                "key_type": {
+                    "description": "Certificate key type, e.g. ssh-ed25519-cert-v01@openssh.com",
+                    "type": "string"
                 },
-                "valid_principals": {
-                    "description": "List of Valid Principals",
+                "matched_ca": {
+                    "description": "Name of the CA in the store that signed the certificate, empty when\nthe signer is not a known CA",
+                    "type": "string"
+                },
+                "principals": {
+                    "description": "Principals the certificate is valid for",
                     "type": "array",
                     "items": {
                         "type": "string"
                     }
+                },
+                "public_key_fingerprint": {
+                    "description": "Fingerprint of the certified public key",
+                    "type": "string"
+                },
+                "serial": {
+                    "description": "Serial number",
+                    "type": "integer"
+                },
+                "signature_algorithm": {
+                    "description": "Algorithm of the CA signature",
+                    "type": "string"
+                },
+                "signing_ca_fingerprint": {
+                    "description": "Fingerprint of the CA key that signed the certificate",
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Certificate type, user or host",
+                    "type": "string"
+                },
+                "valid_after": {
+                    "description": "Start of the validity period",
+                    "type": "string"
+                },
+                "valid_before": {
+                    "description": "End of the validity period, omitted when the certificate never expires",
+                    "type": "string"
                 }
             }
         },
-        "cert.CaResponse": {
+        "cert.JoinTokenRequest": {
             "type": "object",
             "properties": {
-                "bits": {
-                    "description": "Key length",
+                "ca": {
+                    "description": "Host CA the token enrolls hosts with",
+                    "type": "string"
+                },
+                "expires_in_minutes": {
+                    "description": "How long the token can be used for, defaults to an hour",
                     "type": "integer"
                 },
-                "max_ttl_minutes": {
-                    "description": "Maximum TTL certs can be signed for",
+                "hostnames": {
+                    "description": "Host names the enrolling host may ask for, at least one",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "reenroll": {
+                    "description": "Lets a host already in the registry enroll again, replacing its keys,\ne.g. after it was rebuilt",
+                    "type": "boolean"
+                },
+                "ttl_minutes": {
+                    "description": "How long the issued host certificates are valid for",
                     "type": "integer"
+                }
+            }
+        },
+        "cert.JoinTokenResponse": {
+            "type": "object",
+            "properties": {
+                "ca": {
+                    "type": "string"
                 },
-                "name": {
-                    "description": "Name of CA",
+                "expires_at": {
                     "type": "string"
                 },
-                "public_key": {
-                    "description": "CA Public Key",
+                "hostnames": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "description": "Identifies the token in the host registry, the secret is not stored",
                     "type": "string"
                 },
-                "type": {
-                    "description": "Type of ca, rsa, ed25519",
-                    "allOf": [
-                        {
-                            "$ref": "#/definitions/cert.KeyType"
-                        }
-                    ]
+                "reenroll": {
+                    "type": "boolean"
                 },
-                "valid_principals": {
-                    "description": "List of Valid Principals",
+                "token": {
+                    "description": "One time secret handed to the enrolling host",
+                    "type": "string"
+                }
+            }
+        },
+        "cert.KeyPolicy": {
+            "type": "object",
+            "properties": {
+                "allowed_types": {
+                    "description": "Key types the CA signs, defaults to all UserKeyTypes",
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "$ref": "#/definitions/cert.KeyType"
+                    }
+                },
+                "min_bits": {
+                    "description": "Minimum key length in bits by key type, e.g. {\"ssh-rsa\": 3072}",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "integer"
                     }
+                },
+                "require_security_key": {
+                    "description": "Only sign keys backed by a security key, sk-ssh-ed25519@openssh.com\nand sk-ecdsa-sha2-nistp256@openssh.com",
+                    "type": "boolean"
+                },
+                "require_user_verification": {
+                    "description": "Require a PIN or biometric check on the security key as well as a\ntouch, certificates get the verify-required critical option. Only\nsecurity key backed keys are signed.",
+                    "type": "boolean"
                 }
             }
         },
@@ -250,13 +1870,91 @@ const docTemplate = `{
             "type": "string",
             "enum": [
                 "ssh-rsa",
-                "ssh-ed25519"
+                "ssh-ed25519",
+                "ecdsa-sha2-nistp256",
+                "ecdsa-sha2-nistp384",
+                "ecdsa-sha2-nistp521"
             ],
             "x-enum-varnames": [
                 "RSAKey",
-                "ED25519"
+                "ED25519",
+                "ECDSAP256",
+                "ECDSAP384",
+                "ECDSAP521"
             ]
         },
+        "cert.RenewHostRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Name of the host in the registry",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "Host public key to renew the certificate of, in authorized key format",
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "Signature of RenewProof by the host key, base64 encoded SSH signature",
+                    "type": "string"
+                },
+                "timestamp": {
+                    "description": "Unix time the proof was signed at",
+                    "type": "integer"
+                },
+                "ttl_minutes": {
+                    "description": "How long the certificate is valid for, the enrolled TTL when zero",
+                    "type": "integer"
+                }
+            }
+        },
+        "cert.RenewHostResponse": {
+            "type": "object",
+            "properties": {
+                "certificate": {
+                    "description": "Renewed host certificate",
+                    "type": "string"
+                },
+                "serial": {
+                    "type": "integer"
+                },
+                "valid_before": {
+                    "type": "string"
+                }
+            }
+        },
+        "cert.Review": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "by": {
+                    "type": "string"
+                },
+                "comment": {
+                    "type": "string"
+                }
+            }
+        },
+        "cert.ReviewRequest": {
+            "type": "object",
+            "properties": {
+                "comment": {
+                    "description": "Shown with the review, e.g. why a request was denied",
+                    "type": "string"
+                }
+            }
+        },
+        "cert.RevokeRequest": {
+            "type": "object",
+            "properties": {
+                "serial": {
+                    "description": "Serial number of the certificate to revoke",
+                    "type": "integer"
+                }
+            }
+        },
         "cert.SignRequest": {
             "type": "object",
             "properties": {
@@ -272,20 +1970,154 @@ const docTemplate = `{
                     "type": "string"
                 },
                 "ttl_minutes": {
-                    "description": "How long the certificate is valid for",
+                    "description": "How long the certificate is valid for, required unless valid_before is set",
                     "type": "integer"
+                },
+                "valid_after": {
+                    "description": "Start of the certificate's validity, e.g. a maintenance window.\nDefaults to now, less the CA's backdate allowance.",
+                    "type": "string"
+                },
+                "valid_before": {
+                    "description": "End of the certificate's validity, instead of ttl_minutes",
+                    "type": "string"
                 }
             }
         },
         "cert.SignResponse": {
             "type": "object",
             "properties": {
+                "serial": {
+                    "description": "Serial number of the certificate, used to revoke it",
+                    "type": "integer"
+                },
                 "signed_key": {
                     "description": "Signed certificate by the CA",
                     "type": "string"
                 }
             }
         },
+        "cert.SigningRequest": {
+            "type": "object",
+            "properties": {
+                "approvals": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/cert.Review"
+                    }
+                },
+                "approvals_required": {
+                    "description": "Approvals needed before the request is signed",
+                    "type": "integer"
+                },
+                "approvers": {
+                    "description": "Users who may approve, nobody when empty",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "break_glass": {
+                    "description": "Issued in an emergency without approval, see BreakGlassRequest",
+                    "type": "boolean"
+                },
+                "ca": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "denial": {
+                    "$ref": "#/definitions/cert.Review"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key_id": {
+                    "type": "string"
+                },
+                "reason": {
+                    "type": "string"
+                },
+                "request": {
+                    "description": "The request as submitted",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/cert.SignRequest"
+                        }
+                    ]
+                },
+                "requester": {
+                    "type": "string"
+                },
+                "serial": {
+                    "type": "integer"
+                },
+                "signed_key": {
+                    "description": "Certificate issued once the request is approved",
+                    "type": "string"
+                },
+                "status": {
+                    "$ref": "#/definitions/cert.SigningRequestStatus"
+                },
+                "ticket": {
+                    "type": "string"
+                }
+            }
+        },
+        "cert.SigningRequestStatus": {
+            "type": "string",
+            "enum": [
+                "pending",
+                "approved",
+                "denied"
+            ],
+            "x-enum-varnames": [
+                "RequestPending",
+                "RequestApproved",
+                "RequestDenied"
+            ]
+        },
+        "cert.VerifyRequest": {
+            "type": "object",
+            "properties": {
+                "certificate": {
+                    "description": "Certificate in authorized key format, as written to *-cert.pub",
+                    "type": "string"
+                },
+                "principal": {
+                    "description": "Principal the certificate is being used to log in as",
+                    "type": "string"
+                },
+                "supported_critical_options": {
+                    "description": "Critical options the caller enforces itself, such as verify-required.\nCertificates with any other critical option are rejected.",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "cert.VerifyResponse": {
+            "type": "object",
+            "properties": {
+                "key_id": {
+                    "description": "Key ID of the certificate",
+                    "type": "string"
+                },
+                "reason": {
+                    "description": "Why the certificate was rejected",
+                    "type": "string"
+                },
+                "serial": {
+                    "description": "Serial number of the certificate",
+                    "type": "integer"
+                },
+                "valid": {
+                    "description": "Whether the certificate is accepted",
+                    "type": "boolean"
+                }
+            }
+        },
         "handlers.ErrorResponse": {
             "type": "object",
             "properties": {
@@ -293,6 +2125,28 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "handlers.MessageResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "webhook.Result": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`
@@ -1,89 +1,60 @@
 package client
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
 	"path/filepath"
 
 	"github.com/lukegriffith/SSHTrust/pkg/auth"
-	"github.com/lukegriffith/SSHTrust/pkg/handlers"
 )
 
-var TokenLocation = ".sshtrust.token"
-
-type TokenResponse struct {
-	Token string `json:"token"` // Adjust this if the token key in the response JSON is different
-}
-
-func Register(body auth.User) error {
-	jsonValue, _ := json.Marshal(body)
-	resp, err := http.Post("http://localhost:8080/register", "application/json", bytes.NewBuffer(jsonValue))
+func Register(ctx context.Context, body auth.User) error {
+	c, err := New()
 	if err != nil {
-		return fmt.Errorf("failed to register: %w", err)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var errorMessage handlers.ErrorResponse
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("error unmarshalling api error: %v", err)
-		}
-
-		err = json.Unmarshal(bodyBytes, &errorMessage)
-		return fmt.Errorf("failed to register: %v - %s", resp.StatusCode, errorMessage)
+		return err
 	}
-	defer resp.Body.Close()
-	// Read the response body
-	return nil
+	return c.Register(ctx, body)
 }
 
-func Login(body auth.User) error {
-
-	homeDir, err := os.UserHomeDir()
+// Login authenticates against the active profile and stores the token
+func Login(ctx context.Context, body auth.User) error {
+	tokenFilePath, err := TokenPath(activeName)
 	if err != nil {
 		return err
 	}
-	jsonValue, _ := json.Marshal(body)
-	resp, err := http.Post("http://localhost:8080/login", "application/json", bytes.NewBuffer(jsonValue))
-	if err != nil {
-		return fmt.Errorf("failed to login: %w", err)
-	}
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		var errorMessage handlers.ErrorResponse
-		bodyBytes, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return fmt.Errorf("error unmarshalling api error: %v", err)
-		}
-
-		err = json.Unmarshal(bodyBytes, &errorMessage)
-		return fmt.Errorf("failed to login: %v - %s", resp.StatusCode, errorMessage)
-	}
-	defer resp.Body.Close()
-	// Read the response body
-	bodyResp, err := io.ReadAll(resp.Body)
+	c, err := New()
 	if err != nil {
-		return fmt.Errorf("Error reading response body %w", err)
+		return err
 	}
-	var tokenResp TokenResponse
-	err = json.Unmarshal(bodyResp, &tokenResp)
+	token, err := c.Login(ctx, body)
 	if err != nil {
-		return fmt.Errorf("Error parsing JSON: %w", err)
+		return err
 	}
 
-	tokenFilePath := filepath.Join(homeDir, TokenLocation)
 	// Write the token to a file
-	err = writeTokenToFile(tokenResp.Token, tokenFilePath)
+	err = writeTokenToFile(token, tokenFilePath)
 	if err != nil {
 		return fmt.Errorf("Error writing token to file %w", err)
 	}
 	return nil
 }
 
+// TokenPath returns the file the login token for profile is stored in
+func TokenPath(profile string) (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tokens", profile+".token"), nil
+}
+
 func writeTokenToFile(token, filePath string) error {
-	file, err := os.Create(filePath)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
 	if err != nil {
 		return err
 	}
@@ -97,18 +68,20 @@ func writeTokenToFile(token, filePath string) error {
 	return nil
 }
 
+// readToken returns the active profile's token, or an empty token when
+// the user has not logged in so that no-auth servers still work.
 func readToken() (string, error) {
-	// Get the home directory
-	homeDir, err := os.UserHomeDir()
+	// Build the full path to the active profile's token file
+	tokenFilePath, err := TokenPath(activeName)
 	if err != nil {
-		return "", fmt.Errorf("could not get home directory: %v", err)
+		return "", err
 	}
 
-	// Build the full path to the token file
-	tokenFilePath := filepath.Join(homeDir, TokenLocation)
-
 	// Read the file contents
 	tokenBytes, err := os.ReadFile(tokenFilePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
 	if err != nil {
 		return "", fmt.Errorf("could not read token file: %v", err)
 	}
@@ -0,0 +1,18 @@
+package client
+
+import (
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+)
+
+// New returns an API client for the active profile, authenticated with the
+// token stored by Login.
+func New() (*sshtrust.Client, error) {
+	httpClient, err := httpClient()
+	if err != nil {
+		return nil, err
+	}
+	c := sshtrust.New(activeProfile.Server)
+	c.HTTPClient = httpClient
+	c.TokenSource = sshtrust.TokenFunc(readToken)
+	return c, nil
+}
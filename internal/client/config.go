@@ -0,0 +1,166 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DefaultProfileName is used when neither the config file nor the
+	// command line selects a profile.
+	DefaultProfileName = "default"
+	// DefaultServer is the server used by the default profile when it has
+	// not been configured.
+	DefaultServer = "http://localhost:8080"
+)
+
+// Profile describes a single SSHTrust server the CLI can talk to
+type Profile struct {
+	// Base URL of the SSHTrust server
+	Server string `yaml:"server"`
+	// PEM encoded CA bundle used to verify the server's TLS certificate
+	CABundle string `yaml:"ca_bundle,omitempty"`
+	// CA used when a command is not given one explicitly
+	DefaultCA string `yaml:"default_ca,omitempty"`
+	// Identities kept signed by `sshtrust agent`
+	Identities []Identity `yaml:"identities,omitempty"`
+}
+
+// Identity is a key the renewal agent keeps signed
+type Identity struct {
+	// Private key file, the public key is read from <path>.pub
+	Path string `yaml:"path"`
+	// CA to sign with, defaults to the profile's default CA
+	CA string `yaml:"ca,omitempty"`
+	// Principals requested for the certificate
+	Principals []string `yaml:"principals"`
+	// Requested certificate lifetime
+	TTLMinutes int `yaml:"ttl_minutes,omitempty"`
+	// Refresh the key and certificate in ssh-agent on every renewal
+	AddToAgent bool `yaml:"add_to_agent,omitempty"`
+}
+
+// Config is the on disk client configuration, holding named profiles
+type Config struct {
+	// Profile used when --profile is not passed
+	CurrentProfile string `yaml:"current_profile,omitempty"`
+	// Named profiles
+	Profiles map[string]Profile `yaml:"profiles,omitempty"`
+}
+
+var (
+	activeName    = DefaultProfileName
+	activeProfile = Profile{Server: DefaultServer}
+)
+
+// ConfigDir returns the directory holding the client config and tokens,
+// $XDG_CONFIG_HOME/sshtrust or ~/.config/sshtrust.
+func ConfigDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "sshtrust"), nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".config", "sshtrust"), nil
+}
+
+// DefaultConfigPath returns the location of config.yaml in ConfigDir
+func DefaultConfigPath() (string, error) {
+	dir, err := ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.yaml"), nil
+}
+
+// LoadConfig reads the config file at path. A missing file is not an error
+// and results in an empty config.
+func LoadConfig(path string) (*Config, error) {
+	config := &Config{Profiles: map[string]Profile{}}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return config, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	if config.Profiles == nil {
+		config.Profiles = map[string]Profile{}
+	}
+	return config, nil
+}
+
+// Save writes the config to path, creating the parent directory if needed
+func (c *Config) Save(path string) error {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Errorf("could not encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("could not create config directory: %w", err)
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// Resolve returns the profile selected by name, falling back to the
+// current profile and then the default profile when name is empty.
+func (c *Config) Resolve(name string) (string, Profile, error) {
+	if name == "" {
+		name = c.CurrentProfile
+	}
+	if name == "" {
+		name = DefaultProfileName
+	}
+	profile, ok := c.Profiles[name]
+	if !ok {
+		if name != DefaultProfileName {
+			return "", Profile{}, fmt.Errorf("profile %q not found", name)
+		}
+		profile = Profile{}
+	}
+	if profile.Server == "" {
+		profile.Server = DefaultServer
+	}
+	return name, profile, nil
+}
+
+// UseProfile sets the profile used by all subsequent API calls
+func UseProfile(name string, profile Profile) {
+	activeName = name
+	activeProfile = profile
+}
+
+// ActiveProfile returns the profile used for API calls
+func ActiveProfile() (string, Profile) {
+	return activeName, activeProfile
+}
+
+// httpClient returns a client trusting the active profile's CA bundle
+func httpClient() (*http.Client, error) {
+	if activeProfile.CABundle == "" {
+		return &http.Client{}, nil
+	}
+	pem, err := os.ReadFile(activeProfile.CABundle)
+	if err != nil {
+		return nil, fmt.Errorf("could not read CA bundle: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %s", activeProfile.CABundle)
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	return &http.Client{Transport: transport}, nil
+}
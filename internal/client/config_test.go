@@ -0,0 +1,78 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadConfigMissingFile(t *testing.T) {
+	config, err := LoadConfig(filepath.Join(t.TempDir(), "config.yaml"))
+	assert.NoError(t, err)
+	assert.Empty(t, config.Profiles)
+
+	// With nothing configured the default profile points at localhost
+	name, profile, err := config.Resolve("")
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultProfileName, name)
+	assert.Equal(t, DefaultServer, profile.Server)
+}
+
+func TestConfigSaveAndResolve(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sshtrust", "config.yaml")
+	config := &Config{
+		CurrentProfile: "prod",
+		Profiles: map[string]Profile{
+			"prod":    {Server: "https://sshtrust.example.com", CABundle: "/etc/ssl/ca.pem", DefaultCA: "prodca"},
+			"staging": {Server: "https://staging.example.com"},
+		},
+	}
+	assert.NoError(t, config.Save(path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	loaded, err := LoadConfig(path)
+	assert.NoError(t, err)
+
+	name, profile, err := loaded.Resolve("")
+	assert.NoError(t, err)
+	assert.Equal(t, "prod", name)
+	assert.Equal(t, "prodca", profile.DefaultCA)
+
+	name, profile, err = loaded.Resolve("staging")
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", name)
+	assert.Equal(t, "https://staging.example.com", profile.Server)
+
+	_, _, err = loaded.Resolve("missing")
+	assert.Error(t, err)
+}
+
+func TestTokenPathPerProfile(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	UseProfile("prod", Profile{Server: "https://sshtrust.example.com"})
+	defer UseProfile(DefaultProfileName, Profile{Server: DefaultServer})
+
+	path, err := TokenPath("prod")
+	assert.NoError(t, err)
+	assert.NoError(t, writeTokenToFile("prod-token", path))
+
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	token, err := readToken()
+	assert.NoError(t, err)
+	assert.Equal(t, "prod-token", token)
+
+	// Other profiles do not see the token
+	UseProfile("staging", Profile{Server: "https://staging.example.com"})
+	token, err = readToken()
+	assert.NoError(t, err)
+	assert.Empty(t, token)
+}
@@ -0,0 +1,88 @@
+// Package filecache keeps the last good response from the server on disk,
+// so host integrations called by sshd keep working while it is down.
+package filecache
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache stores named responses as files in Dir
+type Cache struct {
+	Dir string
+	// How old a stored response may be and still be used, zero for no limit
+	MaxAge time.Duration
+
+	now    func() time.Time
+	logger *log.Logger
+}
+
+// New returns a Cache storing files in dir
+func New(dir string, maxAge time.Duration) *Cache {
+	return &Cache{Dir: dir, MaxAge: maxAge, now: time.Now, logger: log.Default()}
+}
+
+// Fetch calls fetch and stores its result under name. When fetch fails the
+// stored result is returned instead, as long as it is younger than MaxAge,
+// and cached is true. A fresh result that can not be stored is still
+// returned, with a warning logged.
+func (c *Cache) Fetch(name string, fetch func() ([]byte, error)) (data []byte, cached bool, err error) {
+	path, err := c.path(name)
+	if err != nil {
+		return nil, false, err
+	}
+	data, fetchErr := fetch()
+	if fetchErr == nil {
+		if err := c.write(path, data); err != nil {
+			c.logger.Printf("warning: could not cache response in %s: %v", c.Dir, err)
+		}
+		return data, false, nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, false, fetchErr
+	}
+	if c.MaxAge > 0 && c.now().Sub(info.ModTime()) > c.MaxAge {
+		return nil, false, fmt.Errorf("%w, cached response is older than %s", fetchErr, c.MaxAge)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		return nil, false, fetchErr
+	}
+	return data, true, nil
+}
+
+func (c *Cache) path(name string) (string, error) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return "", errors.New("invalid cache name")
+	}
+	return filepath.Join(c.Dir, name), nil
+}
+
+func (c *Cache) write(path string, data []byte) error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
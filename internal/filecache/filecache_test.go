@@ -0,0 +1,64 @@
+package filecache
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetch(t *testing.T) {
+	cache := New(t.TempDir(), time.Hour)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	serverDown := errors.New("connection refused")
+
+	data, cached, err := cache.Fetch("alice", func() ([]byte, error) { return []byte("alice\n"), nil })
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, "alice\n", string(data))
+
+	// Failures fall back to the stored response
+	data, cached, err = cache.Fetch("alice", func() ([]byte, error) { return nil, serverDown })
+	assert.NoError(t, err)
+	assert.True(t, cached)
+	assert.Equal(t, "alice\n", string(data))
+
+	// Nothing stored, nothing to fall back to
+	_, _, err = cache.Fetch("bob", func() ([]byte, error) { return nil, serverDown })
+	assert.ErrorIs(t, err, serverDown)
+
+	// Stored responses expire
+	now = now.Add(2 * time.Hour)
+	_, _, err = cache.Fetch("alice", func() ([]byte, error) { return nil, serverDown })
+	assert.ErrorIs(t, err, serverDown)
+	assert.ErrorContains(t, err, "older than")
+}
+
+func TestFetchUnwritableDir(t *testing.T) {
+	// A file where the cache directory should be, unwritable even as root
+	file := filepath.Join(t.TempDir(), "file")
+	assert.NoError(t, os.WriteFile(file, nil, 0644))
+	var logged bytes.Buffer
+	cache := New(filepath.Join(file, "principals"), time.Hour)
+	cache.logger = log.New(&logged, "", 0)
+
+	data, cached, err := cache.Fetch("alice", func() ([]byte, error) { return []byte("alice\n"), nil })
+	assert.NoError(t, err)
+	assert.False(t, cached)
+	assert.Equal(t, "alice\n", string(data))
+	assert.Contains(t, logged.String(), "could not cache response")
+}
+
+func TestFetchInvalidName(t *testing.T) {
+	cache := New(t.TempDir(), 0)
+	for _, name := range []string{"", "..", "../etc/passwd", "a/b"} {
+		_, _, err := cache.Fetch(name, func() ([]byte, error) { return []byte("x"), nil })
+		assert.Error(t, err, name)
+	}
+}
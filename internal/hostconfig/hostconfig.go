@@ -0,0 +1,394 @@
+// Package hostconfig renders the files a host needs to trust SSHTrust CAs
+// in sshd, and writes them or reports how the host has drifted from them.
+package hostconfig
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+)
+
+const (
+	DefaultConfigDir  = "/etc/ssh/sshtrust"
+	DefaultDropInPath = "/etc/ssh/sshd_config.d/10-sshtrust.conf"
+	DefaultSSHDConfig = "/etc/ssh/sshd_config"
+
+	PrincipalsCommand = "command"
+	PrincipalsFile    = "file"
+)
+
+// Options describe how the host should be configured
+type Options struct {
+	// CAs the host trusts
+	Filter sshtrust.TrustFilter
+	// Directory for the CA bundle, KRL and principals files
+	ConfigDir string
+	// sshd_config.d drop-in to write
+	DropInPath string
+	// How sshd finds principals: PrincipalsCommand, PrincipalsFile, or
+	// empty to leave it to sshd's defaults
+	Principals string
+	// Accounts to write principals files for, with PrincipalsFile
+	Accounts []string
+	// AuthorizedPrincipalsCommand and its user, with PrincipalsCommand
+	PrincipalsCommand     string
+	PrincipalsCommandUser string
+	// Directory the principals command caches answers in, created for its
+	// user with PrincipalsCommand
+	CacheDir string
+	// Host certificates for sshd to present
+	HostCertificates []string
+}
+
+// File is a file the host should have
+type File struct {
+	Path string
+	Data []byte
+	Mode os.FileMode
+	// Dir files are directories, without data
+	Dir bool
+	// User owning the file, left alone when empty
+	Owner string
+}
+
+// Drift is a difference between the host and the files it should have
+type Drift struct {
+	Path   string
+	Reason string
+}
+
+func (d Drift) String() string {
+	return d.Path + ": " + d.Reason
+}
+
+func (o Options) userCAKeysPath() string {
+	return filepath.Join(o.ConfigDir, "trusted_user_ca_keys")
+}
+
+func (o Options) revokedKeysPath() string {
+	return filepath.Join(o.ConfigDir, "revoked_keys")
+}
+
+func (o Options) principalsDir() string {
+	return filepath.Join(o.ConfigDir, "principals")
+}
+
+// Plan fetches the trust bundles from the server and renders every file
+// the host should have.
+func Plan(ctx context.Context, c *sshtrust.Client, opts Options) ([]File, error) {
+	if opts.ConfigDir == "" {
+		opts.ConfigDir = DefaultConfigDir
+	}
+	if opts.DropInPath == "" {
+		opts.DropInPath = DefaultDropInPath
+	}
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	userCAKeys, err := c.UserCAKeys(ctx, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	revokedKeys, err := c.TrustKRL(ctx, opts.Filter)
+	if err != nil {
+		return nil, err
+	}
+	files := []File{
+		{Path: opts.userCAKeysPath(), Data: userCAKeys, Mode: 0644},
+		{Path: opts.revokedKeysPath(), Data: revokedKeys, Mode: 0644},
+	}
+
+	if opts.Principals == PrincipalsCommand && opts.CacheDir != "" {
+		files = append(files, File{Path: opts.CacheDir, Dir: true, Mode: 0755, Owner: opts.PrincipalsCommandUser})
+	}
+
+	if opts.Principals == PrincipalsFile {
+		var tags []string
+		if opts.Filter.Tag != "" {
+			tags = []string{opts.Filter.Tag}
+		}
+		accounts := append([]string{}, opts.Accounts...)
+		sort.Strings(accounts)
+		for _, account := range accounts {
+			principals, err := c.Principals(ctx, account, tags)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, File{Path: filepath.Join(opts.principalsDir(), account), Data: principals, Mode: 0644})
+		}
+	}
+
+	files = append(files, File{Path: opts.DropInPath, Data: DropIn(opts), Mode: 0644})
+	return files, nil
+}
+
+func (o Options) validate() error {
+	switch o.Principals {
+	case "":
+	case PrincipalsCommand:
+		if o.PrincipalsCommand == "" || o.PrincipalsCommandUser == "" {
+			return fmt.Errorf("the principals command and its user are required")
+		}
+	case PrincipalsFile:
+		for _, account := range o.Accounts {
+			// Accounts name files in the principals directory
+			if account == "" || account == "." || account == ".." || strings.ContainsAny(account, "/\x00") {
+				return fmt.Errorf("invalid account %q", account)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown principals mode %q", o.Principals)
+	}
+	return nil
+}
+
+// DropIn renders the sshd_config.d drop-in. sshd keeps the first value it
+// reads for most keywords, so it should sort before other drop-ins.
+func DropIn(opts Options) []byte {
+	if opts.ConfigDir == "" {
+		opts.ConfigDir = DefaultConfigDir
+	}
+	var b bytes.Buffer
+	b.WriteString("# Managed by sshtrust host configure, changes will be overwritten\n")
+	fmt.Fprintf(&b, "TrustedUserCAKeys %s\n", opts.userCAKeysPath())
+	fmt.Fprintf(&b, "RevokedKeys %s\n", opts.revokedKeysPath())
+	switch opts.Principals {
+	case PrincipalsCommand:
+		fmt.Fprintf(&b, "AuthorizedPrincipalsCommand %s\n", opts.PrincipalsCommand)
+		fmt.Fprintf(&b, "AuthorizedPrincipalsCommandUser %s\n", opts.PrincipalsCommandUser)
+	case PrincipalsFile:
+		fmt.Fprintf(&b, "AuthorizedPrincipalsFile %s\n", filepath.Join(opts.principalsDir(), "%u"))
+	}
+	for _, hostCertificate := range opts.HostCertificates {
+		fmt.Fprintf(&b, "HostCertificate %s\n", hostCertificate)
+	}
+	return b.Bytes()
+}
+
+// Check compares the host against files without changing anything
+func Check(files []File) ([]Drift, error) {
+	drift := []Drift{}
+	for _, f := range files {
+		info, err := os.Stat(f.Path)
+		if os.IsNotExist(err) {
+			drift = append(drift, Drift{f.Path, "missing"})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if f.Dir != info.IsDir() {
+			if f.Dir {
+				drift = append(drift, Drift{f.Path, "not a directory"})
+			} else {
+				drift = append(drift, Drift{f.Path, "is a directory"})
+			}
+			continue
+		}
+		if !f.Dir {
+			existing, err := os.ReadFile(f.Path)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(existing, f.Data) {
+				drift = append(drift, Drift{f.Path, "content differs from the server"})
+			}
+		}
+		if info.Mode().Perm() != f.Mode {
+			drift = append(drift, Drift{f.Path, fmt.Sprintf("mode is %04o, expected %04o", info.Mode().Perm(), f.Mode)})
+		}
+		if f.Owner != "" {
+			uid, _, err := lookupOwner(f.Owner)
+			if err != nil {
+				return nil, err
+			}
+			if stat, ok := info.Sys().(*syscall.Stat_t); ok && int(stat.Uid) != uid {
+				drift = append(drift, Drift{f.Path, fmt.Sprintf("not owned by %s", f.Owner)})
+			}
+		}
+	}
+	return drift, nil
+}
+
+// CheckInclude reports drift unless sshdConfig Includes dropIn, without which
+// sshd never reads it. Relative Include patterns are resolved against the
+// directory of sshdConfig, as sshd does for /etc/ssh.
+func CheckInclude(sshdConfig, dropIn string) ([]Drift, error) {
+	data, err := os.ReadFile(sshdConfig)
+	if err != nil {
+		return nil, fmt.Errorf("could not read sshd config: %w", err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.EqualFold(fields[0], "Include") {
+			continue
+		}
+		for _, pattern := range fields[1:] {
+			if !filepath.IsAbs(pattern) {
+				pattern = filepath.Join(filepath.Dir(sshdConfig), pattern)
+			}
+			if matched, _ := filepath.Match(pattern, dropIn); matched {
+				return []Drift{}, nil
+			}
+		}
+	}
+	return []Drift{{sshdConfig, fmt.Sprintf("does not Include %s", dropIn)}}, nil
+}
+
+// lookupOwner returns the uid and primary gid of the user name
+func lookupOwner(name string) (int, int, error) {
+	owner, err := user.Lookup(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	uid, err := strconv.Atoi(owner.Uid)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid uid for %s", name)
+	}
+	gid, err := strconv.Atoi(owner.Gid)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid gid for %s", name)
+	}
+	return uid, gid, nil
+}
+
+// Apply writes the files that differ from the host, each replaced
+// atomically, and returns the paths it changed.
+func Apply(files []File) ([]string, error) {
+	drift, err := Check(files)
+	if err != nil {
+		return nil, err
+	}
+	drifted := map[string]bool{}
+	for _, d := range drift {
+		drifted[d.Path] = true
+	}
+
+	changed := []string{}
+	for _, f := range files {
+		if !drifted[f.Path] {
+			continue
+		}
+		if err := writeFile(f); err != nil {
+			return changed, fmt.Errorf("could not write %s: %w", f.Path, err)
+		}
+		changed = append(changed, f.Path)
+	}
+	return changed, nil
+}
+
+func writeFile(f File) error {
+	if f.Dir {
+		if err := os.MkdirAll(f.Path, f.Mode); err != nil {
+			return err
+		}
+		if err := os.Chmod(f.Path, f.Mode); err != nil {
+			return err
+		}
+	} else if err := writeFileAtomic(f.Path, f.Data, f.Mode); err != nil {
+		return err
+	}
+	if f.Owner == "" {
+		return nil
+	}
+	uid, gid, err := lookupOwner(f.Owner)
+	if err != nil {
+		return err
+	}
+	return os.Chown(f.Path, uid, gid)
+}
+
+func writeFileAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".sshtrust-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// DefaultHostKeyDir is where sshd keeps its host keys
+const DefaultHostKeyDir = "/etc/ssh"
+
+// HostKey is one of sshd's host keys
+type HostKey struct {
+	// Path of the public key, ssh_host_<type>_key.pub
+	Path      string
+	PublicKey []byte
+}
+
+// CertificatePath is where sshd's HostCertificate for the key is kept
+func (k HostKey) CertificatePath() string {
+	return strings.TrimSuffix(k.Path, ".pub") + "-cert.pub"
+}
+
+// HostKeys reads sshd's host public keys from dir
+func HostKeys(dir string) ([]HostKey, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "ssh_host_*_key.pub"))
+	if err != nil {
+		return nil, err
+	}
+	keys := []HostKey{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, HostKey{Path: path, PublicKey: data})
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no host keys found in %s", dir)
+	}
+	return keys, nil
+}
+
+// PrivateKeyPath is the host's private key for the public key
+func (k HostKey) PrivateKeyPath() string {
+	return strings.TrimSuffix(k.Path, ".pub")
+}
+
+// DefaultSSHDPidFile is where sshd records its pid
+const DefaultSSHDPidFile = "/run/sshd.pid"
+
+// ReloadSSHD sends sshd a SIGHUP, which makes it re-read its configuration
+// and host certificates without dropping connections.
+func ReloadSSHD(pidFile string) error {
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		return fmt.Errorf("could not read sshd pid: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return fmt.Errorf("invalid sshd pid in %s", pidFile)
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Signal(syscall.SIGHUP)
+}
@@ -0,0 +1,213 @@
+package hostconfig
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/server"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/lukegriffith/SSHTrust/pkg/krl"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDropIn(t *testing.T) {
+	dropIn := DropIn(Options{
+		ConfigDir:             "/etc/ssh/sshtrust",
+		Principals:            PrincipalsCommand,
+		PrincipalsCommand:     "/usr/local/bin/sshtrust principals-command %u",
+		PrincipalsCommandUser: "nobody",
+		HostCertificates:      []string{"/etc/ssh/ssh_host_ed25519_key-cert.pub"},
+	})
+	assert.Equal(t, "# Managed by sshtrust host configure, changes will be overwritten\n"+
+		"TrustedUserCAKeys /etc/ssh/sshtrust/trusted_user_ca_keys\n"+
+		"RevokedKeys /etc/ssh/sshtrust/revoked_keys\n"+
+		"AuthorizedPrincipalsCommand /usr/local/bin/sshtrust principals-command %u\n"+
+		"AuthorizedPrincipalsCommandUser nobody\n"+
+		"HostCertificate /etc/ssh/ssh_host_ed25519_key-cert.pub\n", string(dropIn))
+
+	dropIn = DropIn(Options{ConfigDir: "/etc/ssh/sshtrust", Principals: PrincipalsFile})
+	assert.Contains(t, string(dropIn), "AuthorizedPrincipalsFile /etc/ssh/sshtrust/principals/%u\n")
+}
+
+func TestPlanCheckApply(t *testing.T) {
+	ts := httptest.NewServer(server.SetupServer(true))
+	defer ts.Close()
+	ctx := context.Background()
+	c := sshtrust.New(ts.URL)
+	_, err := c.CreateCA(ctx, cert.CaRequest{CommonCa: cert.CommonCa{
+		Name: "myca", Type: cert.ED25519, ValidPrincipals: []string{"alice", "admin"}, MaxTTLMinutes: 60,
+		Accounts: map[string][]string{"root": {"admin"}},
+	}})
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	opts := Options{
+		ConfigDir:  filepath.Join(dir, "sshtrust"),
+		DropInPath: filepath.Join(dir, "sshd_config.d", "10-sshtrust.conf"),
+		Principals: PrincipalsFile,
+		Accounts:   []string{"root"},
+	}
+	files, err := Plan(ctx, c, opts)
+	require.NoError(t, err)
+	require.Len(t, files, 4)
+
+	drift, err := Check(files)
+	require.NoError(t, err)
+	assert.Len(t, drift, 4)
+	assert.Equal(t, "missing", drift[0].Reason)
+
+	changed, err := Apply(files)
+	require.NoError(t, err)
+	assert.Len(t, changed, 4)
+
+	principals, err := os.ReadFile(filepath.Join(opts.ConfigDir, "principals", "root"))
+	require.NoError(t, err)
+	assert.Equal(t, "admin\n", string(principals))
+	revoked, err := os.ReadFile(filepath.Join(opts.ConfigDir, "revoked_keys"))
+	require.NoError(t, err)
+	_, err = krl.Parse(revoked)
+	assert.NoError(t, err)
+
+	// Applying again is a no-op
+	drift, err = Check(files)
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+	changed, err = Apply(files)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+
+	// Local edits and server changes both show up as drift
+	require.NoError(t, os.Chmod(opts.DropInPath, 0666))
+	signed, err := c.Sign(ctx, "myca", cert.SignRequest{
+		PublicKey:  "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAJI+V4/0d5xJTDvOuvR/2ZqahzceFbz00IDIFBEaKvc",
+		Principals: []string{"alice"},
+		TTLMinutes: 10,
+	})
+	require.NoError(t, err)
+	require.NoError(t, c.Revoke(ctx, "myca", signed.Serial))
+
+	files, err = Plan(ctx, c, opts)
+	require.NoError(t, err)
+	drift, err = Check(files)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []Drift{
+		{filepath.Join(opts.ConfigDir, "revoked_keys"), "content differs from the server"},
+		{opts.DropInPath, "mode is 0666, expected 0644"},
+	}, drift)
+}
+
+func TestPlanCacheDir(t *testing.T) {
+	ts := httptest.NewServer(server.SetupServer(true))
+	defer ts.Close()
+	current, err := user.Current()
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	opts := Options{
+		ConfigDir:             filepath.Join(dir, "sshtrust"),
+		DropInPath:            filepath.Join(dir, "sshd_config.d", "10-sshtrust.conf"),
+		Principals:            PrincipalsCommand,
+		PrincipalsCommand:     "/usr/local/bin/sshtrust principals-command %u",
+		PrincipalsCommandUser: current.Username,
+		CacheDir:              filepath.Join(dir, "cache"),
+	}
+	files, err := Plan(context.Background(), sshtrust.New(ts.URL), opts)
+	require.NoError(t, err)
+	assert.Contains(t, files, File{Path: opts.CacheDir, Dir: true, Mode: 0755, Owner: current.Username})
+
+	_, err = Apply(files)
+	require.NoError(t, err)
+	info, err := os.Stat(opts.CacheDir)
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	drift, err := Check(files)
+	require.NoError(t, err)
+	assert.Empty(t, drift)
+}
+
+func TestCheckInclude(t *testing.T) {
+	dir := t.TempDir()
+	sshdConfig := filepath.Join(dir, "sshd_config")
+	dropIn := filepath.Join(dir, "sshd_config.d", "10-sshtrust.conf")
+
+	tests := []struct {
+		name     string
+		config   string
+		included bool
+	}{
+		{"Absolute glob", "Include " + filepath.Join(dir, "sshd_config.d", "*.conf") + "\nPermitRootLogin no\n", true},
+		{"Relative glob", "include sshd_config.d/*.conf\n", true},
+		{"Several patterns", "Include /etc/other.conf sshd_config.d/10-sshtrust.conf\n", true},
+		{"Commented out", "#Include sshd_config.d/*.conf\n", false},
+		{"Other directory", "Include /etc/ssh/other.d/*.conf\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, os.WriteFile(sshdConfig, []byte(tt.config), 0644))
+			drift, err := CheckInclude(sshdConfig, dropIn)
+			require.NoError(t, err)
+			if tt.included {
+				assert.Empty(t, drift)
+			} else {
+				assert.Equal(t, []Drift{{sshdConfig, "does not Include " + dropIn}}, drift)
+			}
+		})
+	}
+
+	_, err := CheckInclude(filepath.Join(dir, "missing"), dropIn)
+	assert.Error(t, err)
+}
+
+func TestPlanRejectsBadOptions(t *testing.T) {
+	c := sshtrust.New("http://unused")
+	_, err := Plan(context.Background(), c, Options{Principals: "bogus"})
+	assert.EqualError(t, err, `unknown principals mode "bogus"`)
+	_, err = Plan(context.Background(), c, Options{Principals: PrincipalsFile, Accounts: []string{"../etc"}})
+	assert.EqualError(t, err, `invalid account "../etc"`)
+	_, err = Plan(context.Background(), c, Options{Principals: PrincipalsCommand})
+	assert.EqualError(t, err, "the principals command and its user are required")
+}
+
+func TestHostKeys(t *testing.T) {
+	dir := t.TempDir()
+	_, err := HostKeys(dir)
+	assert.Error(t, err)
+
+	key := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAJI+V4/0d5xJTDvOuvR/2ZqahzceFbz00IDIFBEaKvc root@web1\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ssh_host_ed25519_key.pub"), []byte(key), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ssh_host_ed25519_key"), []byte("private"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "ssh_host_ed25519_key-cert.pub"), []byte("cert"), 0644))
+
+	keys, err := HostKeys(dir)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.Equal(t, key, string(keys[0].PublicKey))
+	assert.Equal(t, filepath.Join(dir, "ssh_host_ed25519_key-cert.pub"), keys[0].CertificatePath())
+}
+
+func TestReloadSSHD(t *testing.T) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	pidFile := filepath.Join(t.TempDir(), "sshd.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644))
+	require.NoError(t, ReloadSSHD(pidFile))
+	select {
+	case <-hangup:
+	case <-time.After(5 * time.Second):
+		t.Fatal("sshd was not sent a SIGHUP")
+	}
+
+	assert.Error(t, ReloadSSHD(filepath.Join(t.TempDir(), "missing.pid")))
+}
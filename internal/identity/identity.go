@@ -0,0 +1,153 @@
+// Package identity reads and writes the files that make up an OpenSSH
+// identity: the private key, <identity>.pub and <identity>-cert.pub.
+package identity
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"golang.org/x/crypto/ssh"
+)
+
+// Expand resolves a leading ~/ in an identity path to the home directory
+func Expand(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("could not get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, path[2:]), nil
+}
+
+// PublicKeyPath returns the public key file of the identity
+func PublicKeyPath(identity string) string {
+	return identity + ".pub"
+}
+
+// CertificatePath returns the certificate file ssh looks for next to the identity
+func CertificatePath(identity string) string {
+	return identity + "-cert.pub"
+}
+
+// ReadPublicKey returns the authorized key line of the identity's public key
+func ReadPublicKey(identity string) (string, error) {
+	data, err := os.ReadFile(PublicKeyPath(identity))
+	if err != nil {
+		return "", fmt.Errorf("could not read public key: %w", err)
+	}
+	if _, _, _, _, err := ssh.ParseAuthorizedKey(data); err != nil {
+		return "", fmt.Errorf("could not parse public key %s: %w", PublicKeyPath(identity), err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ReadCertificate parses the identity's certificate file
+func ReadCertificate(identity string) (*ssh.Certificate, error) {
+	data, err := os.ReadFile(CertificatePath(identity))
+	if err != nil {
+		return nil, err
+	}
+	return ParseCertificate(data)
+}
+
+// ParseCertificate parses a certificate in authorized key format
+func ParseCertificate(data []byte) (*ssh.Certificate, error) {
+	return cert.ParseCertificate(data)
+}
+
+// CheckCertificate returns an error unless cert is a user certificate for
+// key, signed by one of the authorities, for principal and staying valid for
+// at least margin after now.
+func CheckCertificate(cert *ssh.Certificate, key ssh.PublicKey, authorities []ssh.PublicKey, principal string, now time.Time, margin time.Duration) error {
+	if cert.CertType != ssh.UserCert {
+		return errors.New("not a user certificate")
+	}
+	if !sameKey(cert.Key, key) {
+		return errors.New("certificate is for a different key")
+	}
+	signedByAuthority := false
+	for _, authority := range authorities {
+		if sameKey(cert.SignatureKey, authority) {
+			signedByAuthority = true
+			break
+		}
+	}
+	if !signedByAuthority {
+		return errors.New("certificate is not signed by the CA")
+	}
+	if now.Before(time.Unix(int64(cert.ValidAfter), 0)) {
+		return errors.New("certificate is not yet valid")
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity && now.Add(margin).After(time.Unix(int64(cert.ValidBefore), 0)) {
+		return errors.New("certificate has expired or is about to expire")
+	}
+	for _, p := range cert.ValidPrincipals {
+		if p == principal {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate is not valid for principal %s", principal)
+}
+
+func sameKey(a, b ssh.PublicKey) bool {
+	return a != nil && b != nil && bytes.Equal(a.Marshal(), b.Marshal())
+}
+
+// WriteCertificate writes the signed certificate next to the identity. The
+// file is replaced atomically so ssh never reads a partial certificate.
+func WriteCertificate(identity string, signedKey []byte) error {
+	return writeFileAtomic(CertificatePath(identity), signedKey, 0644)
+}
+
+// LoadPrivateKey parses the identity's private key, asking passphrase for
+// the passphrase when the key is encrypted.
+func LoadPrivateKey(identity string, passphrase func() ([]byte, error)) (interface{}, error) {
+	data, err := os.ReadFile(identity)
+	if err != nil {
+		return nil, fmt.Errorf("could not read private key: %w", err)
+	}
+	key, err := ssh.ParseRawPrivateKey(data)
+	var missing *ssh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		pass, err := passphrase()
+		if err != nil {
+			return nil, err
+		}
+		return ssh.ParseRawPrivateKeyWithPassphrase(data, pass)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not parse private key: %w", err)
+	}
+	return key, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory
+// and renames it over path.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
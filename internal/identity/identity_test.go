@@ -0,0 +1,133 @@
+package identity
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// writeIdentity creates an ed25519 identity in dir, optionally encrypted
+func writeIdentity(t *testing.T, dir, passphrase string) (string, ssh.Signer) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	var block *pem.Block
+	if passphrase == "" {
+		block, err = ssh.MarshalPrivateKey(privateKey, "test")
+	} else {
+		block, err = ssh.MarshalPrivateKeyWithPassphrase(privateKey, "test", []byte(passphrase))
+	}
+	assert.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	assert.NoError(t, err)
+
+	path := filepath.Join(dir, "id_ed25519")
+	assert.NoError(t, os.WriteFile(path, pem.EncodeToMemory(block), 0600))
+	assert.NoError(t, os.WriteFile(PublicKeyPath(path), ssh.MarshalAuthorizedKey(signer.PublicKey()), 0644))
+	return path, signer
+}
+
+func TestReadPublicKey(t *testing.T) {
+	path, signer := writeIdentity(t, t.TempDir(), "")
+
+	publicKey, err := ReadPublicKey(path)
+	assert.NoError(t, err)
+	assert.Equal(t, string(ssh.MarshalAuthorizedKey(signer.PublicKey())), publicKey+"\n")
+
+	_, err = ReadPublicKey(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func TestWriteAndReadCertificate(t *testing.T) {
+	path, signer := writeIdentity(t, t.TempDir(), "")
+
+	cert := &ssh.Certificate{
+		Key:         signer.PublicKey(),
+		CertType:    ssh.UserCert,
+		ValidBefore: uint64(time.Now().Add(time.Hour).Unix()),
+	}
+	assert.NoError(t, cert.SignCert(rand.Reader, signer))
+
+	assert.NoError(t, WriteCertificate(path, ssh.MarshalAuthorizedKey(cert)))
+
+	info, err := os.Stat(CertificatePath(path))
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	read, err := ReadCertificate(path)
+	assert.NoError(t, err)
+	assert.Equal(t, cert.Marshal(), read.Marshal())
+
+	// A plain public key is not a certificate
+	_, err = ParseCertificate(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	assert.Error(t, err)
+}
+
+func TestLoadPrivateKey(t *testing.T) {
+	noPassphrase := func() ([]byte, error) {
+		t.Fatal("passphrase requested for an unencrypted key")
+		return nil, nil
+	}
+	path, _ := writeIdentity(t, t.TempDir(), "")
+	key, err := LoadPrivateKey(path, noPassphrase)
+	assert.NoError(t, err)
+	assert.NotNil(t, key)
+
+	path, _ = writeIdentity(t, t.TempDir(), "secret")
+	key, err = LoadPrivateKey(path, func() ([]byte, error) { return []byte("secret"), nil })
+	assert.NoError(t, err)
+	assert.NotNil(t, key)
+
+	_, err = LoadPrivateKey(path, func() ([]byte, error) { return []byte("wrong"), nil })
+	assert.Error(t, err)
+}
+
+func TestExpand(t *testing.T) {
+	homeDir, err := os.UserHomeDir()
+	assert.NoError(t, err)
+
+	path, err := Expand("~/.ssh/id_ed25519")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(homeDir, ".ssh", "id_ed25519"), path)
+
+	path, err = Expand("/etc/ssh/ssh_host_ed25519_key")
+	assert.NoError(t, err)
+	assert.Equal(t, "/etc/ssh/ssh_host_ed25519_key", path)
+}
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Now()
+	_, key := writeIdentity(t, t.TempDir(), "")
+	_, ca := writeIdentity(t, t.TempDir(), "")
+	_, other := writeIdentity(t, t.TempDir(), "")
+	cert := &ssh.Certificate{
+		Key:             key.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"alice", "deploy"},
+		ValidAfter:      uint64(now.Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(now.Add(10 * time.Minute).Unix()),
+	}
+	assert.NoError(t, cert.SignCert(rand.Reader, ca))
+	authorities := []ssh.PublicKey{other.PublicKey(), ca.PublicKey()}
+
+	assert.NoError(t, CheckCertificate(cert, key.PublicKey(), authorities, "alice", now, time.Minute))
+	assert.NoError(t, CheckCertificate(cert, key.PublicKey(), authorities, "deploy", now, time.Minute))
+	assert.ErrorContains(t, CheckCertificate(cert, key.PublicKey(), authorities, "root", now, time.Minute), "principal root")
+	assert.ErrorContains(t, CheckCertificate(cert, key.PublicKey(), authorities, "alice", now, 15*time.Minute), "about to expire")
+	assert.ErrorContains(t, CheckCertificate(cert, key.PublicKey(), authorities, "alice", now.Add(-time.Hour), time.Minute), "not yet valid")
+
+	// A certificate left over from another key or CA is not reused
+	assert.ErrorContains(t, CheckCertificate(cert, other.PublicKey(), authorities, "alice", now, time.Minute), "different key")
+	assert.ErrorContains(t, CheckCertificate(cert, key.PublicKey(), []ssh.PublicKey{other.PublicKey()}, "alice", now, time.Minute), "not signed by the CA")
+
+	cert.CertType = ssh.HostCert
+	assert.ErrorContains(t, CheckCertificate(cert, key.PublicKey(), authorities, "alice", now, time.Minute), "not a user certificate")
+}
@@ -0,0 +1,97 @@
+// Package knownhosts merges the @cert-authority lines served by SSHTrust
+// into a known_hosts file, inside a block it owns.
+package knownhosts
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	beginMarker = "# BEGIN "
+	endMarker   = "# END "
+)
+
+// Merge replaces the block called name in existing with lines, appending
+// the block when it is not there yet and removing it when lines is empty.
+// Lines outside the block are left untouched, so merging the same lines
+// again returns existing unchanged.
+func Merge(existing []byte, name string, lines []byte) []byte {
+	begin := beginMarker + name
+	end := endMarker + name
+
+	var before, after []string
+	inBlock, found := false, false
+	for _, line := range splitLines(existing) {
+		switch {
+		case !inBlock && line == begin:
+			inBlock, found = true, true
+		case inBlock && line == end:
+			inBlock = false
+		case inBlock:
+		case found:
+			after = append(after, line)
+		default:
+			before = append(before, line)
+		}
+	}
+
+	out := append([]string{}, before...)
+	if block := splitLines(lines); len(block) > 0 {
+		out = append(out, begin)
+		out = append(out, block...)
+		out = append(out, end)
+	}
+	out = append(out, after...)
+	if len(out) == 0 {
+		return []byte{}
+	}
+	return []byte(strings.Join(out, "\n") + "\n")
+}
+
+func splitLines(data []byte) []string {
+	text := strings.TrimRight(string(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// MergeFile merges lines into the known_hosts file at path, creating it if
+// needed. It reports whether the file changed.
+func MergeFile(path, name string, lines []byte) (bool, error) {
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	merged := Merge(existing, name, lines)
+	if err == nil && bytes.Equal(existing, merged) {
+		return false, nil
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return false, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".known_hosts-*")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(merged); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err := tmp.Close(); err != nil {
+		return false, err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return false, err
+	}
+	return true, os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,54 @@
+package knownhosts
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const caLine = "@cert-authority *.example.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAJI+V4/0d5xJTDvOuvR/2ZqahzceFbz00IDIFBEaKvc hostca\n"
+
+func TestMerge(t *testing.T) {
+	existing := []byte("github.com ssh-ed25519 AAAA\n")
+
+	merged := Merge(existing, "sshtrust default", []byte(caLine))
+	assert.Equal(t, "github.com ssh-ed25519 AAAA\n# BEGIN sshtrust default\n"+caLine+"# END sshtrust default\n", string(merged))
+
+	// Merging again changes nothing
+	assert.Equal(t, merged, Merge(merged, "sshtrust default", []byte(caLine)))
+
+	// The block is replaced in place, lines after it are kept
+	withAfter := append(append([]byte{}, merged...), []byte("gitlab.com ssh-ed25519 BBBB\n")...)
+	replaced := Merge(withAfter, "sshtrust default", []byte("@cert-authority * ssh-ed25519 CCCC new\n"))
+	assert.Equal(t, "github.com ssh-ed25519 AAAA\n# BEGIN sshtrust default\n@cert-authority * ssh-ed25519 CCCC new\n# END sshtrust default\ngitlab.com ssh-ed25519 BBBB\n", string(replaced))
+
+	// Blocks of other profiles are left alone
+	other := Merge(merged, "sshtrust prod", []byte(caLine))
+	assert.Contains(t, string(other), "# BEGIN sshtrust default\n")
+	assert.Contains(t, string(other), "# BEGIN sshtrust prod\n")
+
+	// No lines removes the block
+	assert.Equal(t, "github.com ssh-ed25519 AAAA\n", string(Merge(merged, "sshtrust default", nil)))
+}
+
+func TestMergeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".ssh", "known_hosts")
+
+	changed, err := MergeFile(path, "sshtrust default", []byte(caLine))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	assert.NoError(t, os.Chmod(path, 0600))
+	changed, err = MergeFile(path, "sshtrust default", []byte(caLine))
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	changed, err = MergeFile(path, "sshtrust default", []byte("@cert-authority * ssh-ed25519 CCCC new\n"))
+	assert.NoError(t, err)
+	assert.True(t, changed)
+	info, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm(), "mode should be preserved")
+}
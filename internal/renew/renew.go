@@ -0,0 +1,132 @@
+// Package renew keeps certificates signed before they expire.
+package renew
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/client"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultFraction   = 0.75
+	DefaultMinBackoff = 5 * time.Second
+	DefaultMaxBackoff = 5 * time.Minute
+)
+
+// Target is a certificate kept fresh by the Renewer
+type Target struct {
+	// Name used in log messages
+	Name string
+	// Current returns the installed certificate, or an error when there is
+	// no usable certificate and one should be obtained immediately.
+	Current func() (*ssh.Certificate, error)
+	// Renew obtains and installs a new certificate
+	Renew func(ctx context.Context) (*ssh.Certificate, error)
+}
+
+// Renewer re-signs targets once a fraction of their lifetime has elapsed
+type Renewer struct {
+	// Fraction of the certificate lifetime after which it is renewed
+	Fraction float64
+	// Backoff bounds after a failed renewal
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	Logger     *log.Logger
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// New returns a Renewer with the default fraction and backoff
+func New(logger *log.Logger) *Renewer {
+	return &Renewer{
+		Fraction:   DefaultFraction,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		Logger:     logger,
+		now:        time.Now,
+		sleep:      sleep,
+	}
+}
+
+// RenewAt returns when a certificate should be renewed
+func RenewAt(cert *ssh.Certificate, fraction float64) time.Time {
+	validAfter := time.Unix(int64(cert.ValidAfter), 0)
+	validBefore := time.Unix(int64(cert.ValidBefore), 0)
+	lifetime := validBefore.Sub(validAfter)
+	return validAfter.Add(time.Duration(float64(lifetime) * fraction))
+}
+
+// Run renews the target until ctx is cancelled
+func (r *Renewer) Run(ctx context.Context, target Target) error {
+	backoff := r.MinBackoff
+
+	cert, err := target.Current()
+	if err != nil {
+		r.Logger.Printf("%s: no usable certificate, signing now: %v", target.Name, err)
+		cert = nil
+	}
+
+	for {
+		if cert != nil && cert.ValidBefore == ssh.CertTimeInfinity {
+			r.Logger.Printf("%s: certificate never expires, not renewing it", target.Name)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		if cert != nil {
+			renewAt := RenewAt(cert, r.Fraction)
+			r.Logger.Printf("%s: certificate valid until %s, renewing at %s", target.Name,
+				time.Unix(int64(cert.ValidBefore), 0).Format(time.RFC3339), renewAt.Format(time.RFC3339))
+			if err := r.sleep(ctx, renewAt.Sub(r.now())); err != nil {
+				return err
+			}
+		}
+
+		renewed, err := target.Renew(ctx)
+		if err == nil {
+			r.Logger.Printf("%s: certificate renewed", target.Name)
+			cert = renewed
+			backoff = r.MinBackoff
+			continue
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		// Retrying quickly will not help until the user logs in again, so
+		// wait the longest backoff for auth failures.
+		wait := backoff
+		if client.IsStatus(err, http.StatusUnauthorized) || client.IsStatus(err, http.StatusForbidden) {
+			r.Logger.Printf("%s: not authorized, run `sshtrust login`: %v", target.Name, err)
+			wait = r.MaxBackoff
+		} else {
+			r.Logger.Printf("%s: renewal failed, retrying in %s: %v", target.Name, wait, err)
+			backoff = min(backoff*2, r.MaxBackoff)
+		}
+		// Keep the current certificate's schedule out of the way while
+		// retrying, it is already due.
+		cert = nil
+		if err := r.sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d or until ctx is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,145 @@
+package renew
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+var testNow = time.Unix(1700000000, 0)
+
+func certValid(from time.Time, lifetime time.Duration) *ssh.Certificate {
+	return &ssh.Certificate{
+		ValidAfter:  uint64(from.Unix()),
+		ValidBefore: uint64(from.Add(lifetime).Unix()),
+	}
+}
+
+// testRenewer records every sleep and cancels the run after maxSleeps
+func testRenewer(maxSleeps int) (*Renewer, *[]time.Duration, context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sleeps := []time.Duration{}
+	r := New(log.New(io.Discard, "", 0))
+	r.now = func() time.Time { return testNow }
+	r.sleep = func(ctx context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		if len(sleeps) >= maxSleeps {
+			cancel()
+			return context.Canceled
+		}
+		return nil
+	}
+	return r, &sleeps, ctx
+}
+
+func TestRenewAt(t *testing.T) {
+	cert := certValid(testNow, time.Hour)
+	assert.Equal(t, testNow.Add(45*time.Minute), RenewAt(cert, 0.75))
+	assert.Equal(t, testNow.Add(30*time.Minute), RenewAt(cert, 0.5))
+}
+
+func TestRunRenewsAtFraction(t *testing.T) {
+	r, sleeps, ctx := testRenewer(2)
+	renewals := 0
+
+	err := r.Run(ctx, Target{
+		Name: "test",
+		Current: func() (*ssh.Certificate, error) {
+			// Signed 10 minutes ago for an hour
+			return certValid(testNow.Add(-10*time.Minute), time.Hour), nil
+		},
+		Renew: func(ctx context.Context) (*ssh.Certificate, error) {
+			renewals++
+			return certValid(testNow, time.Hour), nil
+		},
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, renewals)
+	assert.Equal(t, []time.Duration{35 * time.Minute, 45 * time.Minute}, *sleeps)
+}
+
+func TestRunNeverRenewsInfiniteCertificate(t *testing.T) {
+	r, sleeps, _ := testRenewer(1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	renewals := 0
+
+	err := r.Run(ctx, Target{
+		Name: "test",
+		Current: func() (*ssh.Certificate, error) {
+			return &ssh.Certificate{ValidAfter: 0, ValidBefore: ssh.CertTimeInfinity}, nil
+		},
+		Renew: func(ctx context.Context) (*ssh.Certificate, error) {
+			renewals++
+			return certValid(testNow, time.Hour), nil
+		},
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, renewals)
+	assert.Empty(t, *sleeps)
+}
+
+func TestRunSignsImmediatelyWithoutCertificate(t *testing.T) {
+	r, sleeps, ctx := testRenewer(1)
+	renewals := 0
+
+	_ = r.Run(ctx, Target{
+		Name:    "test",
+		Current: func() (*ssh.Certificate, error) { return nil, errors.New("no certificate") },
+		Renew: func(ctx context.Context) (*ssh.Certificate, error) {
+			renewals++
+			return certValid(testNow, time.Hour), nil
+		},
+	})
+
+	assert.Equal(t, 1, renewals)
+	assert.Equal(t, []time.Duration{45 * time.Minute}, *sleeps)
+}
+
+func TestRunBacksOffOnFailure(t *testing.T) {
+	r, sleeps, ctx := testRenewer(5)
+	r.MinBackoff = time.Second
+	r.MaxBackoff = 4 * time.Second
+
+	_ = r.Run(ctx, Target{
+		Name:    "test",
+		Current: func() (*ssh.Certificate, error) { return nil, errors.New("no certificate") },
+		Renew: func(ctx context.Context) (*ssh.Certificate, error) {
+			return nil, errors.New("connection refused")
+		},
+	})
+
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second, 4 * time.Second}, *sleeps)
+}
+
+func TestRunWaitsLongestWhenUnauthorized(t *testing.T) {
+	r, sleeps, ctx := testRenewer(3)
+	r.MinBackoff = time.Second
+	r.MaxBackoff = time.Minute
+	attempts := 0
+
+	_ = r.Run(ctx, Target{
+		Name:    "test",
+		Current: func() (*ssh.Certificate, error) { return nil, errors.New("no certificate") },
+		Renew: func(ctx context.Context) (*ssh.Certificate, error) {
+			attempts++
+			if attempts == 1 {
+				return nil, &client.APIError{StatusCode: http.StatusUnauthorized}
+			}
+			return certValid(testNow, time.Hour), nil
+		},
+	})
+
+	// Backoff is reset after a successful renewal
+	assert.Equal(t, []time.Duration{time.Minute, 45 * time.Minute, 45 * time.Minute}, *sleeps)
+}
@@ -2,17 +2,22 @@ package server
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/base64"
 	"log"
 	"os"
+	"strings"
 
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"            // Echo core library
 	"github.com/labstack/echo/v4/middleware" // Optional Echo middleware
 	_ "github.com/lukegriffith/SSHTrust/docs"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
 	"github.com/lukegriffith/SSHTrust/pkg/auth"
 	"github.com/lukegriffith/SSHTrust/pkg/certStore"
 	"github.com/lukegriffith/SSHTrust/pkg/handlers" // Import your cert package
+	"github.com/lukegriffith/SSHTrust/pkg/metrics"
+	"github.com/lukegriffith/SSHTrust/pkg/webhook"
 	echoSwagger "github.com/swaggo/echo-swagger"
 )
 
@@ -48,12 +53,70 @@ func loadJWTSecret() []byte {
 	return generateRandomJWTSecret()
 }
 
+// Option configures the server's handlers
+type Option func(*handlers.App)
+
+// WithAuditLog records security relevant events, such as logins, CA changes
+// and signing, to logger
+func WithAuditLog(logger *audit.Logger) Option {
+	return func(app *handlers.App) {
+		app.Audit = logger
+	}
+}
+
+// WithWebhooks publishes CA, certificate, login and approval events to the
+// dispatcher's webhooks
+func WithWebhooks(dispatcher *webhook.Dispatcher) Option {
+	return func(app *handlers.App) {
+		app.Webhooks = dispatcher
+	}
+}
+
+// WithPrincipalsToken lets hosts fetch /trust/principals with token rather
+// than a login, for sshd's AuthorizedPrincipalsCommand
+func WithPrincipalsToken(token string) Option {
+	return func(app *handlers.App) {
+		app.PrincipalsToken = token
+	}
+}
+
+// WithMetricsToken lets Prometheus scrape /metrics with token rather than a
+// login
+func WithMetricsToken(token string) Option {
+	return func(app *handlers.App) {
+		app.MetricsToken = token
+	}
+}
+
+// tokenOrLogin lets requests carrying token as their bearer token through,
+// and sends everything else through the login middleware
+func tokenOrLogin(token string, login []echo.MiddlewareFunc) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		loggedIn := next
+		for i := len(login) - 1; i >= 0; i-- {
+			loggedIn = login[i](loggedIn)
+		}
+		return func(c echo.Context) error {
+			bearer, ok := strings.CutPrefix(c.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+			if ok && token != "" && subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) == 1 {
+				return next(c)
+			}
+			return loggedIn(c)
+		}
+	}
+}
+
 // SetupServer configures the Echo instance and returns it for testing or running
-func SetupServer(noAuth bool) *echo.Echo {
+func SetupServer(noAuth bool, options ...Option) *echo.Echo {
 	e := echo.New()
 	e.HideBanner = true
 	e.HidePort = true
 	e.Logger.SetLevel(2)
+	// Keep stdout for the audit log, access logs included
+	e.Logger.SetOutput(os.Stderr)
+	// Client IPs come from the connection, as headers such as
+	// X-Forwarded-For can be set by anyone
+	e.IPExtractor = echo.ExtractIPDirect()
 
 	// Optional middleware for logging and recovery
 	e.Use(middleware.Logger())
@@ -68,23 +131,74 @@ func SetupServer(noAuth bool) *echo.Echo {
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	App := handlers.App{
-		Store: certStore.NewInMemoryCaStore(),
+		Store:       certStore.NewInMemoryCaStore(),
+		Revocations: certStore.NewInMemoryRevocationStore(),
+		Hosts:       certStore.NewInMemoryHostStore(),
+		Requests:    certStore.NewInMemorySigningRequestStore(),
+	}
+	for _, option := range options {
+		option(&App)
 	}
+	App.Metrics = metrics.New(metrics.Sources{
+		CAs:               App.CACount,
+		Users:             auth.Users.Count,
+		ExpiringHostCerts: func() int { return App.ExpiringHostCerts(metrics.ExpiringWithin) },
+	})
+	e.Use(App.Metrics.Middleware())
 
-	var ca *echo.Group
-	e.POST("/login", auth.Login)
-	e.POST("/register", auth.Register)
-	if noAuth {
-		ca = e.Group("/CA")
-	} else {
-		ca = e.Group("/CA", echojwt.WithConfig(echojwt.Config{
+	e.POST("/login", auth.Login, App.Audited("user.login"))
+	e.POST("/register", auth.Register, App.Audited("user.register"))
+	var authMiddleware []echo.MiddlewareFunc
+	if !noAuth {
+		authMiddleware = append(authMiddleware, echojwt.WithConfig(echojwt.Config{
 			SigningKey: auth.JWTSecret,
 		}))
 	}
+	// Metrics name CAs and count users, scrapers need the metrics token
+	e.GET("/metrics", echo.WrapHandler(App.Metrics.Handler()), tokenOrLogin(App.MetricsToken, authMiddleware))
+
+	ca := e.Group("/CA", authMiddleware...)
 	// Define routes and their corresponding handlers
-	ca.GET("", App.ListCA)         // List CAs
-	ca.POST("", App.CreateCA)      // Create a new CA
-	ca.GET("/:id", App.GetCA)      // Get a specific CA by ID
-	ca.POST("/:id/Sign", App.Sign) // Sign a public key with a specific CA
+	ca.GET("", App.ListCA)                                                                   // List CAs
+	ca.POST("", App.CreateCA, App.Audited("ca.create"))                                      // Create a new CA
+	ca.GET("/:id", App.GetCA)                                                                // Get a specific CA by ID
+	ca.POST("/:id/Sign", App.Sign, App.Audited("cert.sign"))                                 // Sign a public key with a specific CA
+	ca.POST("/:id/revoke", App.Revoke, App.Audited("cert.revoke"))                           // Revoke a certificate issued by a specific CA
+	ca.POST("/:id/verify", App.Verify)                                                       // Verify a certificate against a specific CA
+	ca.GET("/:id/krl", App.KRL)                                                              // Get the revocation list of a specific CA
+	ca.POST("/:id/rotate", App.RotateCA, App.Audited("ca.rotate"))                           // Rotate the key of a specific CA
+	ca.POST("/:id/rotate/complete", App.CompleteRotation, App.Audited("ca.rotate.complete")) // Stop trusting the retiring keys of a specific CA
+	ca.POST("/:id/requests", App.CreateSigningRequest, App.Audited("request.create"))        // Request a certificate that may need approval
+	ca.POST("/:id/breakglass", App.BreakGlassSign, App.Audited("cert.breakglass"))           // Sign a public key in an emergency, without approval
+
+	requests := e.Group("/requests", authMiddleware...)
+	requests.GET("", App.ListSigningRequests)                                                // List signing requests
+	requests.GET("/:id", App.GetSigningRequest)                                              // Get a signing request
+	requests.POST("/:id/approve", App.ApproveSigningRequest, App.Audited("request.approve")) // Approve a pending signing request
+	requests.POST("/:id/deny", App.DenySigningRequest, App.Audited("request.deny"))          // Deny a pending signing request
+
+	e.GET("/breakglass", App.ListBreakGlass, authMiddleware...) // List break-glass certificates for review
+
+	e.POST("/webhooks/test", App.TestWebhooks, authMiddleware...) // Send a test event to every webhook
+
+	certs := e.Group("/certs", authMiddleware...)
+	certs.POST("/inspect", App.InspectCert) // Inspect a certificate
+
+	// Enrolling hosts authenticate with their join token instead of a login
+	e.POST("/hosts/enroll", App.Enroll, App.Audited("host.enroll"))
+	// Renewing hosts prove possession of their host key instead
+	e.POST("/hosts/renew", App.RenewHost, App.Audited("host.renew"))
+	hosts := e.Group("/hosts", authMiddleware...)
+	hosts.GET("", App.ListHosts)                                                 // List enrolled hosts
+	hosts.POST("/tokens", App.CreateJoinToken, App.Audited("host.token.create")) // Issue a join token
+	hosts.GET("/:name", App.GetHost)                                             // Get an enrolled host
+
+	// Trust bundles only hold public keys, hosts fetch them without logging in
+	trust := e.Group("/trust")
+	trust.GET("/user-ca-keys", App.UserCAKeys) // TrustedUserCAKeys file
+	trust.GET("/known_hosts", App.KnownHosts)  // @cert-authority lines for host CAs
+	trust.GET("/krl", App.TrustKRL)            // RevokedKeys file for user CAs
+	// Principals say who can log in where, hosts need the principals token
+	trust.GET("/principals", App.Principals, tokenOrLogin(App.PrincipalsToken, authMiddleware)) // AuthorizedPrincipalsCommand output
 	return e
 }
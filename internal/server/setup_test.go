@@ -1,9 +1,20 @@
 package server
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
 
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
 )
 
 func TestMainFunction(t *testing.T) {
@@ -15,3 +26,121 @@ func TestMainFunction(t *testing.T) {
 	// Instead, we check that the instance has the expected routes and properties.
 	assert.NotNil(t, e, "Expected Echo instance to be set up")
 }
+
+func TestLogsGoToStderr(t *testing.T) {
+	// stdout is left to --audit-log -
+	e := SetupServer(false)
+	assert.Equal(t, os.Stderr, e.Logger.Output())
+}
+
+func TestPrincipalsNeedToken(t *testing.T) {
+	token := strings.Repeat("t", 32)
+	e := SetupServer(false, WithPrincipalsToken(token))
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"No token", "", http.StatusUnauthorized},
+		{"Wrong token", "Bearer " + strings.Repeat("x", 32), http.StatusUnauthorized},
+		{"Principals token", "Bearer " + token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/trust/principals?user=root", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestMetricsNeedToken(t *testing.T) {
+	token := strings.Repeat("m", 32)
+	e := SetupServer(false, WithMetricsToken(token))
+
+	tests := []struct {
+		name           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"No token", "", http.StatusUnauthorized},
+		{"Wrong token", "Bearer " + strings.Repeat("x", 32), http.StatusUnauthorized},
+		{"Metrics token", "Bearer " + token, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestAuditSourceIPFromConnection(t *testing.T) {
+	var buf bytes.Buffer
+	e := SetupServer(false, WithAuditLog(audit.NewLogger(&buf, []byte(strings.Repeat("k", 32)))))
+
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"mallory","password":"wrong"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set(echo.HeaderXForwardedFor, "198.51.100.7")
+	req.Header.Set(echo.HeaderXRealIP, "198.51.100.8")
+	req.RemoteAddr = "192.0.2.10:52000"
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+
+	var event audit.Event
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &event))
+	assert.Equal(t, "user.login", event.Action)
+	assert.Equal(t, "192.0.2.10", event.SourceIP)
+}
+
+// call sends a JSON request to e, logged in with token when it is set
+func call(e *echo.Echo, method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// login registers user and returns their token
+func login(t *testing.T, e *echo.Echo, user string) string {
+	credentials := `{"username":"` + user + `","password":"correct horse battery staple"}`
+	require.Equal(t, http.StatusOK, call(e, http.MethodPost, "/register", "", credentials).Code)
+	rec := call(e, http.MethodPost, "/login", "", credentials)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var response map[string]string
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	return response["token"]
+}
+
+func TestBreakGlassResponders(t *testing.T) {
+	e := SetupServer(false)
+	alice := login(t, e, "alice")
+	mallory := login(t, e, "mallory")
+
+	rec := call(e, http.MethodPost, "/CA", alice, `{"name":"prod-ca","type":"ssh-ed25519","valid_principals":["root"],"max_ttl_minutes":60,"break_glass_responders":["alice"]}`)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	signer, err := cert.GenerateSSHKey(cert.ED25519, 0)
+	require.NoError(t, err)
+	publicKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+	body := `{"public_key":"` + publicKey + `","principals":["root"],"reason":"database down","ticket":"INC-42"}`
+
+	rec = call(e, http.MethodPost, "/CA/prod-ca/breakglass", alice, body)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	rec = call(e, http.MethodPost, "/CA/prod-ca/breakglass", mallory, body)
+	assert.Equal(t, http.StatusForbidden, rec.Code, rec.Body.String())
+}
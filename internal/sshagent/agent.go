@@ -0,0 +1,63 @@
+// Package sshagent loads SSHTrust certificates into a running ssh-agent.
+package sshagent
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// Connect dials the agent listening on $SSH_AUTH_SOCK. The returned
+// connection must be closed by the caller.
+func Connect() (agent.ExtendedAgent, net.Conn, error) {
+	socket := os.Getenv("SSH_AUTH_SOCK")
+	if socket == "" {
+		return nil, nil, errors.New("SSH_AUTH_SOCK is not set, is ssh-agent running?")
+	}
+	conn, err := net.Dial("unix", socket)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not connect to ssh-agent: %w", err)
+	}
+	return agent.NewClient(conn), conn, nil
+}
+
+// Lifetime returns how long the certificate remains valid after now, the
+// agent drops the key once it expires.
+func Lifetime(cert *ssh.Certificate, now time.Time) (time.Duration, error) {
+	if cert.ValidBefore == ssh.CertTimeInfinity {
+		return 0, nil
+	}
+	remaining := time.Unix(int64(cert.ValidBefore), 0).Sub(now)
+	if remaining < time.Second {
+		return 0, errors.New("certificate has expired")
+	}
+	return remaining, nil
+}
+
+// AddCertificate adds the private key and its certificate to the agent,
+// with a lifetime matching the certificate's remaining validity.
+func AddCertificate(a agent.Agent, privateKey interface{}, cert *ssh.Certificate, now time.Time) error {
+	lifetime, err := Lifetime(cert, now)
+	if err != nil {
+		return err
+	}
+	comment := cert.KeyId
+	if comment == "" {
+		comment = "sshtrust"
+	}
+	err = a.Add(agent.AddedKey{
+		PrivateKey:   privateKey,
+		Certificate:  cert,
+		Comment:      comment,
+		LifetimeSecs: uint32(lifetime.Seconds()),
+	})
+	if err != nil {
+		return fmt.Errorf("could not add certificate to ssh-agent: %w", err)
+	}
+	return nil
+}
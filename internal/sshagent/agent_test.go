@@ -0,0 +1,75 @@
+package sshagent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+func signedCert(t *testing.T, validFor time.Duration) (ed25519.PrivateKey, *ssh.Certificate) {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	caSigner, err := ssh.NewSignerFromKey(caKey)
+	assert.NoError(t, err)
+
+	_, userKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	userSigner, err := ssh.NewSignerFromKey(userKey)
+	assert.NoError(t, err)
+
+	cert := &ssh.Certificate{
+		Key:             userSigner.PublicKey(),
+		KeyId:           "test-cert",
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"testuser"},
+		ValidAfter:      uint64(time.Now().Add(-time.Minute).Unix()),
+		ValidBefore:     uint64(time.Now().Add(validFor).Unix()),
+	}
+	assert.NoError(t, cert.SignCert(rand.Reader, caSigner))
+	return userKey, cert
+}
+
+func TestAddCertificate(t *testing.T) {
+	keyring := agent.NewKeyring()
+	privateKey, cert := signedCert(t, time.Hour)
+
+	err := AddCertificate(keyring, privateKey, cert, time.Now())
+	assert.NoError(t, err)
+
+	keys, err := keyring.List()
+	assert.NoError(t, err)
+	if assert.Len(t, keys, 1) {
+		assert.Equal(t, cert.Marshal(), keys[0].Marshal())
+		assert.Equal(t, "test-cert", keys[0].Comment)
+	}
+}
+
+func TestAddExpiredCertificate(t *testing.T) {
+	keyring := agent.NewKeyring()
+	privateKey, cert := signedCert(t, -time.Minute)
+
+	err := AddCertificate(keyring, privateKey, cert, time.Now())
+	assert.Error(t, err)
+
+	keys, _ := keyring.List()
+	assert.Empty(t, keys)
+}
+
+func TestLifetime(t *testing.T) {
+	now := time.Now()
+	_, cert := signedCert(t, time.Hour)
+
+	lifetime, err := Lifetime(cert, now)
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), lifetime.Seconds(), 5)
+
+	cert.ValidBefore = ssh.CertTimeInfinity
+	lifetime, err = Lifetime(cert, now)
+	assert.NoError(t, err)
+	assert.Zero(t, lifetime)
+}
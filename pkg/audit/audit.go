@@ -0,0 +1,200 @@
+// Package audit writes security relevant events as an append-only stream of
+// JSON lines. Each event carries the hash of the one before it, so removing
+// or editing an event breaks the chain for every event after it. Hashes are
+// HMACs under the server's audit key, so the chain can not be rebuilt by
+// someone who can write the log but does not have the key.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Result is how an audited action ended
+type Result string
+
+const (
+	Success Result = "success"
+	// Denied actions were refused, e.g. by a CA's policy or a wrong password
+	Denied Result = "denied"
+	// Failed actions were allowed but went wrong on the server
+	Failed Result = "failure"
+)
+
+// GenesisHash is the previous hash of the first event in a log
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// ErrChainBroken is returned when an audit log has been edited, reordered or
+// had events removed
+var ErrChainBroken = errors.New("audit hash chain broken")
+
+// Event is one line of the audit log
+type Event struct {
+	// Position in the log, from one
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+	// Logged in user, or the user logging in or registering
+	Actor    string `json:"actor,omitempty"`
+	SourceIP string `json:"source_ip,omitempty"`
+	// What was done, e.g. cert.sign
+	Action     string   `json:"action"`
+	CA         string   `json:"ca,omitempty"`
+	Principals []string `json:"principals,omitempty"`
+	// Serial of the certificate issued or revoked
+	Serial uint64 `json:"serial,omitempty"`
+	Result Result `json:"result"`
+	// Why the action was denied or failed
+	Reason string `json:"reason,omitempty"`
+	// Anything else about the action, e.g. a signing request's ID
+	Details map[string]string `json:"details,omitempty"`
+	// Hash of the previous event, GenesisHash for the first
+	PrevHash string `json:"prev_hash"`
+	// HMAC-SHA256 of the event without its hash, under the audit key
+	Hash string `json:"hash"`
+}
+
+// computeHash returns the hash of the event under key, which covers every
+// field but the hash itself
+func (e Event) computeHash(key []byte) (string, error) {
+	e.Hash = ""
+	encoded, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(encoded)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Logger appends events to an audit log. A nil Logger discards them.
+type Logger struct {
+	mu   sync.Mutex
+	w    io.Writer
+	key  []byte
+	seq  uint64
+	prev string
+	now  func() time.Time
+}
+
+// NewLogger starts a new audit log on w, hashing events under key
+func NewLogger(w io.Writer, key []byte) *Logger {
+	return &Logger{w: w, key: key, prev: GenesisHash, now: time.Now}
+}
+
+// Open appends to the audit log at path, continuing its hash chain, or
+// writes a new log to stdout for "-". The existing log has to verify under
+// key.
+func Open(path string, key []byte) (*Logger, error) {
+	if path == "-" {
+		return NewLogger(os.Stdout, key), nil
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	count, last, err := Verify(file, key)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("refusing to append to audit log %s: %w", path, err)
+	}
+	logger := NewLogger(file, key)
+	logger.seq = uint64(count)
+	logger.prev = last
+	return logger, nil
+}
+
+// Log appends event to the log, setting its sequence number, time and hashes
+func (l *Logger) Log(event Event) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	event.Seq = l.seq + 1
+	if event.Time.IsZero() {
+		event.Time = l.now()
+	}
+	event.Time = event.Time.UTC()
+	event.PrevHash = l.prev
+	hash, err := event.computeHash(l.key)
+	if err != nil {
+		return err
+	}
+	event.Hash = hash
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err := l.w.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	if file, ok := l.w.(*os.File); ok && file != os.Stdout {
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("failed to sync audit log: %w", err)
+		}
+	}
+	l.seq = event.Seq
+	l.prev = event.Hash
+	return nil
+}
+
+// Close closes the log's file, if it has one
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+	if file, ok := l.w.(*os.File); ok && file != os.Stdout {
+		return file.Close()
+	}
+	return nil
+}
+
+// Verify checks the hash chain of the audit log in r under key from its
+// first event, returning how many events it holds and the hash of the last
+// one. Events cut from the end of a log can only be noticed by comparing the
+// last hash with one recorded earlier.
+func Verify(r io.Reader, key []byte) (int, string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	count, prev := 0, GenesisHash
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var event Event
+		decoder := json.NewDecoder(bytes.NewReader(line))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&event); err != nil {
+			return count, prev, fmt.Errorf("event %d: %w: %v", count+1, ErrChainBroken, err)
+		}
+		hash, err := event.computeHash(key)
+		if err != nil {
+			return count, prev, err
+		}
+		switch {
+		case event.Seq != uint64(count+1):
+			return count, prev, fmt.Errorf("event %d: %w: expected event %d, got %d", count+1, ErrChainBroken, count+1, event.Seq)
+		case event.PrevHash != prev:
+			return count, prev, fmt.Errorf("event %d: %w: previous hash does not match", count+1, ErrChainBroken)
+		case event.Hash != hash:
+			return count, prev, fmt.Errorf("event %d: %w: event has been modified", count+1, ErrChainBroken)
+		}
+		count, prev = count+1, event.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return count, prev, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	return count, prev, nil
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testKey = []byte(strings.Repeat("k", 32))
+
+func writeEvents(t *testing.T, logger *Logger, n int) {
+	for i := 0; i < n; i++ {
+		err := logger.Log(Event{Actor: "alice", Action: "cert.sign", CA: "prod-ca", Principals: []string{"root"}, Serial: uint64(i + 1), Result: Success})
+		assert.NoError(t, err)
+	}
+}
+
+func TestLogChainsEvents(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewLogger(&buf, testKey)
+	logger.now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 6, time.FixedZone("x", 3600)) }
+	writeEvents(t, logger, 3)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Contains(t, lines[0], `"seq":1,"time":"2026-01-02T02:04:05.000000006Z"`)
+		assert.Contains(t, lines[0], `"prev_hash":"`+GenesisHash+`"`)
+	}
+	count, last, err := Verify(strings.NewReader(buf.String()), testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, logger.prev, last)
+
+	// A nil logger discards events
+	var discard *Logger
+	assert.NoError(t, discard.Log(Event{Action: "cert.sign"}))
+}
+
+func TestVerifyDetectsTampering(t *testing.T) {
+	var buf bytes.Buffer
+	writeEvents(t, NewLogger(&buf, testKey), 4)
+	lines := strings.SplitAfter(strings.TrimSpace(buf.String()), "\n")
+
+	tests := []struct {
+		name  string
+		lines []string
+		want  string
+	}{
+		{"edited", []string{lines[0], strings.Replace(lines[1], `"actor":"alice"`, `"actor":"mallory"`, 1), lines[2]}, "event 2: audit hash chain broken: event has been modified"},
+		{"removed", []string{lines[0], lines[2], lines[3]}, "event 2: audit hash chain broken: expected event 2, got 3"},
+		{"reordered", []string{lines[0], lines[2], lines[1]}, "event 2"},
+		{"removed from start", lines[1:], "event 1"},
+		{"field added", []string{strings.Replace(lines[0], `"seq":1`, `"seq":1,"note":"x"`, 1)}, "unknown field"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Verify(strings.NewReader(strings.Join(tt.lines, "")), testKey)
+			assert.ErrorIs(t, err, ErrChainBroken)
+			assert.ErrorContains(t, err, tt.want)
+		})
+	}
+}
+
+func TestVerifyNeedsKey(t *testing.T) {
+	// A log rewritten without the audit key does not verify
+	var buf bytes.Buffer
+	writeEvents(t, NewLogger(&buf, []byte("not the audit key")), 2)
+	_, _, err := Verify(strings.NewReader(buf.String()), testKey)
+	assert.ErrorIs(t, err, ErrChainBroken)
+	assert.ErrorContains(t, err, "event 1: audit hash chain broken: event has been modified")
+
+	count, _, err := Verify(strings.NewReader(buf.String()), []byte("not the audit key"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func TestOpenContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	logger, err := Open(path, testKey)
+	assert.NoError(t, err)
+	writeEvents(t, logger, 2)
+	assert.NoError(t, logger.Close())
+
+	logger, err = Open(path, testKey)
+	assert.NoError(t, err)
+	writeEvents(t, logger, 1)
+	assert.NoError(t, logger.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	count, _, err := Verify(file, testKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	// A tampered log is not appended to
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(path, bytes.Replace(contents, []byte("alice"), []byte("bob"), 1), 0o600))
+	_, err = Open(path, testKey)
+	assert.ErrorIs(t, err, ErrChainBroken)
+}
@@ -0,0 +1,17 @@
+package audit
+
+import (
+	echo "github.com/labstack/echo/v4"
+)
+
+// ContextKey is where the event being audited for a request is kept
+const ContextKey = "audit"
+
+// FromContext returns the event being audited for the request, for handlers
+// to fill in. Requests that are not audited get an event that is discarded.
+func FromContext(c echo.Context) *Event {
+	if event, ok := c.Get(ContextKey).(*Event); ok {
+		return event
+	}
+	return &Event{}
+}
@@ -3,6 +3,7 @@ package auth
 import (
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
 	"golang.org/x/crypto/bcrypt"
 	"net/http"
 	"time"
@@ -23,6 +24,8 @@ type User struct {
 type UserList interface {
 	GetPasswordHash(un string) (string, error)
 	Register(u *User) *echo.HTTPError
+	// Count returns how many users are registered
+	Count() int
 }
 
 func GenerateHash(s string) (string, error) {
@@ -39,6 +42,7 @@ func Register(c echo.Context) error {
 	if err := c.Bind(u); err != nil {
 		return err
 	}
+	audit.FromContext(c).Actor = u.Username
 	httpErr := Users.Register(u)
 
 	if httpErr != nil {
@@ -53,15 +57,19 @@ func Login(c echo.Context) error {
 	if err := c.Bind(u); err != nil {
 		return err
 	}
+	event := audit.FromContext(c)
+	event.Actor = u.Username
 
 	storedPassHash, err := Users.GetPasswordHash(u.Username)
 	if err != nil {
+		event.Reason = "unknown user"
 		return echo.ErrNotFound
 	}
 
 	// Validate user credentials (check if username and password match)
 	if err := bcrypt.CompareHashAndPassword([]byte(storedPassHash), []byte(u.Password)); err != nil {
 		c.Logger().Warn(err)
+		event.Reason = "wrong password"
 		return echo.ErrUnauthorized
 	}
 
@@ -83,3 +91,18 @@ func Login(c echo.Context) error {
 		"token": t,
 	})
 }
+
+// Username returns the logged in user making the request, or "" when the
+// server runs without authentication
+func Username(c echo.Context) string {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	user, _ := claims["user"].(string)
+	return user
+}
@@ -0,0 +1,173 @@
+package cert
+
+import (
+	"errors"
+	"time"
+)
+
+// ApprovalRule marks signing requests that need a second person to approve
+// them before they are signed
+type ApprovalRule struct {
+	// Requests for any of these principals need approval
+	Principals []string `json:"principals,omitempty"`
+	// Requests for certificates valid longer than this need approval
+	TTLOverMinutes int `json:"ttl_over_minutes,omitempty"`
+	// Approvals needed, defaults to one
+	Approvals int `json:"approvals,omitempty"`
+	// Users who may approve, required. Requesters never approve their own
+	// requests, so a rule needs more approvers than approvals to be usable
+	// by its approvers too.
+	Approvers []string `json:"approvers"`
+}
+
+// ErrNotEnoughApprovers is returned for requests that could never get the
+// approvals they need, because too few users are allowed by every matching
+// rule
+var ErrNotEnoughApprovers = errors.New("not enough approvers are allowed by every matching approval rule")
+
+// Matches reports whether a request for principals valid for ttl needs the
+// rule's approval
+func (r ApprovalRule) Matches(principals []string, ttl time.Duration) bool {
+	for _, principal := range principals {
+		if contains(r.Principals, principal) {
+			return true
+		}
+	}
+	return r.TTLOverMinutes > 0 && ttl > time.Duration(r.TTLOverMinutes)*time.Minute
+}
+
+// RequiredApprovals returns the approvals the rule needs
+func (r ApprovalRule) RequiredApprovals() int {
+	if r.Approvals > 0 {
+		return r.Approvals
+	}
+	return 1
+}
+
+func (r ApprovalRule) validate() error {
+	if len(r.Principals) == 0 && r.TTLOverMinutes <= 0 {
+		return errors.New("approval rules need principals or ttl_over_minutes")
+	}
+	if r.Approvals < 0 || r.TTLOverMinutes < 0 {
+		return errors.New("approval rules can not be negative")
+	}
+	// Anyone could otherwise register a second account to approve with
+	if len(r.Approvers) == 0 {
+		return errors.New("approval rules need approvers")
+	}
+	if r.RequiredApprovals() > len(r.Approvers) {
+		return errors.New("approval rule needs more approvals than it has approvers")
+	}
+	return nil
+}
+
+// ApprovalsRequired returns the approvals a request by requester for
+// principals valid for ttl needs, and who may give them. Zero means it is
+// signed straight away. When several rules match the request needs the most
+// approvals any of them asks for, from users every one of them allows, and
+// ErrNotEnoughApprovers when fewer of those than needed are not the
+// requester.
+func (c CommonCa) ApprovalsRequired(principals []string, ttl time.Duration, requester string) (int, []string, error) {
+	required := 0
+	approvers := []string{}
+	for _, rule := range c.ApprovalRules {
+		if !rule.Matches(principals, ttl) {
+			continue
+		}
+		if required == 0 {
+			approvers = append(approvers, rule.Approvers...)
+		} else {
+			allowed := []string{}
+			for _, approver := range approvers {
+				if contains(rule.Approvers, approver) {
+					allowed = append(allowed, approver)
+				}
+			}
+			approvers = allowed
+		}
+		required = max(required, rule.RequiredApprovals())
+	}
+	if required == 0 {
+		return 0, nil, nil
+	}
+	eligible := 0
+	for _, approver := range approvers {
+		if approver != requester {
+			eligible++
+		}
+	}
+	if eligible < required {
+		return required, approvers, ErrNotEnoughApprovers
+	}
+	return required, approvers, nil
+}
+
+// SigningRequestStatus is where a signing request is in the approval workflow
+type SigningRequestStatus string
+
+const (
+	RequestPending  SigningRequestStatus = "pending"
+	RequestApproved SigningRequestStatus = "approved"
+	RequestDenied   SigningRequestStatus = "denied"
+)
+
+type CreateSigningRequest struct {
+	SignRequest
+	// Why the certificate is needed, shown to approvers
+	Reason string `json:"reason,omitempty"`
+}
+
+// Review is an approval or denial of a signing request
+type Review struct {
+	By      string    `json:"by"`
+	At      time.Time `json:"at"`
+	Comment string    `json:"comment,omitempty"`
+}
+
+type ReviewRequest struct {
+	// Shown with the review, e.g. why a request was denied
+	Comment string `json:"comment,omitempty"`
+}
+
+// SigningRequest is a signing request waiting for, or through, approval
+type SigningRequest struct {
+	ID        string `json:"id"`
+	CA        string `json:"ca"`
+	Requester string `json:"requester"`
+	// The request as submitted
+	Request   SignRequest          `json:"request"`
+	Reason    string               `json:"reason,omitempty"`
+	Status    SigningRequestStatus `json:"status"`
+	CreatedAt time.Time            `json:"created_at"`
+	// Approvals needed before the request is signed
+	ApprovalsRequired int `json:"approvals_required"`
+	// Users who may approve, nobody when empty
+	Approvers []string `json:"approvers,omitempty"`
+	Approvals []Review `json:"approvals,omitempty"`
+	Denial    *Review  `json:"denial,omitempty"`
+	// Certificate issued once the request is approved
+	SignedKey string `json:"signed_key,omitempty"`
+	Serial    uint64 `json:"serial,omitempty"`
+	// Issued in an emergency without approval, see BreakGlassRequest
+	BreakGlass bool   `json:"break_glass,omitempty"`
+	Ticket     string `json:"ticket,omitempty"`
+	KeyID      string `json:"key_id,omitempty"`
+}
+
+// ApprovedBy reports whether user has already approved the request
+func (r SigningRequest) ApprovedBy(user string) bool {
+	for _, approval := range r.Approvals {
+		if approval.By == user {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReview reports whether user may approve or deny the request
+func (r SigningRequest) CanReview(user string) bool {
+	if user == "" || user == r.Requester {
+		return false
+	}
+	return contains(r.Approvers, user)
+}
@@ -0,0 +1,53 @@
+package cert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApprovalsRequired(t *testing.T) {
+	ca := CommonCa{ApprovalRules: []ApprovalRule{
+		{Principals: []string{"root"}, Approvals: 2, Approvers: []string{"bob", "carol", "dave", "erin"}},
+		{TTLOverMinutes: 60, Approvers: []string{"carol", "dave"}},
+		{Principals: []string{"root", "db"}, Approvers: []string{"dave", "erin"}},
+		{Principals: []string{"billing"}, Approvers: []string{"frank"}},
+	}}
+	tests := []struct {
+		name       string
+		principals []string
+		ttl        time.Duration
+		requester  string
+		required   int
+		approvers  []string
+		err        error
+	}{
+		{"No rule matches", []string{"deploy"}, time.Hour, "alice", 0, nil, nil},
+		{"Principal rule", []string{"deploy", "root"}, time.Hour, "alice", 2, []string{"dave", "erin"}, nil},
+		{"TTL rule", []string{"deploy"}, 2 * time.Hour, "alice", 1, []string{"carol", "dave"}, nil},
+		{"Too few approvers every rule allows", []string{"root"}, 2 * time.Hour, "alice", 2, []string{"dave"}, ErrNotEnoughApprovers},
+		{"Approver lists without names in common", []string{"billing"}, 2 * time.Hour, "alice", 1, []string{}, ErrNotEnoughApprovers},
+		{"Requester is the only approver", []string{"billing"}, time.Hour, "frank", 1, []string{"frank"}, ErrNotEnoughApprovers},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			required, approvers, err := ca.ApprovalsRequired(tt.principals, tt.ttl, tt.requester)
+			assert.Equal(t, tt.required, required)
+			assert.Equal(t, tt.approvers, approvers)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestSigningRequestCanReview(t *testing.T) {
+	request := SigningRequest{Requester: "alice", Approvers: []string{"alice", "carol"}}
+	assert.True(t, request.CanReview("carol"))
+	assert.False(t, request.CanReview("bob"))
+	assert.False(t, request.CanReview("alice"))
+	assert.False(t, request.CanReview(""))
+
+	// No approvers means nobody, not anybody
+	request.Approvers = []string{}
+	assert.False(t, request.CanReview("bob"))
+}
@@ -0,0 +1,68 @@
+package cert
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BreakGlassMaxTTLMinutes is the longest a break-glass certificate is valid,
+// whatever the CA's max TTL
+const BreakGlassMaxTTLMinutes = 60
+
+// BreakGlassKeyIDPrefix starts the key ID of every break-glass certificate, so
+// they stand out in sshd's logs
+const BreakGlassKeyIDPrefix = "BREAK-GLASS"
+
+// BreakGlassRequest is an emergency signing request that skips the CA's
+// approval rules. It still has to pass the CA's key policy and principals.
+type BreakGlassRequest struct {
+	SignRequest
+	// Why the rules are being bypassed, mandatory
+	Reason string `json:"reason"`
+	// Incident or ticket reference, mandatory
+	Ticket string `json:"ticket"`
+}
+
+// BreakGlassTTL returns how long a break-glass certificate from the CA is
+// valid when the request does not say, the shorter of the CA's max TTL and
+// BreakGlassMaxTTLMinutes
+func (c CommonCa) BreakGlassTTL() int {
+	return min(c.MaxTTLMinutes, BreakGlassMaxTTLMinutes)
+}
+
+// CanBreakGlass reports whether user is one of the CA's break-glass
+// responders
+func (c CommonCa) CanBreakGlass(user string) bool {
+	return user != "" && contains(c.BreakGlassResponders, user)
+}
+
+// Validate checks the request is justified and short-lived
+func (r BreakGlassRequest) Validate() error {
+	if strings.TrimSpace(r.Reason) == "" {
+		return errors.New("break-glass requests need a reason")
+	}
+	if strings.TrimSpace(r.Ticket) == "" {
+		return errors.New("break-glass requests need a ticket reference")
+	}
+	if strings.ContainsAny(r.Ticket, " \t\n") {
+		return errors.New("break-glass ticket references can not contain spaces")
+	}
+	if r.ValidAfter != nil || r.ValidBefore != nil {
+		return errors.New("break-glass certificates start now, valid_after and valid_before can not be set")
+	}
+	if r.TTLMinutes < 0 || r.TTLMinutes > BreakGlassMaxTTLMinutes {
+		return fmt.Errorf("break-glass certificates are valid for at most %d minutes", BreakGlassMaxTTLMinutes)
+	}
+	return nil
+}
+
+// BreakGlassKeyID returns the key ID for a break-glass certificate issued to
+// requester under ticket
+func BreakGlassKeyID(ticket, requester string, at time.Time) string {
+	if requester == "" {
+		requester = "anonymous"
+	}
+	return fmt.Sprintf("%s ticket=%s user=%s at=%s", BreakGlassKeyIDPrefix, ticket, requester, at.UTC().Format(time.RFC3339))
+}
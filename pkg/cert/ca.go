@@ -2,16 +2,37 @@ package cert
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/ssh"
 )
 
 type CA struct {
-	Name            string
-	Signer          ssh.Signer
-	Bits            int
-	MaxTTLMinutes   int
-	ValidPrincipals []string
+	Name          string
+	Signer        ssh.Signer
+	Bits          int
+	MaxTTLMinutes int
+	// Longest host certificate lifetime, MaxTTLMinutes when zero
+	MaxHostTTLMinutes int
+	ValidPrincipals   []string
+	Tags              []string
+	Kind              CAKind
+	HostPatterns      []string
+	Accounts          map[string][]string
+	// Algorithm certificates are signed with, Signer is restricted to it
+	SignatureAlgorithm string
+	KeyPolicy          *KeyPolicy
+	// Seconds certificates start before they are signed
+	BackdateSeconds int
+	// Furthest ahead a requested validity window may start, in minutes
+	MaxFutureStartMinutes int
+	ApprovalRules         []ApprovalRule
+	// Users who may sign break-glass certificates
+	BreakGlassResponders []string
+	// Previous keys still trusted while a rotation is in progress
+	Retiring []ssh.PublicKey
 }
 
 func NewCA(name string, signer ssh.Signer, validPrincipals []string, bits, maxTtl int) CA {
@@ -25,29 +46,130 @@ func NewCA(name string, signer ssh.Signer, validPrincipals []string, bits, maxTt
 }
 
 func (c CA) CreateResponse() *CaResponse {
+	var retiring []string
+	for _, key := range c.Retiring {
+		retiring = append(retiring, string(ssh.MarshalAuthorizedKey(key)))
+	}
 	return &CaResponse{
 		CommonCa: CommonCa{
-			Name:            c.Name,
-			Type:            KeyType(c.Signer.PublicKey().Type()),
-			Bits:            c.Bits,
-			MaxTTLMinutes:   c.MaxTTLMinutes,
-			ValidPrincipals: c.ValidPrincipals,
+			Name:                  c.Name,
+			Type:                  KeyType(c.Signer.PublicKey().Type()),
+			Bits:                  c.Bits,
+			MaxTTLMinutes:         c.MaxTTLMinutes,
+			MaxHostTTLMinutes:     c.MaxHostTTLMinutes,
+			ValidPrincipals:       c.ValidPrincipals,
+			Tags:                  c.Tags,
+			Kind:                  c.Kind,
+			HostPatterns:          c.HostPatterns,
+			Accounts:              c.Accounts,
+			SignatureAlgorithm:    c.SignatureAlgorithm,
+			KeyPolicy:             c.KeyPolicy,
+			BackdateSeconds:       c.BackdateSeconds,
+			MaxFutureStartMinutes: c.MaxFutureStartMinutes,
+			ApprovalRules:         c.ApprovalRules,
+			BreakGlassResponders:  c.BreakGlassResponders,
 		},
-		PublicKey: string(ssh.MarshalAuthorizedKey(c.Signer.PublicKey())),
+		PublicKey:          string(ssh.MarshalAuthorizedKey(c.Signer.PublicKey())),
+		RetiringPublicKeys: retiring,
 	}
 }
 
 type CommonCa struct {
 	// Name of CA
 	Name string `json:"name"`
-	// Type of ca, rsa, ed25519
+	// Type of ca: ssh-rsa, ssh-ed25519, ecdsa-sha2-nistp256,
+	// ecdsa-sha2-nistp384 or ecdsa-sha2-nistp521
 	Type KeyType `json:"type"`
 	// Key length
 	Bits int `json:"bits"`
 	// Maximum TTL certs can be signed for
 	MaxTTLMinutes int `json:"max_ttl_minutes"`
+	// Maximum TTL host certificates can be signed for, renewed host
+	// certificates usually outlive user certificates. Defaults to
+	// MaxTTLMinutes, only for host CAs.
+	MaxHostTTLMinutes int `json:"max_host_ttl_minutes,omitempty"`
 	// List of Valid Principals
 	ValidPrincipals []string `json:"valid_principals"`
+	// Tags used to select CAs, e.g. for host trust bundles
+	Tags []string `json:"tags,omitempty"`
+	// Whether the CA signs user or host certificates, defaults to user
+	Kind CAKind `json:"kind,omitempty"`
+	// Host patterns a host CA is trusted for in known_hosts, e.g. *.example.com
+	HostPatterns []string `json:"host_patterns,omitempty"`
+	// Local accounts and the principals that may log in to them. Without it
+	// each valid principal may log in to the account of the same name.
+	Accounts map[string][]string `json:"accounts,omitempty"`
+	// Algorithm certificates are signed with. RSA CAs sign with rsa-sha2-512
+	// by default or rsa-sha2-256, other CAs with their key type.
+	SignatureAlgorithm string `json:"signature_algorithm,omitempty"`
+	// Restricts the user keys the CA signs, only for user CAs
+	KeyPolicy *KeyPolicy `json:"key_policy,omitempty"`
+	// Seconds certificates are valid before they are signed, for hosts whose
+	// clocks are behind. At most MaxBackdateSeconds.
+	BackdateSeconds int `json:"backdate_seconds,omitempty"`
+	// Furthest ahead in minutes a requested validity window may start,
+	// defaults to DefaultMaxFutureStartMinutes
+	MaxFutureStartMinutes int `json:"max_future_start_minutes,omitempty"`
+	// Requests matching any rule are queued for approval instead of signed
+	ApprovalRules []ApprovalRule `json:"approval_rules,omitempty"`
+	// Users who may sign break-glass certificates, nobody when empty. Only
+	// for user CAs.
+	BreakGlassResponders []string `json:"break_glass_responders,omitempty"`
+}
+
+const (
+	// MaxBackdateSeconds is the largest backdate allowance a CA can have
+	MaxBackdateSeconds = 3600
+	// DefaultMaxFutureStartMinutes lets certificates be requested up to a
+	// week ahead
+	DefaultMaxFutureStartMinutes = 7 * 24 * 60
+)
+
+// Backdate returns how long before signing certificates start
+func (c CommonCa) Backdate() time.Duration {
+	return time.Duration(c.BackdateSeconds) * time.Second
+}
+
+// MaxFutureStart returns the furthest ahead a certificate may start
+func (c CommonCa) MaxFutureStart() time.Duration {
+	if c.MaxFutureStartMinutes > 0 {
+		return time.Duration(c.MaxFutureStartMinutes) * time.Minute
+	}
+	return DefaultMaxFutureStartMinutes * time.Minute
+}
+
+// CAKind is the type of certificate a CA signs
+type CAKind string
+
+const (
+	UserCA CAKind = "user"
+	HostCA CAKind = "host"
+)
+
+// AccountPrincipals returns the principals allowed to log in to account
+func (c CommonCa) AccountPrincipals(account string) []string {
+	if c.Accounts != nil {
+		return c.Accounts[account]
+	}
+	for _, principal := range c.ValidPrincipals {
+		if principal == account {
+			return []string{principal}
+		}
+	}
+	return nil
+}
+
+// MaxHostTTL returns the longest a host certificate can be signed for
+func (c CommonCa) MaxHostTTL() int {
+	if c.MaxHostTTLMinutes > 0 {
+		return c.MaxHostTTLMinutes
+	}
+	return c.MaxTTLMinutes
+}
+
+// IsHostCA reports whether the CA signs host certificates
+func (c CommonCa) IsHostCA() bool {
+	return c.Kind == HostCA
 }
 
 type CaRequest struct {
@@ -58,12 +180,21 @@ func (c CaRequest) Validate() (error, bool) {
 	if c.Name == "" {
 		return errors.New("invalid name"), false
 	}
-	if c.Type != RSAKey && c.Type != ED25519 {
+	switch {
+	case c.Type == RSAKey:
+		if !(c.Bits == 2048 || c.Bits == 3072 || c.Bits == 4096) {
+			return errors.New("invalid key length"), false
+		}
+	case c.Type.IsECDSA():
+		// The curve sets the key length, bits may only repeat it
+		if c.Bits != 0 && c.Bits != c.Type.CurveBits() {
+			return fmt.Errorf("invalid key length, %s keys are %d bits", c.Type, c.Type.CurveBits()), false
+		}
+	case c.Type != ED25519:
 		return InvalidKeyErr, false
 	}
-	if !(c.Bits == 2048 || c.Bits == 3072 || c.Bits == 4096) &&
-		c.Type != ED25519 {
-		return errors.New("invalid key length"), false
+	if c.SignatureAlgorithm != "" && !contains(SignatureAlgorithms(c.Type), c.SignatureAlgorithm) {
+		return fmt.Errorf("invalid signature algorithm, %s CAs sign with %s", c.Type, strings.Join(SignatureAlgorithms(c.Type), " or ")), false
 	}
 	if len(c.ValidPrincipals) < 1 {
 		return errors.New("no principals provided"), false
@@ -71,6 +202,50 @@ func (c CaRequest) Validate() (error, bool) {
 	if c.MaxTTLMinutes == 0 {
 		return errors.New("MaxTTL not set"), false
 	}
+	if c.Kind != "" && c.Kind != UserCA && c.Kind != HostCA {
+		return errors.New("invalid kind"), false
+	}
+	if c.IsHostCA() && len(c.HostPatterns) < 1 {
+		return errors.New("no host patterns provided"), false
+	}
+	if c.MaxHostTTLMinutes < 0 || (c.MaxHostTTLMinutes > 0 && !c.IsHostCA()) {
+		return errors.New("max host TTL is only for host CAs"), false
+	}
+	if c.IsHostCA() && len(c.Accounts) > 0 {
+		return errors.New("host CAs do not map accounts"), false
+	}
+	for account, principals := range c.Accounts {
+		for _, principal := range principals {
+			if !contains(c.ValidPrincipals, principal) {
+				return fmt.Errorf("account %s maps principal %s which is not a valid principal", account, principal), false
+			}
+		}
+	}
+	if c.BackdateSeconds < 0 || c.BackdateSeconds > MaxBackdateSeconds {
+		return fmt.Errorf("backdate must be between 0 and %d seconds", MaxBackdateSeconds), false
+	}
+	if c.MaxFutureStartMinutes < 0 {
+		return errors.New("max future start can not be negative"), false
+	}
+	for _, rule := range c.ApprovalRules {
+		if err := rule.validate(); err != nil {
+			return err, false
+		}
+	}
+	if c.IsHostCA() && c.KeyPolicy != nil {
+		return errors.New("key policies are only for user CAs"), false
+	}
+	if c.IsHostCA() && len(c.BreakGlassResponders) > 0 {
+		return errors.New("break-glass responders are only for user CAs"), false
+	}
+	if err := c.KeyPolicy.Validate(); err != nil {
+		return err, false
+	}
+	for _, pattern := range c.HostPatterns {
+		if pattern == "" || strings.ContainsAny(pattern, ", \t") {
+			return fmt.Errorf("invalid host pattern %q", pattern), false
+		}
+	}
 	return nil, true
 }
 
@@ -78,4 +253,33 @@ type CaResponse struct {
 	CommonCa
 	// CA Public Key
 	PublicKey string `json:"public_key"`
+	// Previous public keys still trusted while the CA is being rotated
+	RetiringPublicKeys []string `json:"retiring_public_keys,omitempty"`
+}
+
+// TrustedKeys parses the CA's current public key followed by any retiring keys
+func (c CaResponse) TrustedKeys() ([]ssh.PublicKey, error) {
+	keys := []ssh.PublicKey{}
+	for _, authorizedKey := range append([]string{c.PublicKey}, c.RetiringPublicKeys...) {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse public key of CA %s: %w", c.Name, err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// HasTag reports whether the CA is tagged with tag
+func (c CommonCa) HasTag(tag string) bool {
+	return contains(c.Tags, tag)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
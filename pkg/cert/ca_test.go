@@ -20,6 +20,33 @@ func TestCaValidation(t *testing.T) {
 		{"Invalid RSA 1234 bits", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-rsa", Bits: 1234, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, false},
 		{"Invalid empty Type", CaRequest{CommonCa{Name: "TestCA", Type: "", Bits: 2048, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, false},
 		{"Invalid RSA 0 bits", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-rsa", Bits: 0, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, false},
+		{"Valid ECDSA P-256", CaRequest{CommonCa{Name: "TestCA", Type: "ecdsa-sha2-nistp256", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, true},
+		{"Valid ECDSA P-384 with curve bits", CaRequest{CommonCa{Name: "TestCA", Type: "ecdsa-sha2-nistp384", Bits: 384, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, true},
+		{"Valid ECDSA P-521 with curve bits", CaRequest{CommonCa{Name: "TestCA", Type: "ecdsa-sha2-nistp521", Bits: 521, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, true},
+		{"Invalid ECDSA bits for curve", CaRequest{CommonCa{Name: "TestCA", Type: "ecdsa-sha2-nistp256", Bits: 384, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, false},
+		{"Invalid ECDSA RSA bits", CaRequest{CommonCa{Name: "TestCA", Type: "ecdsa-sha2-nistp256", Bits: 2048, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, false},
+		{"Invalid ECDSA curve", CaRequest{CommonCa{Name: "TestCA", Type: "ecdsa-sha2-nistp224", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600}}, false},
+		{"Valid host CA", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"web1.example.com"}, MaxTTLMinutes: 3600, Kind: HostCA, HostPatterns: []string{"*.example.com"}}}, true},
+		{"Invalid host CA without patterns", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"web1.example.com"}, MaxTTLMinutes: 3600, Kind: HostCA}}, false},
+		{"Invalid host pattern", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"web1.example.com"}, MaxTTLMinutes: 3600, Kind: HostCA, HostPatterns: []string{"a.com,b.com"}}}, false},
+		{"Valid accounts", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"alice"}, MaxTTLMinutes: 3600, Accounts: map[string][]string{"root": {"alice"}}}}, true},
+		{"Invalid account principal", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"alice"}, MaxTTLMinutes: 3600, Accounts: map[string][]string{"root": {"mallory"}}}}, false},
+		{"Valid max host TTL", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"web1.example.com"}, MaxTTLMinutes: 60, MaxHostTTLMinutes: 43200, Kind: HostCA, HostPatterns: []string{"*.example.com"}}}, true},
+		{"Invalid max host TTL on user CA", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 60, MaxHostTTLMinutes: 43200}}, false},
+		{"Valid RSA SHA-256 signatures", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-rsa", Bits: 2048, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, SignatureAlgorithm: "rsa-sha2-256"}}, true},
+		{"Invalid RSA SHA-1 signatures", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-rsa", Bits: 2048, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, SignatureAlgorithm: "ssh-rsa"}}, false},
+		{"Invalid signature algorithm for key type", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, SignatureAlgorithm: "rsa-sha2-512"}}, false},
+		{"Valid key policy", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, KeyPolicy: &KeyPolicy{RequireSecurityKey: true}}}, true},
+		{"Invalid key policy", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, KeyPolicy: &KeyPolicy{AllowedTypes: []KeyType{"ssh-dss"}}}}, false},
+		{"Invalid key policy on host CA", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"web1.example.com"}, MaxTTLMinutes: 3600, Kind: HostCA, HostPatterns: []string{"*.example.com"}, KeyPolicy: &KeyPolicy{RequireSecurityKey: true}}}, false},
+		{"Valid backdate", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, BackdateSeconds: 60, MaxFutureStartMinutes: 2880}}, true},
+		{"Invalid backdate over max", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, BackdateSeconds: 3601}}, false},
+		{"Invalid negative max future start", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, MaxFutureStartMinutes: -1}}, false},
+		{"Valid approval rule", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"root"}, MaxTTLMinutes: 3600, ApprovalRules: []ApprovalRule{{Principals: []string{"root"}, Approvals: 2, Approvers: []string{"carol", "dave"}}}}}, true},
+		{"Invalid approval rule without approvers", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"root"}, MaxTTLMinutes: 3600, ApprovalRules: []ApprovalRule{{Principals: []string{"root"}}}}}, false},
+		{"Invalid empty approval rule", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"root"}, MaxTTLMinutes: 3600, ApprovalRules: []ApprovalRule{{Approvals: 2}}}}, false},
+		{"Invalid approval rule without enough approvers", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"root"}, MaxTTLMinutes: 3600, ApprovalRules: []ApprovalRule{{Principals: []string{"root"}, Approvals: 2, Approvers: []string{"carol"}}}}}, false},
+		{"Invalid kind", CaRequest{CommonCa{Name: "TestCA", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 3600, Kind: "group"}}, false},
 	}
 
 	// Loop through test cases
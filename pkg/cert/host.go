@@ -0,0 +1,144 @@
+package cert
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type JoinTokenRequest struct {
+	// Host CA the token enrolls hosts with
+	CA string `json:"ca"`
+	// Host names the enrolling host may ask for, at least one
+	Hostnames []string `json:"hostnames"`
+	// How long the issued host certificates are valid for
+	TTLMinutes int `json:"ttl_minutes"`
+	// How long the token can be used for, defaults to an hour
+	ExpiresInMinutes int `json:"expires_in_minutes,omitempty"`
+	// Lets a host already in the registry enroll again, replacing its keys,
+	// e.g. after it was rebuilt
+	Reenroll bool `json:"reenroll,omitempty"`
+}
+
+type JoinTokenResponse struct {
+	// One time secret handed to the enrolling host
+	Token string `json:"token"`
+	// Identifies the token in the host registry, the secret is not stored
+	ID        string    `json:"id"`
+	CA        string    `json:"ca"`
+	Hostnames []string  `json:"hostnames"`
+	Reenroll  bool      `json:"reenroll,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type EnrollRequest struct {
+	// Join token issued by an admin
+	Token string `json:"token"`
+	// Host names to certify, the first names the host in the registry
+	Hostnames []string `json:"hostnames"`
+	// Host public keys in authorized key format, as in ssh_host_*_key.pub
+	HostKeys []string `json:"host_keys"`
+}
+
+type EnrollResponse struct {
+	// The host as recorded in the registry
+	Host Host `json:"host"`
+	// Signed host certificates, in the order of the request's host keys
+	Certificates []string `json:"certificates"`
+}
+
+// Host is an enrolled host in the registry
+type Host struct {
+	// First host name the host enrolled with
+	Name      string   `json:"name"`
+	Hostnames []string `json:"hostnames"`
+	// Host CA the host's certificates were issued by
+	CA string `json:"ca"`
+	// Join token the host enrolled with
+	TokenID string `json:"token_id"`
+	// Lifetime of the host's certificates, kept on renewal
+	TTLMinutes int       `json:"ttl_minutes"`
+	EnrolledAt time.Time `json:"enrolled_at"`
+	Keys       []HostKey `json:"keys"`
+}
+
+// HostKey is a host key and the certificate last issued for it
+type HostKey struct {
+	Type        string    `json:"type"`
+	Fingerprint string    `json:"fingerprint"`
+	PublicKey   string    `json:"public_key"`
+	Serial      uint64    `json:"serial"`
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+type RenewHostRequest struct {
+	// Name of the host in the registry
+	Name string `json:"name"`
+	// Host public key to renew the certificate of, in authorized key format
+	PublicKey string `json:"public_key"`
+	// Unix time the proof was signed at
+	Timestamp int64 `json:"timestamp"`
+	// Signature of RenewProof by the host key, base64 encoded SSH signature
+	Signature string `json:"signature"`
+	// How long the certificate is valid for, the enrolled TTL when zero
+	TTLMinutes int `json:"ttl_minutes,omitempty"`
+}
+
+type RenewHostResponse struct {
+	// Renewed host certificate
+	Certificate string    `json:"certificate"`
+	Serial      uint64    `json:"serial"`
+	ValidBefore time.Time `json:"valid_before"`
+}
+
+var ErrInvalidProof = errors.New("invalid proof of possession")
+
+// RenewProof is the message a host signs with its host key to prove
+// possession when renewing
+func RenewProof(name string, publicKey ssh.PublicKey, timestamp int64) []byte {
+	return ssh.Marshal(struct {
+		Purpose   string
+		Name      string
+		PublicKey []byte
+		Timestamp uint64
+	}{"sshtrust-host-renew-v1", name, publicKey.Marshal(), uint64(timestamp)})
+}
+
+// NewRenewHostRequest signs a renewal request for the host key held by signer
+func NewRenewHostRequest(signer ssh.Signer, name string, ttlMinutes int, now time.Time) (*RenewHostRequest, error) {
+	timestamp := now.Unix()
+	signature, err := signer.Sign(rand.Reader, RenewProof(name, signer.PublicKey(), timestamp))
+	if err != nil {
+		return nil, err
+	}
+	return &RenewHostRequest{
+		Name:       name,
+		PublicKey:  string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+		Timestamp:  timestamp,
+		Signature:  base64.StdEncoding.EncodeToString(ssh.Marshal(signature)),
+		TTLMinutes: ttlMinutes,
+	}, nil
+}
+
+// VerifyProof checks the request was signed by its host key, returning the key
+func (r RenewHostRequest) VerifyProof() (ssh.PublicKey, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.PublicKey))
+	if err != nil {
+		return nil, err
+	}
+	blob, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return nil, ErrInvalidProof
+	}
+	var signature ssh.Signature
+	if err := ssh.Unmarshal(blob, &signature); err != nil {
+		return nil, ErrInvalidProof
+	}
+	if err := publicKey.Verify(RenewProof(r.Name, publicKey, r.Timestamp), &signature); err != nil {
+		return nil, ErrInvalidProof
+	}
+	return publicKey, nil
+}
@@ -0,0 +1,101 @@
+package cert
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type InspectRequest struct {
+	// Certificate in authorized key format, as written to *-cert.pub
+	Certificate string `json:"certificate"`
+}
+
+type InspectResponse struct {
+	// Certificate type, user or host
+	Type string `json:"type"`
+	// Certificate key type, e.g. ssh-ed25519-cert-v01@openssh.com
+	KeyType string `json:"key_type"`
+	// Fingerprint of the certified public key
+	PublicKeyFingerprint string `json:"public_key_fingerprint"`
+	// Serial number
+	Serial uint64 `json:"serial"`
+	// Key ID
+	KeyID string `json:"key_id"`
+	// Principals the certificate is valid for
+	Principals []string `json:"principals"`
+	// Start of the validity period
+	ValidAfter time.Time `json:"valid_after"`
+	// End of the validity period, omitted when the certificate never expires
+	ValidBefore *time.Time `json:"valid_before,omitempty"`
+	// Certificate extensions
+	Extensions map[string]string `json:"extensions"`
+	// Certificate critical options
+	CriticalOptions map[string]string `json:"critical_options"`
+	// Fingerprint of the CA key that signed the certificate
+	SigningCAFingerprint string `json:"signing_ca_fingerprint"`
+	// Algorithm of the CA signature
+	SignatureAlgorithm string `json:"signature_algorithm"`
+	// Name of the CA in the store that signed the certificate, empty when
+	// the signer is not a known CA
+	MatchedCA string `json:"matched_ca,omitempty"`
+}
+
+// ParseCertificate parses a certificate in authorized key format
+func ParseCertificate(data []byte) (*ssh.Certificate, error) {
+	key, _, _, _, err := ssh.ParseAuthorizedKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse certificate: %w", err)
+	}
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, errors.New("key is not a certificate")
+	}
+	return cert, nil
+}
+
+// SignedBy reports whether key signed the certificate, checking the
+// signature rather than trusting the signature key it names
+func SignedBy(cert *ssh.Certificate, key ssh.PublicKey) bool {
+	if cert.Signature == nil || !bytes.Equal(cert.SignatureKey.Marshal(), key.Marshal()) {
+		return false
+	}
+	// The signature covers the certificate up to the signature itself, as
+	// in ssh.CertChecker
+	unsigned := *cert
+	unsigned.Signature = nil
+	data := unsigned.Marshal()
+	data = data[:len(data)-4]
+	return key.Verify(data, cert.Signature) == nil
+}
+
+// Inspect describes the contents of a certificate
+func Inspect(cert *ssh.Certificate) *InspectResponse {
+	certType := "user"
+	if cert.CertType == ssh.HostCert {
+		certType = "host"
+	}
+	info := &InspectResponse{
+		Type:                 certType,
+		KeyType:              cert.Type(),
+		PublicKeyFingerprint: ssh.FingerprintSHA256(cert.Key),
+		Serial:               cert.Serial,
+		KeyID:                cert.KeyId,
+		Principals:           cert.ValidPrincipals,
+		ValidAfter:           time.Unix(int64(cert.ValidAfter), 0),
+		Extensions:           cert.Extensions,
+		CriticalOptions:      cert.CriticalOptions,
+		SigningCAFingerprint: ssh.FingerprintSHA256(cert.SignatureKey),
+	}
+	if cert.ValidBefore != ssh.CertTimeInfinity {
+		validBefore := time.Unix(int64(cert.ValidBefore), 0)
+		info.ValidBefore = &validBefore
+	}
+	if cert.Signature != nil {
+		info.SignatureAlgorithm = cert.Signature.Format
+	}
+	return info
+}
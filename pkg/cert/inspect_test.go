@@ -0,0 +1,74 @@
+package cert
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestInspect(t *testing.T) {
+	caSigner, err := GenerateSSHKey(ED25519, 0)
+	assert.NoError(t, err)
+	userSigner, err := GenerateSSHKey(ED25519, 0)
+	assert.NoError(t, err)
+
+	signed, err := SignUserKey(caSigner, userSigner.PublicKey(), []string{"alice", "deploy"}, 30)
+	assert.NoError(t, err)
+
+	parsed, err := ParseCertificate(ssh.MarshalAuthorizedKey(signed))
+	assert.NoError(t, err)
+
+	info := Inspect(parsed)
+	assert.Equal(t, "user", info.Type)
+	assert.Equal(t, ssh.CertAlgoED25519v01, info.KeyType)
+	assert.Equal(t, []string{"alice", "deploy"}, info.Principals)
+	assert.Equal(t, ssh.FingerprintSHA256(userSigner.PublicKey()), info.PublicKeyFingerprint)
+	assert.Equal(t, ssh.FingerprintSHA256(caSigner.PublicKey()), info.SigningCAFingerprint)
+	assert.Equal(t, ssh.KeyAlgoED25519, info.SignatureAlgorithm)
+	assert.Contains(t, info.Extensions, "permit-pty")
+	if assert.NotNil(t, info.ValidBefore) {
+		assert.Equal(t, 30*time.Minute, info.ValidBefore.Sub(info.ValidAfter))
+	}
+
+	// Plain public keys are rejected
+	_, err = ParseCertificate(ssh.MarshalAuthorizedKey(userSigner.PublicKey()))
+	assert.Error(t, err)
+}
+
+func TestInspectNeverExpires(t *testing.T) {
+	caSigner, err := GenerateSSHKey(ED25519, 0)
+	assert.NoError(t, err)
+
+	cert := &ssh.Certificate{
+		Key:         caSigner.PublicKey(),
+		CertType:    ssh.HostCert,
+		ValidBefore: ssh.CertTimeInfinity,
+	}
+	assert.NoError(t, cert.SignCert(rand.Reader, caSigner))
+
+	info := Inspect(cert)
+	assert.Equal(t, "host", info.Type)
+	assert.Nil(t, info.ValidBefore)
+}
+
+func TestSignedBy(t *testing.T) {
+	caSigner, err := GenerateSSHKey(ED25519, 0)
+	assert.NoError(t, err)
+	otherSigner, err := GenerateSSHKey(ED25519, 0)
+	assert.NoError(t, err)
+	userSigner, err := GenerateSSHKey(ED25519, 0)
+	assert.NoError(t, err)
+
+	signed, err := SignUserKey(caSigner, userSigner.PublicKey(), []string{"alice"}, 30)
+	assert.NoError(t, err)
+	assert.True(t, SignedBy(signed, caSigner.PublicKey()))
+	assert.False(t, SignedBy(signed, otherSigner.PublicKey()))
+
+	// Edited after signing
+	edited := *signed
+	edited.ValidPrincipals = []string{"root"}
+	assert.False(t, SignedBy(&edited, caSigner.PublicKey()))
+}
@@ -1,7 +1,9 @@
 package cert
 
 import (
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
 	"errors"
@@ -13,29 +15,95 @@ import (
 type KeyType string
 
 const (
-	RSAKey  KeyType = "ssh-rsa"
-	ED25519 KeyType = "ssh-ed25519"
+	RSAKey    KeyType = "ssh-rsa"
+	ED25519   KeyType = "ssh-ed25519"
+	ECDSAP256 KeyType = "ecdsa-sha2-nistp256"
+	ECDSAP384 KeyType = "ecdsa-sha2-nistp384"
+	ECDSAP521 KeyType = "ecdsa-sha2-nistp521"
 )
 
 var InvalidKeyErr error = errors.New("unsupported key type")
 
-// GenerateSSHKey generates a new SSH keypair with a 4096-bit RSA private key
+// ecdsaCurves maps the ECDSA key types to their curves
+var ecdsaCurves = map[KeyType]elliptic.Curve{
+	ECDSAP256: elliptic.P256(),
+	ECDSAP384: elliptic.P384(),
+	ECDSAP521: elliptic.P521(),
+}
+
+// IsECDSA reports whether the key type is one of the ECDSA curves
+func (k KeyType) IsECDSA() bool {
+	_, ok := ecdsaCurves[k]
+	return ok
+}
+
+// CurveBits returns the size of an ECDSA key type's curve, or 0 for other
+// key types
+func (k KeyType) CurveBits() int {
+	if curve, ok := ecdsaCurves[k]; ok {
+		return curve.Params().BitSize
+	}
+	return 0
+}
+
+// ECDSAKeyType returns the ECDSA key type for a curve size, as ssh-keygen's
+// -t ecdsa -b takes it
+func ECDSAKeyType(bits int) (KeyType, error) {
+	for keyType, curve := range ecdsaCurves {
+		if curve.Params().BitSize == bits {
+			return keyType, nil
+		}
+	}
+	return "", errors.New("invalid ECDSA curve size, expected 256, 384 or 521")
+}
+
+// SignatureAlgorithms returns the algorithms a CA of keyType can sign
+// certificates with, the default first. RSA CAs only sign with SHA-2, OpenSSH
+// refuses ssh-rsa (SHA-1) signatures by default.
+func SignatureAlgorithms(keyType KeyType) []string {
+	if keyType == RSAKey {
+		return []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256}
+	}
+	return []string{string(keyType)}
+}
+
+// NewCASigner restricts signer to sign with algorithm
+func NewCASigner(signer ssh.Signer, algorithm string) (ssh.Signer, error) {
+	algorithmSigner, ok := signer.(ssh.AlgorithmSigner)
+	if !ok {
+		return nil, errors.New("signer cannot choose its signature algorithm")
+	}
+	return ssh.NewSignerWithAlgorithms(algorithmSigner, []string{algorithm})
+}
+
+// GenerateSSHKey generates a new SSH keypair of keyType
 func GenerateSSHKey(keyType KeyType, bits int) (ssh.Signer, error) {
+	_, signer, err := GenerateSSHKeyPair(keyType, bits)
+	return signer, err
+}
+
+// GenerateSSHKeyPair generates a new SSH keypair, returning the raw private
+// key alongside its signer for callers such as ssh-agent that need both.
+func GenerateSSHKeyPair(keyType KeyType, bits int) (interface{}, ssh.Signer, error) {
 	privateKey, err := generatePrivateKey(keyType, bits)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	// Create an SSH signer using the generated private key
 	signer, err := ssh.NewSignerFromKey(privateKey)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	return signer, nil
+	return privateKey, signer, nil
 }
 
-// generatePrivateKey generates a new RSA private key
+// generatePrivateKey generates a new private key. bits is the RSA key
+// length, ECDSA curves are picked by the key type.
 func generatePrivateKey(keyType KeyType, bits int) (interface{}, error) {
+	if curve, ok := ecdsaCurves[keyType]; ok {
+		return ecdsa.GenerateKey(curve, rand.Reader)
+	}
 	switch keyType {
 	case RSAKey:
 		privateKey, err := rsa.GenerateKey(rand.Reader, bits)
@@ -1,13 +1,19 @@
 package cert
 
 import (
+	"bytes"
 	"os"
 	"testing"
+
+	"golang.org/x/crypto/ssh"
 )
 
 var keyTypeList []KeyType = []KeyType{
 	RSAKey,
 	ED25519,
+	ECDSAP256,
+	ECDSAP384,
+	ECDSAP521,
 }
 
 // TestGenerateSSHKey tests the generation of an SSH keypair
@@ -30,6 +36,24 @@ func TestGenerateSSHKey(t *testing.T) {
 	}
 }
 
+// TestGenerateSSHKeyPair tests the private key matches the returned signer
+func TestGenerateSSHKeyPair(t *testing.T) {
+	for _, key := range keyTypeList {
+		privateKey, signer, err := GenerateSSHKeyPair(key, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate SSH keypair: %v", err)
+		}
+
+		fromPrivate, err := ssh.NewSignerFromKey(privateKey)
+		if err != nil {
+			t.Fatalf("Private key is not usable as a signer: %v", err)
+		}
+		if !bytes.Equal(fromPrivate.PublicKey().Marshal(), signer.PublicKey().Marshal()) {
+			t.Fatalf("Private key does not match signer for %s", key)
+		}
+	}
+}
+
 // TestSavePublicKey tests the saving of the public key to a file
 func TestSavePublicKey(t *testing.T) {
 	// Generate a test signer (SSH keypair)
@@ -58,3 +82,26 @@ func TestSavePublicKey(t *testing.T) {
 		os.Remove(filePath)
 	}
 }
+
+// TestECDSAKeyType tests curve sizes map to the ECDSA key types
+func TestECDSAKeyType(t *testing.T) {
+	for _, key := range []KeyType{ECDSAP256, ECDSAP384, ECDSAP521} {
+		keyType, err := ECDSAKeyType(key.CurveBits())
+		if err != nil || keyType != key {
+			t.Fatalf("Expected %s for %d bits, got %s: %v", key, key.CurveBits(), keyType, err)
+		}
+		signer, err := GenerateSSHKey(key, 0)
+		if err != nil {
+			t.Fatalf("Failed to generate %s key: %v", key, err)
+		}
+		if signer.PublicKey().Type() != string(key) {
+			t.Fatalf("Expected a %s key, got %s", key, signer.PublicKey().Type())
+		}
+	}
+	if _, err := ECDSAKeyType(2048); err == nil {
+		t.Fatal("Expected an error for an invalid curve size")
+	}
+	if RSAKey.IsECDSA() || RSAKey.CurveBits() != 0 {
+		t.Fatal("RSA is not an ECDSA key type")
+	}
+}
@@ -0,0 +1,149 @@
+package cert
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Security key backed user key types, the private key never leaves the
+// hardware token
+const (
+	SKED25519   KeyType = ssh.KeyAlgoSKED25519
+	SKECDSAP256 KeyType = ssh.KeyAlgoSKECDSA256
+)
+
+// UserKeyTypes are the user key types CAs can sign. DSA keys are never
+// signed.
+var UserKeyTypes = []KeyType{RSAKey, ED25519, ECDSAP256, ECDSAP384, ECDSAP521, SKED25519, SKECDSAP256}
+
+// MinRSABits is the smallest RSA user key any CA signs
+const MinRSABits = 2048
+
+// IsSecurityKey reports whether the key type is backed by a security key
+func (k KeyType) IsSecurityKey() bool {
+	return k == SKED25519 || k == SKECDSAP256
+}
+
+// KeyPolicy restricts the user keys a CA signs. Without one a CA signs any
+// of the UserKeyTypes, with RSA keys of at least MinRSABits.
+type KeyPolicy struct {
+	// Key types the CA signs, defaults to all UserKeyTypes
+	AllowedTypes []KeyType `json:"allowed_types,omitempty"`
+	// Minimum key length in bits by key type, e.g. {"ssh-rsa": 3072}
+	MinBits map[KeyType]int `json:"min_bits,omitempty"`
+	// Only sign keys backed by a security key, sk-ssh-ed25519@openssh.com
+	// and sk-ecdsa-sha2-nistp256@openssh.com
+	RequireSecurityKey bool `json:"require_security_key,omitempty"`
+	// Require a PIN or biometric check on the security key as well as a
+	// touch, certificates get the verify-required critical option. Only
+	// security key backed keys are signed.
+	RequireUserVerification bool `json:"require_user_verification,omitempty"`
+}
+
+// KeyPolicyError is returned for a key refused by a CA's key policy, Rule is
+// the policy field that refused it
+type KeyPolicyError struct {
+	Rule   string
+	Reason string
+}
+
+func (e *KeyPolicyError) Error() string {
+	return fmt.Sprintf("key policy %s: %s", e.Rule, e.Reason)
+}
+
+// Validate checks the policy only refers to user key types CAs can sign
+func (p *KeyPolicy) Validate() error {
+	if p == nil {
+		return nil
+	}
+	for _, keyType := range p.AllowedTypes {
+		if !isUserKeyType(keyType) {
+			return fmt.Errorf("key policy allows unsupported key type %s", keyType)
+		}
+		if (p.RequireSecurityKey || p.RequireUserVerification) && !keyType.IsSecurityKey() {
+			return fmt.Errorf("key policy allows %s but requires security keys", keyType)
+		}
+	}
+	for keyType, bits := range p.MinBits {
+		if !isUserKeyType(keyType) {
+			return fmt.Errorf("key policy sets minimum bits for unsupported key type %s", keyType)
+		}
+		if bits <= 0 {
+			return fmt.Errorf("key policy minimum bits for %s must be positive", keyType)
+		}
+	}
+	return nil
+}
+
+// Check returns a KeyPolicyError if the CA may not sign key. A nil policy
+// applies the defaults.
+func (p *KeyPolicy) Check(key ssh.PublicKey) error {
+	keyType := KeyType(key.Type())
+	if !isUserKeyType(keyType) {
+		return &KeyPolicyError{"allowed_types", fmt.Sprintf("%s keys are not supported, use one of %s", keyType, joinKeyTypes(UserKeyTypes))}
+	}
+	if p != nil && p.RequireSecurityKey && !keyType.IsSecurityKey() {
+		return &KeyPolicyError{"require_security_key", fmt.Sprintf("CA only signs security key backed keys, got %s", keyType)}
+	}
+	if p != nil && p.RequireUserVerification && !keyType.IsSecurityKey() {
+		return &KeyPolicyError{"require_user_verification", fmt.Sprintf("user verification needs a security key backed key, got %s", keyType)}
+	}
+	if p != nil && len(p.AllowedTypes) > 0 && !containsKeyType(p.AllowedTypes, keyType) {
+		return &KeyPolicyError{"allowed_types", fmt.Sprintf("%s keys are not allowed, use one of %s", keyType, joinKeyTypes(p.AllowedTypes))}
+	}
+	minBits := 0
+	if keyType == RSAKey {
+		minBits = MinRSABits
+	}
+	if p != nil && p.MinBits[keyType] > minBits {
+		minBits = p.MinBits[keyType]
+	}
+	if bits := keyBits(key); bits < minBits {
+		return &KeyPolicyError{"min_bits", fmt.Sprintf("%s key is %d bits, at least %d are required", keyType, bits, minBits)}
+	}
+	return nil
+}
+
+// keyBits returns the length of key, security keys by their curve
+func keyBits(key ssh.PublicKey) int {
+	switch KeyType(key.Type()) {
+	case ED25519, SKED25519, SKECDSAP256:
+		return 256
+	}
+	cryptoKey, ok := key.(ssh.CryptoPublicKey)
+	if !ok {
+		return 0
+	}
+	switch k := cryptoKey.CryptoPublicKey().(type) {
+	case *rsa.PublicKey:
+		return k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return k.Curve.Params().BitSize
+	}
+	return 0
+}
+
+func isUserKeyType(keyType KeyType) bool {
+	return containsKeyType(UserKeyTypes, keyType)
+}
+
+func containsKeyType(list []KeyType, keyType KeyType) bool {
+	for _, k := range list {
+		if k == keyType {
+			return true
+		}
+	}
+	return false
+}
+
+func joinKeyTypes(keyTypes []KeyType) string {
+	names := make([]string, len(keyTypes))
+	for i, keyType := range keyTypes {
+		names[i] = string(keyType)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,114 @@
+package cert
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	skEd25519Key = "sk-ssh-ed25519@openssh.com AAAAGnNrLXNzaC1lZDI1NTE5QG9wZW5zc2guY29tAAAAIBYn2e7wnMiZEmCARxhfZLGVHCynH3bHJDV4nbF9SBTlAAAABHNzaDo="
+	skECDSAKey   = "sk-ecdsa-sha2-nistp256@openssh.com AAAAInNrLWVjZHNhLXNoYTItbmlzdHAyNTZAb3BlbnNzaC5jb20AAAAIbmlzdHAyNTYAAABBBBig68WuTb6mqYZqbBNLXZPXs9JKzNYYRIoH4+FIM0jIeOzZ5vxgUF+E8mTuZyI5lXunlerHhCNWsGJscT84/T8AAAAEc3NoOg=="
+	rsa1024Key   = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDFiiH09E/KHO2WKtl46JRWUdKJa0vfajI5FA2/ZHknqwKeKKi7/6HY2YA2RABQ5AoC5Rh6LSsdqkME2YoRYrukLJ9n+Q39PD3LdtBg3kEeXTliefoWLDtS+yptCMqFvtMNk5V1y3T7Qwkv1Z6hQla0t8XxiQUz/iwJ7tM97XjRKQ=="
+	dsaKey       = "ssh-dss AAAAB3NzaC1kc3MAAACBAKZD71pFA7LMGHuN9tpYlxbEgOhyeUoBfYzUzkz0uHjQWJqiYOK2DlWSjgz8uUTE8OG1c3YUF0f00xfZMHQZbZxgFX9NffmsZbR10FMASLqRHzAJYb2wAsfyeeYC02FXrxFsjkUyDlIjtzoxUZrPce6qxAb1g2eTOLRowrUJZ5BxAAAAFQCPyVrs1Ut7r9bwDR2ZgoTZIJzEjwAAAIANTD3J+ZA8YtpxVZF2RSEPdGvhcD7VMuhE6qj1nNzgSv8LUfyA6v9dej48bo+6gM59Jpm3WN0qw/9Qk0zfKlOyxiZjI4Dn3+5/wMHBtFTpQUTz0f1RzgOkX3VJEc+hbqsOQckefV6W5rbO07lE/YRXY7r3Eyh4ManoiSpK3tTIkQAAAIBzSPR8b0nZcE3HWRmtnySMSMWj4+FfG3UKcm5DpixDDdI2ckOvak1u/DGuz007mdGi6CtF/j0KtTnHtMYSOw7Zc/95Umsig2L8c5ybFNSVUaH7Zb5xgmZ7ae6uOGJnzqTLdgIwpKf4FuHZn+DGIQ99OJhRvlWh9aDe50F6zGf/uQ=="
+)
+
+func parseTestKey(t *testing.T, authorizedKey string) ssh.PublicKey {
+	key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		t.Fatalf("Failed to parse test key: %v", err)
+	}
+	return key
+}
+
+func TestKeyPolicyCheck(t *testing.T) {
+	rsa2048, _ := GenerateSSHKey(RSAKey, 2048)
+	ed25519Key, _ := GenerateSSHKey(ED25519, 0)
+	ecdsa384, _ := GenerateSSHKey(ECDSAP384, 0)
+
+	tests := []struct {
+		name   string
+		policy *KeyPolicy
+		key    ssh.PublicKey
+		rule   string
+	}{
+		{name: "Default RSA 2048", key: rsa2048.PublicKey()},
+		{name: "Default ed25519", key: ed25519Key.PublicKey()},
+		{name: "Default sk-ssh-ed25519", key: parseTestKey(t, skEd25519Key)},
+		{name: "Default sk-ecdsa", key: parseTestKey(t, skECDSAKey)},
+		{name: "Default refuses DSA", key: parseTestKey(t, dsaKey), rule: "allowed_types"},
+		{name: "Default refuses RSA 1024", key: parseTestKey(t, rsa1024Key), rule: "min_bits"},
+		{name: "Policy can not lower RSA minimum", policy: &KeyPolicy{MinBits: map[KeyType]int{RSAKey: 1024}}, key: parseTestKey(t, rsa1024Key), rule: "min_bits"},
+		{name: "RSA under policy minimum", policy: &KeyPolicy{MinBits: map[KeyType]int{RSAKey: 3072}}, key: rsa2048.PublicKey(), rule: "min_bits"},
+		{name: "ECDSA over policy minimum", policy: &KeyPolicy{MinBits: map[KeyType]int{ECDSAP384: 384}}, key: ecdsa384.PublicKey()},
+		{name: "Allowed type", policy: &KeyPolicy{AllowedTypes: []KeyType{ED25519}}, key: ed25519Key.PublicKey()},
+		{name: "Type not allowed", policy: &KeyPolicy{AllowedTypes: []KeyType{ED25519}}, key: rsa2048.PublicKey(), rule: "allowed_types"},
+		{name: "Security key required", policy: &KeyPolicy{RequireSecurityKey: true}, key: ed25519Key.PublicKey(), rule: "require_security_key"},
+		{name: "Security key ed25519", policy: &KeyPolicy{RequireSecurityKey: true}, key: parseTestKey(t, skEd25519Key)},
+		{name: "Security key ecdsa", policy: &KeyPolicy{RequireSecurityKey: true}, key: parseTestKey(t, skECDSAKey)},
+		{name: "User verification needs a security key", policy: &KeyPolicy{RequireUserVerification: true}, key: ed25519Key.PublicKey(), rule: "require_user_verification"},
+		{name: "User verification with security key", policy: &KeyPolicy{RequireUserVerification: true}, key: parseTestKey(t, skECDSAKey)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.key)
+			if tt.rule == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var policyErr *KeyPolicyError
+			if assert.True(t, errors.As(err, &policyErr), "Expected a KeyPolicyError, got %v", err) {
+				assert.Equal(t, tt.rule, policyErr.Rule)
+				assert.Contains(t, err.Error(), "key policy "+tt.rule)
+			}
+		})
+	}
+}
+
+func TestKeyPolicyValidate(t *testing.T) {
+	assert.NoError(t, (*KeyPolicy)(nil).Validate())
+	assert.NoError(t, (&KeyPolicy{AllowedTypes: []KeyType{SKED25519, SKECDSAP256}, RequireSecurityKey: true}).Validate())
+	assert.Error(t, (&KeyPolicy{AllowedTypes: []KeyType{"ssh-dss"}}).Validate())
+	assert.Error(t, (&KeyPolicy{AllowedTypes: []KeyType{ED25519}, RequireSecurityKey: true}).Validate())
+	assert.Error(t, (&KeyPolicy{MinBits: map[KeyType]int{RSAKey: 0}}).Validate())
+	assert.Error(t, (&KeyPolicy{AllowedTypes: []KeyType{ED25519}, RequireUserVerification: true}).Validate())
+}
+
+func TestSignUserKeySecurityKeyOptions(t *testing.T) {
+	caSigner, _ := GenerateSSHKey(ED25519, 0)
+	ed25519Key, _ := GenerateSSHKey(ED25519, 0)
+	// A caller trying to waive the touch requirement
+	noTouch := func(cert *ssh.Certificate) { cert.Extensions[NoTouchRequired] = "" }
+	verify := WithKeyPolicy(&KeyPolicy{RequireUserVerification: true})
+
+	tests := []struct {
+		name           string
+		key            ssh.PublicKey
+		options        []SignOption
+		verifyRequired bool
+	}{
+		{name: "Security key without policy", key: parseTestKey(t, skEd25519Key), options: []SignOption{WithKeyPolicy(nil)}},
+		{name: "Security key with user verification", key: parseTestKey(t, skEd25519Key), options: []SignOption{verify}, verifyRequired: true},
+		{name: "ECDSA security key with user verification", key: parseTestKey(t, skECDSAKey), options: []SignOption{verify, noTouch}, verifyRequired: true},
+		{name: "No touch is never issued", key: parseTestKey(t, skEd25519Key), options: []SignOption{noTouch}},
+		{name: "Plain keys are not marked", key: ed25519Key.PublicKey(), options: []SignOption{verify}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signed, err := SignUserKey(caSigner, tt.key, []string{"alice"}, 30, tt.options...)
+			if !assert.NoError(t, err) {
+				return
+			}
+			parsed, err := ParseCertificate(ssh.MarshalAuthorizedKey(signed))
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.NotContains(t, parsed.Extensions, NoTouchRequired)
+			assert.Contains(t, parsed.Extensions, "permit-pty")
+			_, verifyRequired := parsed.CriticalOptions[VerifyRequired]
+			assert.Equal(t, tt.verifyRequired, verifyRequired)
+		})
+	}
+}
@@ -2,6 +2,9 @@ package cert
 
 import (
 	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"golang.org/x/crypto/ssh"
 	"time"
 )
@@ -11,21 +14,133 @@ type SignRequest struct {
 	PublicKey string `json:"public_key"`
 	// List of valid principals, usernames
 	Principals []string `json:"principals"`
-	// How long the certificate is valid for
+	// How long the certificate is valid for, required unless valid_before is set
 	TTLMinutes int `json:"ttl_minutes"`
+	// Start of the certificate's validity, e.g. a maintenance window.
+	// Defaults to now, less the CA's backdate allowance.
+	ValidAfter *time.Time `json:"valid_after,omitempty"`
+	// End of the certificate's validity, instead of ttl_minutes
+	ValidBefore *time.Time `json:"valid_before,omitempty"`
+}
+
+var (
+	ErrTTLTooLong         = errors.New("requested TTL longer than configured max")
+	ErrInvalidValidity    = errors.New("invalid validity window")
+	ErrValidAfterTooEarly = errors.New("valid_after is earlier than the CA's backdate allowance")
+	ErrValidAfterTooLate  = errors.New("valid_after is further ahead than the CA allows")
+)
+
+// ValidityWindow returns when a certificate for request starts and ends.
+// The window is at most the CA's max TTL long, or its max host TTL for host
+// CAs, and starts no further ahead of now than the CA's max future start.
+func (c CommonCa) ValidityWindow(request SignRequest, now time.Time) (time.Time, time.Time, error) {
+	maxTTL := time.Duration(c.MaxTTLMinutes) * time.Minute
+	if c.IsHostCA() {
+		maxTTL = time.Duration(c.MaxHostTTL()) * time.Minute
+	}
+	if request.ValidBefore != nil && request.TTLMinutes != 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: ttl_minutes and valid_before can not both be set", ErrInvalidValidity)
+	}
+	if request.ValidBefore == nil && request.TTLMinutes <= 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: ttl_minutes must be positive", ErrInvalidValidity)
+	}
+
+	validAfter := now
+	if request.ValidAfter != nil {
+		validAfter = *request.ValidAfter
+		if validAfter.Before(now.Add(-c.Backdate())) {
+			return time.Time{}, time.Time{}, ErrValidAfterTooEarly
+		}
+		if validAfter.After(now.Add(c.MaxFutureStart())) {
+			return time.Time{}, time.Time{}, ErrValidAfterTooLate
+		}
+	}
+	validBefore := validAfter.Add(time.Duration(request.TTLMinutes) * time.Minute)
+	if request.ValidBefore != nil {
+		validBefore = *request.ValidBefore
+		if !validBefore.After(validAfter) || !validBefore.After(now) {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: valid_before must be after valid_after and now", ErrInvalidValidity)
+		}
+	}
+	if validBefore.Sub(validAfter) > maxTTL {
+		return time.Time{}, time.Time{}, ErrTTLTooLong
+	}
+	// Backdating is on top of the TTL, it only covers clock skew
+	if request.ValidAfter == nil {
+		validAfter = validAfter.Add(-c.Backdate())
+	}
+	return validAfter, validBefore, nil
 }
 
 type SignResponse struct {
 	// Signed certificate by the CA
 	SignedKey string `json:"signed_key"`
+	// Serial number of the certificate, used to revoke it
+	Serial uint64 `json:"serial"`
+}
+
+// Security key certificate options. OpenSSH requires a touch of the security
+// key for each login unless the certificate has NoTouchRequired, and a PIN or
+// biometric check as well when it has VerifyRequired.
+const (
+	NoTouchRequired = "no-touch-required"
+	VerifyRequired  = "verify-required"
+)
+
+// SignOption adjusts a certificate before it is signed
+type SignOption func(*ssh.Certificate)
+
+// WithKeyPolicy applies a CA's key policy to a user certificate, adding the
+// verify-required critical option for security keys when the policy
+// requires user verification
+func WithKeyPolicy(policy *KeyPolicy) SignOption {
+	return func(cert *ssh.Certificate) {
+		if policy == nil || !policy.RequireUserVerification || !KeyType(cert.Key.Type()).IsSecurityKey() {
+			return
+		}
+		if cert.CriticalOptions == nil {
+			cert.CriticalOptions = map[string]string{}
+		}
+		cert.CriticalOptions[VerifyRequired] = ""
+	}
+}
+
+// WithValidity sets when the certificate starts and ends, instead of from
+// now for the TTL
+func WithValidity(validAfter, validBefore time.Time) SignOption {
+	return func(cert *ssh.Certificate) {
+		cert.ValidAfter = uint64(validAfter.Unix())
+		cert.ValidBefore = uint64(validBefore.Unix())
+	}
+}
+
+// WithBackdate starts the certificate earlier, for hosts whose clocks are
+// behind
+func WithBackdate(backdate time.Duration) SignOption {
+	return func(cert *ssh.Certificate) {
+		cert.ValidAfter -= uint64(backdate / time.Second)
+	}
+}
+
+// WithKeyID sets the certificate's key ID, which sshd logs for each login
+func WithKeyID(keyID string) SignOption {
+	return func(cert *ssh.Certificate) {
+		cert.KeyId = keyID
+	}
 }
 
 // SignUserKey signs a user's public key using the CA private key.
-// It returns a signed SSH certificate.
-func SignUserKey(caSigner ssh.Signer, userPublicKey ssh.PublicKey, principals []string, ttlMinutes int) (*ssh.Certificate, error) {
+// It returns a signed SSH certificate. Security keys always need a touch,
+// no-touch-required is never issued.
+func SignUserKey(caSigner ssh.Signer, userPublicKey ssh.PublicKey, principals []string, ttlMinutes int, options ...SignOption) (*ssh.Certificate, error) {
+	serial, err := NewSerial()
+	if err != nil {
+		return nil, err
+	}
 	// Create a new certificate with the user's public key
 	cert := &ssh.Certificate{
 		Key:             userPublicKey,                                                          // The user's public key
+		Serial:          serial,                                                                 // Unique serial so the certificate can be revoked
 		ValidPrincipals: principals,                                                             // Set the principal (can be a username)
 		ValidAfter:      uint64(time.Now().Unix()),                                              // Start time (now)
 		ValidBefore:     uint64(time.Now().Add(time.Duration(ttlMinutes) * time.Minute).Unix()), // End time (1-hour TTL)
@@ -37,12 +152,81 @@ func SignUserKey(caSigner ssh.Signer, userPublicKey ssh.PublicKey, principals []
 			},
 		},
 	}
+	for _, option := range options {
+		option(cert)
+	}
+	delete(cert.Extensions, NoTouchRequired)
 
 	// Sign the certificate using the CA's private key
-	err := cert.SignCert(rand.Reader, caSigner)
+	err = signCert(cert, caSigner)
 	if err != nil {
 		return nil, err
 	}
 
 	return cert, nil
 }
+
+// NewSerial returns a random, non zero certificate serial number
+func NewSerial() (uint64, error) {
+	var b [8]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, err
+		}
+		if serial := binary.BigEndian.Uint64(b[:]); serial != 0 {
+			return serial, nil
+		}
+	}
+}
+
+// SignHostKey signs a host's public key using the CA private key, for the
+// host names in principals. It returns a signed SSH host certificate.
+func SignHostKey(caSigner ssh.Signer, hostPublicKey ssh.PublicKey, principals []string, ttlMinutes int, options ...SignOption) (*ssh.Certificate, error) {
+	serial, err := NewSerial()
+	if err != nil {
+		return nil, err
+	}
+	cert := &ssh.Certificate{
+		Key:             hostPublicKey,
+		Serial:          serial,
+		ValidPrincipals: principals,
+		ValidAfter:      uint64(time.Now().Unix()),
+		ValidBefore:     uint64(time.Now().Add(time.Duration(ttlMinutes) * time.Minute).Unix()),
+		CertType:        ssh.HostCert,
+	}
+	for _, option := range options {
+		option(cert)
+	}
+	if err := signCert(cert, caSigner); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// ErrSHA1Signature is returned when an RSA CA would sign with ssh-rsa (SHA-1)
+var ErrSHA1Signature = errors.New("RSA CAs must sign with rsa-sha2-256 or rsa-sha2-512")
+
+// signCert signs cert with the CA's signature algorithm. RSA signers without
+// one sign with rsa-sha2-512, and are never allowed to fall back to SHA-1.
+func signCert(cert *ssh.Certificate, caSigner ssh.Signer) error {
+	if caSigner.PublicKey().Type() == ssh.KeyAlgoRSA {
+		restricted, ok := caSigner.(ssh.MultiAlgorithmSigner)
+		if !ok {
+			algorithmSigner, ok := caSigner.(ssh.AlgorithmSigner)
+			if !ok {
+				return ErrSHA1Signature
+			}
+			var err error
+			restricted, err = ssh.NewSignerWithAlgorithms(algorithmSigner, []string{ssh.KeyAlgoRSASHA512})
+			if err != nil {
+				return err
+			}
+		}
+		// SignCert signs with the first algorithm
+		if restricted.Algorithms()[0] == ssh.KeyAlgoRSA {
+			return ErrSHA1Signature
+		}
+		caSigner = restricted
+	}
+	return cert.SignCert(rand.Reader, caSigner)
+}
@@ -0,0 +1,73 @@
+package cert
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidityWindow(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	ca := CommonCa{MaxTTLMinutes: 120, BackdateSeconds: 30}
+
+	tests := []struct {
+		name        string
+		ca          CommonCa
+		request     SignRequest
+		validAfter  time.Time
+		validBefore time.Time
+		err         error
+	}{
+		{name: "TTL from now, backdated", ca: ca, request: SignRequest{TTLMinutes: 60}, validAfter: now.Add(-30 * time.Second), validBefore: now.Add(time.Hour)},
+		{name: "TTL over max", ca: ca, request: SignRequest{TTLMinutes: 121}, err: ErrTTLTooLong},
+		{name: "Negative TTL", ca: ca, request: SignRequest{TTLMinutes: -5}, err: ErrInvalidValidity},
+		{name: "No TTL or valid before", ca: ca, request: SignRequest{}, err: ErrInvalidValidity},
+		{name: "Negative TTL with scheduled start", ca: ca, request: SignRequest{ValidAfter: at(time.Hour), TTLMinutes: -30}, err: ErrInvalidValidity},
+		{name: "Scheduled window", ca: ca, request: SignRequest{ValidAfter: at(24 * time.Hour), ValidBefore: at(26 * time.Hour)}, validAfter: now.Add(24 * time.Hour), validBefore: now.Add(26 * time.Hour)},
+		{name: "Scheduled start with TTL", ca: ca, request: SignRequest{ValidAfter: at(time.Hour), TTLMinutes: 30}, validAfter: now.Add(time.Hour), validBefore: now.Add(90 * time.Minute)},
+		{name: "Valid before from now", ca: ca, request: SignRequest{ValidBefore: at(time.Hour)}, validAfter: now.Add(-30 * time.Second), validBefore: now.Add(time.Hour)},
+		{name: "Scheduled window over max", ca: ca, request: SignRequest{ValidAfter: at(time.Hour), ValidBefore: at(4 * time.Hour)}, err: ErrTTLTooLong},
+		{name: "Start within backdate", ca: ca, request: SignRequest{ValidAfter: at(-20 * time.Second), TTLMinutes: 60}, validAfter: now.Add(-20 * time.Second), validBefore: now.Add(time.Hour - 20*time.Second)},
+		{name: "Start before backdate", ca: ca, request: SignRequest{ValidAfter: at(-time.Minute), TTLMinutes: 60}, err: ErrValidAfterTooEarly},
+		{name: "Start past default future limit", ca: ca, request: SignRequest{ValidAfter: at(8 * 24 * time.Hour), TTLMinutes: 60}, err: ErrValidAfterTooLate},
+		{name: "Start past CA future limit", ca: CommonCa{MaxTTLMinutes: 120, MaxFutureStartMinutes: 60}, request: SignRequest{ValidAfter: at(2 * time.Hour), TTLMinutes: 60}, err: ErrValidAfterTooLate},
+		{name: "TTL and valid before", ca: ca, request: SignRequest{ValidBefore: at(time.Hour), TTLMinutes: 60}, err: ErrInvalidValidity},
+		{name: "Valid before ahead of valid after", ca: ca, request: SignRequest{ValidAfter: at(2 * time.Hour), ValidBefore: at(time.Hour)}, err: ErrInvalidValidity},
+		{name: "Valid before in the past", ca: ca, request: SignRequest{ValidBefore: at(-time.Second)}, err: ErrInvalidValidity},
+		{name: "Host CA max host TTL", ca: CommonCa{MaxTTLMinutes: 60, MaxHostTTLMinutes: 600, Kind: HostCA}, request: SignRequest{TTLMinutes: 600}, validAfter: now, validBefore: now.Add(10 * time.Hour)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validAfter, validBefore, err := tt.ca.ValidityWindow(tt.request, now)
+			if tt.err != nil {
+				assert.True(t, errors.Is(err, tt.err), "Expected %v, got %v", tt.err, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.validAfter, validAfter)
+			assert.Equal(t, tt.validBefore, validBefore)
+		})
+	}
+}
+
+func TestSignUserKeyValidity(t *testing.T) {
+	caSigner, _ := GenerateSSHKey(ED25519, 0)
+	userSigner, _ := GenerateSSHKey(ED25519, 0)
+	validAfter := time.Now().Add(24 * time.Hour).Truncate(time.Second)
+	validBefore := validAfter.Add(2 * time.Hour)
+
+	signed, err := SignUserKey(caSigner, userSigner.PublicKey(), []string{"alice"}, 60, WithValidity(validAfter, validBefore))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(validAfter.Unix()), signed.ValidAfter)
+	assert.Equal(t, uint64(validBefore.Unix()), signed.ValidBefore)
+
+	signed, err = SignHostKey(caSigner, userSigner.PublicKey(), []string{"web1"}, 60, WithBackdate(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(61*60), signed.ValidBefore-signed.ValidAfter)
+}
@@ -1,34 +1,35 @@
 package cert
 
 import (
-	"bytes"
 	"crypto/rand"
 	"crypto/rsa"
-	"golang.org/x/crypto/ssh"
 	"io"
 	"log"
 	"net"
 	"os"
 	"testing"
+
+	"github.com/lukegriffith/SSHTrust/pkg/verify"
+	"golang.org/x/crypto/ssh"
 )
 
-// Helper function to start a lightweight SSH server in a separate goroutine
-func startSSHServer(caPublicKey ssh.PublicKey) (net.Listener, error) {
+// Helper function to start a lightweight SSH server in a separate goroutine.
+// options adjust the server config before it starts.
+func startSSHServer(caPublicKey ssh.PublicKey, options ...func(*ssh.ServerConfig)) (net.Listener, error) {
 	// Configure the SSH server to trust the provided CA public key
+	verifier := &verify.Verifier{
+		Authorities: verify.AuthorityList{{Name: "test", Key: caPublicKey}},
+	}
 	config := &ssh.ServerConfig{
 		PublicKeyCallback: func(conn ssh.ConnMetadata, pubKey ssh.PublicKey) (*ssh.Permissions, error) {
-			cert, ok := pubKey.(*ssh.Certificate)
-			if !ok {
-				log.Printf("Received public key that is not a certificate.")
-				return nil, ssh.ErrNoAuth
-			}
-			// Compare the SignatureKey with the trusted CA public key using bytes.Equal
-			if bytes.Equal(ssh.MarshalAuthorizedKey(cert.SignatureKey), ssh.MarshalAuthorizedKey(caPublicKey)) {
-				log.Printf("Successful certificate validation for user: %s", conn.User())
-				return nil, nil
+			// Checks the signing CA, validity and principals as sshd would
+			cert, _, err := verifier.Verify(pubKey, conn.User())
+			if err != nil {
+				log.Printf("Certificate rejected for user %s: %v", conn.User(), err)
+				return nil, err
 			}
-			log.Printf("Certificate signed by unknown authority for user: %s", conn.User())
-			return nil, ssh.ErrNoAuth
+			log.Printf("Successful certificate validation for user: %s", conn.User())
+			return &cert.Permissions, nil
 		},
 	}
 
@@ -46,6 +47,9 @@ func startSSHServer(caPublicKey ssh.PublicKey) (net.Listener, error) {
 
 	// Add the generated private key as the host key for the server
 	config.AddHostKey(signer)
+	for _, option := range options {
+		option(config)
+	}
 
 	// Start listening for SSH connections on a random port
 	listener, err := net.Listen("tcp", "127.0.0.1:0")
@@ -197,6 +201,87 @@ func TestGenerateSSHCertificateEndToEnd(t *testing.T) {
 	}
 }
 
+// TestRSACertificateWithoutSHA1EndToEnd tests RSA CA certificates are
+// accepted by a server that refuses SHA-1, as OpenSSH does by default
+func TestRSACertificateWithoutSHA1EndToEnd(t *testing.T) {
+	refuseSHA1 := func(config *ssh.ServerConfig) {
+		// Client signatures, CA signatures are refused by the verifier
+		config.PublicKeyAuthAlgorithms = []string{ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256, ssh.KeyAlgoED25519}
+	}
+	login := func(caSigner ssh.Signer, signedCert *ssh.Certificate, userKey ssh.Signer) error {
+		listener, err := startSSHServer(caSigner.PublicKey(), refuseSHA1)
+		if err != nil {
+			t.Fatalf("Failed to start SSH server: %v", err)
+		}
+		defer listener.Close()
+		certSigner, err := ssh.NewCertSigner(signedCert, userKey)
+		if err != nil {
+			t.Fatalf("Failed to create certificate signer: %v", err)
+		}
+		client, err := ssh.Dial("tcp", listener.Addr().String(), &ssh.ClientConfig{
+			User:            "testuser",
+			Auth:            []ssh.AuthMethod{ssh.PublicKeys(certSigner)},
+			HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		})
+		if err != nil {
+			return err
+		}
+		return client.Close()
+	}
+
+	userKey, err := GenerateSSHKey(RSAKey, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate user keypair: %v", err)
+	}
+	for _, algorithm := range SignatureAlgorithms(RSAKey) {
+		key, err := GenerateSSHKey(RSAKey, 2048)
+		if err != nil {
+			t.Fatalf("Failed to generate CA keypair: %v", err)
+		}
+		caSigner, err := NewCASigner(key, algorithm)
+		if err != nil {
+			t.Fatalf("Failed to restrict CA to %s: %v", algorithm, err)
+		}
+		signedCert, err := SignUserKey(caSigner, userKey.PublicKey(), []string{"testuser"}, 60)
+		if err != nil {
+			t.Fatalf("Failed to sign user's public key: %v", err)
+		}
+		if signedCert.Signature.Format != algorithm {
+			t.Fatalf("Expected a %s signature, got %s", algorithm, signedCert.Signature.Format)
+		}
+		if err := login(caSigner, signedCert, userKey); err != nil {
+			t.Fatalf("Certificate signed with %s was refused: %v", algorithm, err)
+		}
+	}
+
+	// CAs never sign with SHA-1, even when restricted to it
+	key, err := GenerateSSHKey(RSAKey, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate CA keypair: %v", err)
+	}
+	sha1Signer, err := NewCASigner(key, ssh.KeyAlgoRSA)
+	if err != nil {
+		t.Fatalf("Failed to restrict CA to ssh-rsa: %v", err)
+	}
+	if _, err := SignUserKey(sha1Signer, userKey.PublicKey(), []string{"testuser"}, 60); err != ErrSHA1Signature {
+		t.Fatalf("Expected ErrSHA1Signature, got %v", err)
+	}
+
+	// The server does refuse SHA-1 certificates signed elsewhere
+	sha1Cert := &ssh.Certificate{
+		Key:             userKey.PublicKey(),
+		CertType:        ssh.UserCert,
+		ValidPrincipals: []string{"testuser"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	if err := sha1Cert.SignCert(rand.Reader, sha1Signer); err != nil {
+		t.Fatalf("Failed to sign SHA-1 certificate: %v", err)
+	}
+	if err := login(sha1Signer, sha1Cert, userKey); err == nil {
+		t.Fatal("Expected the server to refuse a SHA-1 certificate")
+	}
+}
+
 // signedCertSigner is a custom signer that includes both the private key and the signed certificate
 type signedCertSigner struct {
 	signer ssh.Signer
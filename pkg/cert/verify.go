@@ -0,0 +1,27 @@
+package cert
+
+type RevokeRequest struct {
+	// Serial number of the certificate to revoke
+	Serial uint64 `json:"serial"`
+}
+
+type VerifyRequest struct {
+	// Certificate in authorized key format, as written to *-cert.pub
+	Certificate string `json:"certificate"`
+	// Principal the certificate is being used to log in as
+	Principal string `json:"principal"`
+	// Critical options the caller enforces itself, such as verify-required.
+	// Certificates with any other critical option are rejected.
+	SupportedCriticalOptions []string `json:"supported_critical_options,omitempty"`
+}
+
+type VerifyResponse struct {
+	// Whether the certificate is accepted
+	Valid bool `json:"valid"`
+	// Why the certificate was rejected
+	Reason string `json:"reason,omitempty"`
+	// Serial number of the certificate
+	Serial uint64 `json:"serial"`
+	// Key ID of the certificate
+	KeyID string `json:"key_id"`
+}
@@ -0,0 +1,147 @@
+package certStore
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+var (
+	ErrJoinTokenNotFound = errors.New("join token not found")
+	ErrHostNotFound      = errors.New("host not found")
+	ErrHostKeyNotFound   = errors.New("host key not found")
+	ErrHostExists        = errors.New("host already enrolled")
+)
+
+// JoinToken is an issued join token, stored without its secret
+type JoinToken struct {
+	ID         string
+	CA         string
+	Hostnames  []string
+	TTLMinutes int
+	ExpiresAt  time.Time
+	// Whether hosts already in the registry may enroll again with it
+	Reenroll bool
+}
+
+type HostStore interface {
+	// AddJoinToken stores token, looked up by the hash of secret
+	AddJoinToken(secret string, token JoinToken) error
+	GetJoinToken(secret string) (*JoinToken, error)
+	// RedeemJoinToken removes the token, failing if it was already used
+	RedeemJoinToken(secret string) error
+	// RecordHost adds the host to the registry. A host with the same name is
+	// only replaced with replace, otherwise ErrHostExists is returned.
+	RecordHost(host cert.Host, replace bool) error
+	// UpdateHostKey replaces the host's key with the same fingerprint, after
+	// its certificate is renewed
+	UpdateHostKey(name string, key cert.HostKey) error
+	GetHost(name string) (*cert.Host, error)
+	ListHosts() ([]cert.Host, error)
+}
+
+// JoinTokenID derives the ID recorded against hosts from a token's secret
+func JoinTokenID(secret string) string {
+	return tokenHash(secret)[:12]
+}
+
+func tokenHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+type InMemoryHostStore struct {
+	sync.RWMutex
+	tokens map[string]JoinToken
+	hosts  map[string]cert.Host
+}
+
+func NewInMemoryHostStore() *InMemoryHostStore {
+	return &InMemoryHostStore{
+		tokens: make(map[string]JoinToken),
+		hosts:  make(map[string]cert.Host),
+	}
+}
+
+func (store *InMemoryHostStore) AddJoinToken(secret string, token JoinToken) error {
+	store.Lock()
+	defer store.Unlock()
+	store.tokens[tokenHash(secret)] = token
+	return nil
+}
+
+func (store *InMemoryHostStore) GetJoinToken(secret string) (*JoinToken, error) {
+	store.RLock()
+	defer store.RUnlock()
+	token, exists := store.tokens[tokenHash(secret)]
+	if !exists {
+		return nil, ErrJoinTokenNotFound
+	}
+	return &token, nil
+}
+
+func (store *InMemoryHostStore) RedeemJoinToken(secret string) error {
+	store.Lock()
+	defer store.Unlock()
+	hash := tokenHash(secret)
+	if _, exists := store.tokens[hash]; !exists {
+		return ErrJoinTokenNotFound
+	}
+	delete(store.tokens, hash)
+	return nil
+}
+
+func (store *InMemoryHostStore) RecordHost(host cert.Host, replace bool) error {
+	store.Lock()
+	defer store.Unlock()
+	if _, exists := store.hosts[host.Name]; exists && !replace {
+		return ErrHostExists
+	}
+	store.hosts[host.Name] = host
+	return nil
+}
+
+func (store *InMemoryHostStore) UpdateHostKey(name string, key cert.HostKey) error {
+	store.Lock()
+	defer store.Unlock()
+	host, exists := store.hosts[name]
+	if !exists {
+		return ErrHostNotFound
+	}
+	keys := append([]cert.HostKey{}, host.Keys...)
+	for i := range keys {
+		if keys[i].Fingerprint == key.Fingerprint {
+			keys[i] = key
+			host.Keys = keys
+			store.hosts[name] = host
+			return nil
+		}
+	}
+	return ErrHostKeyNotFound
+}
+
+func (store *InMemoryHostStore) GetHost(name string) (*cert.Host, error) {
+	store.RLock()
+	defer store.RUnlock()
+	host, exists := store.hosts[name]
+	if !exists {
+		return nil, ErrHostNotFound
+	}
+	return &host, nil
+}
+
+func (store *InMemoryHostStore) ListHosts() ([]cert.Host, error) {
+	store.RLock()
+	defer store.RUnlock()
+	hosts := []cert.Host{}
+	for _, host := range store.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Slice(hosts, func(i, j int) bool { return hosts[i].Name < hosts[j].Name })
+	return hosts, nil
+}
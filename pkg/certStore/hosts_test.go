@@ -0,0 +1,63 @@
+package certStore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostStoreJoinTokens(t *testing.T) {
+	store := NewInMemoryHostStore()
+	token := JoinToken{ID: JoinTokenID("secret"), CA: "hostca", TTLMinutes: 60, ExpiresAt: time.Now().Add(time.Hour)}
+	assert.NoError(t, store.AddJoinToken("secret", token))
+
+	found, err := store.GetJoinToken("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, token, *found)
+
+	_, err = store.GetJoinToken("other")
+	assert.ErrorIs(t, err, ErrJoinTokenNotFound)
+
+	// Tokens can only be redeemed once
+	assert.NoError(t, store.RedeemJoinToken("secret"))
+	assert.ErrorIs(t, store.RedeemJoinToken("secret"), ErrJoinTokenNotFound)
+	_, err = store.GetJoinToken("secret")
+	assert.ErrorIs(t, err, ErrJoinTokenNotFound)
+}
+
+func TestHostStoreRegistry(t *testing.T) {
+	store := NewInMemoryHostStore()
+	assert.NoError(t, store.RecordHost(cert.Host{Name: "web2.example.com", CA: "hostca"}, false))
+	assert.NoError(t, store.RecordHost(cert.Host{Name: "web1.example.com", CA: "hostca"}, false))
+	// Enrolling again only replaces the record when asked to
+	assert.ErrorIs(t, store.RecordHost(cert.Host{Name: "web1.example.com", CA: "otherca"}, false), ErrHostExists)
+	assert.NoError(t, store.RecordHost(cert.Host{Name: "web1.example.com", CA: "newca"}, true))
+
+	hosts, err := store.ListHosts()
+	assert.NoError(t, err)
+	if assert.Len(t, hosts, 2) {
+		assert.Equal(t, "web1.example.com", hosts[0].Name)
+		assert.Equal(t, "newca", hosts[0].CA)
+	}
+
+	_, err = store.GetHost("db1.example.com")
+	assert.ErrorIs(t, err, ErrHostNotFound)
+}
+
+func TestHostStoreUpdateHostKey(t *testing.T) {
+	store := NewInMemoryHostStore()
+	assert.NoError(t, store.RecordHost(cert.Host{Name: "web1.example.com", Keys: []cert.HostKey{
+		{Fingerprint: "SHA256:a", Serial: 1},
+		{Fingerprint: "SHA256:b", Serial: 2},
+	}}, false))
+
+	assert.NoError(t, store.UpdateHostKey("web1.example.com", cert.HostKey{Fingerprint: "SHA256:b", Serial: 3}))
+	host, err := store.GetHost("web1.example.com")
+	assert.NoError(t, err)
+	assert.Equal(t, []cert.HostKey{{Fingerprint: "SHA256:a", Serial: 1}, {Fingerprint: "SHA256:b", Serial: 3}}, host.Keys)
+
+	assert.ErrorIs(t, store.UpdateHostKey("web1.example.com", cert.HostKey{Fingerprint: "SHA256:c"}), ErrHostKeyNotFound)
+	assert.ErrorIs(t, store.UpdateHostKey("db1.example.com", cert.HostKey{Fingerprint: "SHA256:a"}), ErrHostNotFound)
+}
@@ -45,16 +45,36 @@ func (store *InMemortCaStore) CreateCA(CAReq cert.CaRequest) (*cert.CaResponse,
 		return nil, fmt.Errorf("invalid CA request: %w", err)
 	}
 	store.RLock()
-	if _, exists := store.cas[CAReq.Name]; exists {
+	_, exists := store.cas[CAReq.Name]
+	store.RUnlock()
+	if exists {
 		return nil, errors.New("CA already exists")
 	}
-	store.RUnlock()
-	signer, err := cert.GenerateSSHKey(CAReq.Type, CAReq.Bits)
+	algorithm := CAReq.SignatureAlgorithm
+	if algorithm == "" {
+		algorithm = cert.SignatureAlgorithms(CAReq.Type)[0]
+	}
+	signer, err := generateCASigner(CAReq.Type, CAReq.Bits, algorithm)
 	if err != nil {
-		return nil, errors.New("failed to generate CA keypair")
+		return nil, err
 	}
 
-	c := cert.NewCA(CAReq.Name, signer, CAReq.ValidPrincipals, CAReq.Bits, CAReq.MaxTTLMinutes)
+	bits := CAReq.Bits
+	if CAReq.Type.IsECDSA() {
+		bits = CAReq.Type.CurveBits()
+	}
+	c := cert.NewCA(CAReq.Name, signer, CAReq.ValidPrincipals, bits, CAReq.MaxTTLMinutes)
+	c.Tags = CAReq.Tags
+	c.Kind = CAReq.Kind
+	c.MaxHostTTLMinutes = CAReq.MaxHostTTLMinutes
+	c.HostPatterns = CAReq.HostPatterns
+	c.Accounts = CAReq.Accounts
+	c.SignatureAlgorithm = algorithm
+	c.KeyPolicy = CAReq.KeyPolicy
+	c.BackdateSeconds = CAReq.BackdateSeconds
+	c.MaxFutureStartMinutes = CAReq.MaxFutureStartMinutes
+	c.ApprovalRules = CAReq.ApprovalRules
+	c.BreakGlassResponders = CAReq.BreakGlassResponders
 	store.Lock()
 	store.cas[CAReq.Name] = c
 	store.Unlock()
@@ -71,3 +91,41 @@ func (store *InMemortCaStore) ListCAs() ([]*cert.CaResponse, error) {
 	}
 	return keys, nil
 }
+
+func (store *InMemortCaStore) RotateCA(ID string) (*cert.CaResponse, error) {
+	store.Lock()
+	defer store.Unlock()
+	c, exists := store.cas[ID]
+	if !exists {
+		return nil, errors.New("unable to find CA by ID")
+	}
+	signer, err := generateCASigner(cert.KeyType(c.Signer.PublicKey().Type()), c.Bits, c.SignatureAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	c.Retiring = append(append([]ssh.PublicKey{}, c.Retiring...), c.Signer.PublicKey())
+	c.Signer = signer
+	store.cas[ID] = c
+	return c.CreateResponse(), nil
+}
+
+func (store *InMemortCaStore) CompleteRotation(ID string) (*cert.CaResponse, error) {
+	store.Lock()
+	defer store.Unlock()
+	c, exists := store.cas[ID]
+	if !exists {
+		return nil, errors.New("unable to find CA by ID")
+	}
+	c.Retiring = nil
+	store.cas[ID] = c
+	return c.CreateResponse(), nil
+}
+
+// generateCASigner generates a CA key restricted to signing with algorithm
+func generateCASigner(keyType cert.KeyType, bits int, algorithm string) (ssh.Signer, error) {
+	signer, err := cert.GenerateSSHKey(keyType, bits)
+	if err != nil {
+		return nil, errors.New("failed to generate CA keypair")
+	}
+	return cert.NewCASigner(signer, algorithm)
+}
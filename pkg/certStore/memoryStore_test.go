@@ -126,3 +126,83 @@ func TestListCAs(t *testing.T) {
 	assert.True(t, ca1Exists, "Expected test-ca1 to be present")
 	assert.True(t, ca2Exists, "Expected test-ca2 to be present")
 }
+
+// Test rotating a CA keeps the old key as retiring until completed
+func TestRotateCA(t *testing.T) {
+	store := NewInMemoryCaStore()
+	mockRequest := cert.CaRequest{CommonCa: cert.CommonCa{Name: "test-ca", Type: "ssh-ed25519", ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 60, Tags: []string{"prod"}}}
+	original, err := store.CreateCA(mockRequest)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prod"}, original.Tags)
+
+	rotated, err := store.RotateCA("test-ca")
+	assert.NoError(t, err)
+	assert.NotEqual(t, original.PublicKey, rotated.PublicKey, "Expected a new key")
+	assert.Equal(t, []string{original.PublicKey}, rotated.RetiringPublicKeys)
+
+	keys, err := rotated.TrustedKeys()
+	assert.NoError(t, err)
+	assert.Len(t, keys, 2)
+
+	completed, err := store.CompleteRotation("test-ca")
+	assert.NoError(t, err)
+	assert.Empty(t, completed.RetiringPublicKeys)
+
+	_, err = store.RotateCA("missing")
+	assert.Error(t, err)
+}
+
+func TestCreateECDSACA(t *testing.T) {
+	store := NewInMemoryCaStore()
+	for _, keyType := range []cert.KeyType{cert.ECDSAP256, cert.ECDSAP384, cert.ECDSAP521} {
+		name := string(keyType)
+		ca, err := store.CreateCA(cert.CaRequest{CommonCa: cert.CommonCa{Name: name, Type: keyType, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 60}})
+		assert.NoError(t, err)
+		assert.Equal(t, keyType, ca.Type)
+		assert.Equal(t, keyType.CurveBits(), ca.Bits, "Expected the curve size as the key length")
+
+		// Rotation keeps the curve
+		rotated, err := store.RotateCA(name)
+		assert.NoError(t, err)
+		assert.Equal(t, keyType, rotated.Type)
+	}
+}
+
+func TestCreateRSACASignatureAlgorithm(t *testing.T) {
+	store := NewInMemoryCaStore()
+	ca, err := store.CreateCA(cert.CaRequest{CommonCa: cert.CommonCa{Name: "default", Type: cert.RSAKey, Bits: 2048, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 60}})
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-sha2-512", ca.SignatureAlgorithm, "Expected the strongest algorithm by default")
+
+	ca, err = store.CreateCA(cert.CaRequest{CommonCa: cert.CommonCa{Name: "sha256", Type: cert.RSAKey, Bits: 2048, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 60, SignatureAlgorithm: "rsa-sha2-256"}})
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-sha2-256", ca.SignatureAlgorithm)
+
+	// Rotation keeps the algorithm
+	rotated, err := store.RotateCA("sha256")
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-sha2-256", rotated.SignatureAlgorithm)
+	signer, err := store.GetSignerByID("sha256")
+	assert.NoError(t, err)
+	userSigner, _ := cert.GenerateSSHKey(cert.ED25519, 0)
+	signed, err := cert.SignUserKey(signer, userSigner.PublicKey(), []string{"testuser"}, 30)
+	assert.NoError(t, err)
+	assert.Equal(t, "rsa-sha2-256", signed.Signature.Format)
+}
+
+func TestCreateCABreakGlassResponders(t *testing.T) {
+	store := NewInMemoryCaStore()
+	ca, err := store.CreateCA(cert.CaRequest{CommonCa: cert.CommonCa{Name: "prod-ca", Type: cert.ED25519, ValidPrincipals: []string{"root"}, MaxTTLMinutes: 60, BreakGlassResponders: []string{"alice"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, ca.BreakGlassResponders)
+
+	stored, err := store.GetCAByID("prod-ca")
+	assert.NoError(t, err)
+	assert.True(t, stored.CanBreakGlass("alice"))
+	assert.False(t, stored.CanBreakGlass("mallory"))
+
+	// Rotation keeps the responders
+	rotated, err := store.RotateCA("prod-ca")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, rotated.BreakGlassResponders)
+}
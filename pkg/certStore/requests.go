@@ -0,0 +1,84 @@
+package certStore
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+var ErrRequestNotFound = errors.New("signing request not found")
+
+type SigningRequestStore interface {
+	AddRequest(request cert.SigningRequest) error
+	GetRequest(id string) (*cert.SigningRequest, error)
+	// ListRequests lists requests oldest first, only those with status
+	// unless it is empty
+	ListRequests(status cert.SigningRequestStatus) ([]cert.SigningRequest, error)
+	// UpdateRequest applies update to the request and stores the result,
+	// unless update fails. Updates to the same request do not interleave.
+	UpdateRequest(id string, update func(*cert.SigningRequest) error) (*cert.SigningRequest, error)
+}
+
+type InMemorySigningRequestStore struct {
+	sync.Mutex
+	requests map[string]cert.SigningRequest
+}
+
+func NewInMemorySigningRequestStore() *InMemorySigningRequestStore {
+	return &InMemorySigningRequestStore{
+		requests: make(map[string]cert.SigningRequest),
+	}
+}
+
+func (store *InMemorySigningRequestStore) AddRequest(request cert.SigningRequest) error {
+	store.Lock()
+	defer store.Unlock()
+	store.requests[request.ID] = request
+	return nil
+}
+
+func (store *InMemorySigningRequestStore) GetRequest(id string) (*cert.SigningRequest, error) {
+	store.Lock()
+	defer store.Unlock()
+	request, exists := store.requests[id]
+	if !exists {
+		return nil, ErrRequestNotFound
+	}
+	return &request, nil
+}
+
+func (store *InMemorySigningRequestStore) ListRequests(status cert.SigningRequestStatus) ([]cert.SigningRequest, error) {
+	store.Lock()
+	defer store.Unlock()
+	requests := []cert.SigningRequest{}
+	for _, request := range store.requests {
+		if status == "" || request.Status == status {
+			requests = append(requests, request)
+		}
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		if requests[i].CreatedAt.Equal(requests[j].CreatedAt) {
+			return requests[i].ID < requests[j].ID
+		}
+		return requests[i].CreatedAt.Before(requests[j].CreatedAt)
+	})
+	return requests, nil
+}
+
+func (store *InMemorySigningRequestStore) UpdateRequest(id string, update func(*cert.SigningRequest) error) (*cert.SigningRequest, error) {
+	store.Lock()
+	defer store.Unlock()
+	request, exists := store.requests[id]
+	if !exists {
+		return nil, ErrRequestNotFound
+	}
+	// Copy the slices so a failed update leaves the stored request alone
+	request.Approvals = append([]cert.Review{}, request.Approvals...)
+	if err := update(&request); err != nil {
+		return nil, err
+	}
+	store.requests[id] = request
+	return &request, nil
+}
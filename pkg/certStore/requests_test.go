@@ -0,0 +1,50 @@
+package certStore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSigningRequestStore(t *testing.T) {
+	store := NewInMemorySigningRequestStore()
+	now := time.Now()
+	assert.NoError(t, store.AddRequest(cert.SigningRequest{ID: "b", Status: cert.RequestPending, CreatedAt: now.Add(time.Minute)}))
+	assert.NoError(t, store.AddRequest(cert.SigningRequest{ID: "a", Status: cert.RequestApproved, CreatedAt: now}))
+
+	requests, err := store.ListRequests("")
+	assert.NoError(t, err)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "a", requests[0].ID, "Expected oldest first")
+	}
+	requests, err = store.ListRequests(cert.RequestPending)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 1)
+
+	updated, err := store.UpdateRequest("b", func(request *cert.SigningRequest) error {
+		request.Approvals = append(request.Approvals, cert.Review{By: "bob"})
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, updated.Approvals, 1)
+
+	// Failed updates are not stored
+	_, err = store.UpdateRequest("b", func(request *cert.SigningRequest) error {
+		request.Approvals = append(request.Approvals, cert.Review{By: "carol"})
+		request.Status = cert.RequestApproved
+		return errors.New("signing failed")
+	})
+	assert.Error(t, err)
+	request, err := store.GetRequest("b")
+	assert.NoError(t, err)
+	assert.Equal(t, cert.RequestPending, request.Status)
+	assert.Len(t, request.Approvals, 1)
+
+	_, err = store.GetRequest("missing")
+	assert.ErrorIs(t, err, ErrRequestNotFound)
+	_, err = store.UpdateRequest("missing", func(*cert.SigningRequest) error { return nil })
+	assert.ErrorIs(t, err, ErrRequestNotFound)
+}
@@ -0,0 +1,46 @@
+package certStore
+
+import (
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+type InMemoryRevocationStore struct {
+	sync.RWMutex
+	revoked map[string]map[uint64]bool
+}
+
+func NewInMemoryRevocationStore() *InMemoryRevocationStore {
+	return &InMemoryRevocationStore{
+		revoked: make(map[string]map[uint64]bool),
+	}
+}
+
+func (store *InMemoryRevocationStore) Revoke(caID string, serial uint64) error {
+	store.Lock()
+	defer store.Unlock()
+	if _, exists := store.revoked[caID]; !exists {
+		store.revoked[caID] = make(map[uint64]bool)
+	}
+	store.revoked[caID][serial] = true
+	return nil
+}
+
+func (store *InMemoryRevocationStore) IsRevoked(caID string, cert *ssh.Certificate) (bool, error) {
+	store.RLock()
+	defer store.RUnlock()
+	return store.revoked[caID][cert.Serial], nil
+}
+
+func (store *InMemoryRevocationStore) ListRevoked(caID string) ([]uint64, error) {
+	store.RLock()
+	defer store.RUnlock()
+	serials := []uint64{}
+	for serial := range store.revoked[caID] {
+		serials = append(serials, serial)
+	}
+	sort.Slice(serials, func(i, j int) bool { return serials[i] < serials[j] })
+	return serials, nil
+}
@@ -0,0 +1,32 @@
+package certStore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestRevocationStore(t *testing.T) {
+	store := NewInMemoryRevocationStore()
+
+	assert.NoError(t, store.Revoke("test-ca", 42))
+	assert.NoError(t, store.Revoke("test-ca", 7))
+
+	revoked, err := store.IsRevoked("test-ca", &ssh.Certificate{Serial: 42})
+	assert.NoError(t, err)
+	assert.True(t, revoked, "Expected serial 42 to be revoked")
+
+	// Revocations are scoped to the CA that issued the certificate
+	revoked, err = store.IsRevoked("other-ca", &ssh.Certificate{Serial: 42})
+	assert.NoError(t, err)
+	assert.False(t, revoked, "Expected serial 42 not to be revoked for another CA")
+
+	serials, err := store.ListRevoked("test-ca")
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{7, 42}, serials)
+
+	serials, err = store.ListRevoked("other-ca")
+	assert.NoError(t, err)
+	assert.Empty(t, serials)
+}
@@ -10,4 +10,14 @@ type CAStore interface {
 	GetSignerByID(ID string) (ssh.Signer, error)
 	CreateCA(Req cert.CaRequest) (*cert.CaResponse, error)
 	ListCAs() ([]*cert.CaResponse, error)
+	// RotateCA replaces the CA's key, keeping the old key trusted as retiring
+	RotateCA(ID string) (*cert.CaResponse, error)
+	// CompleteRotation stops trusting the CA's retiring keys
+	CompleteRotation(ID string) (*cert.CaResponse, error)
+}
+
+type RevocationStore interface {
+	Revoke(caID string, serial uint64) error
+	IsRevoked(caID string, cert *ssh.Certificate) (bool, error)
+	ListRevoked(caID string) ([]uint64, error)
 }
@@ -15,6 +15,10 @@ func (ul InMemoryUserList) GetPasswordHash(un string) (string, error) {
 	return "", errors.New("Unable to find user")
 }
 
+func (ul InMemoryUserList) Count() int {
+	return len(ul)
+}
+
 func (ul InMemoryUserList) Register(u *auth.User) *echo.HTTPError {
 	if _, ok := ul[u.Username]; ok {
 		return echo.ErrBadRequest
@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/lukegriffith/SSHTrust/pkg/auth"
+)
+
+type TokenResponse struct {
+	Token string `json:"token"`
+}
+
+// Register creates a new user on the server
+func (c *Client) Register(ctx context.Context, user auth.User) error {
+	if err := c.do(ctx, http.MethodPost, "/register", user, nil); err != nil {
+		return fmt.Errorf("failed to register: %w", err)
+	}
+	return nil
+}
+
+// Login authenticates the user and returns a bearer token. The token is not
+// stored, set TokenSource to use it for subsequent requests.
+func (c *Client) Login(ctx context.Context, user auth.User) (string, error) {
+	var tokenResp TokenResponse
+	if err := c.do(ctx, http.MethodPost, "/login", user, &tokenResp); err != nil {
+		return "", fmt.Errorf("failed to login: %w", err)
+	}
+	return tokenResp.Token, nil
+}
@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+// BreakGlassSign signs a public key with the CA identified by id in an
+// emergency, skipping its approval rules
+func (c *Client) BreakGlassSign(ctx context.Context, id string, body cert.BreakGlassRequest) (*cert.SigningRequest, error) {
+	var request cert.SigningRequest
+	if err := c.do(ctx, http.MethodPost, "/CA/"+url.PathEscape(id)+"/breakglass", body, &request); err != nil {
+		return nil, fmt.Errorf("failed to break-glass sign public key: %w", err)
+	}
+	return &request, nil
+}
+
+// ListBreakGlass lists break-glass certificates, only those from the CA
+// identified by caID and issued since unless they are empty
+func (c *Client) ListBreakGlass(ctx context.Context, caID string, since time.Time) ([]cert.SigningRequest, error) {
+	values := url.Values{}
+	if caID != "" {
+		values.Set("ca", caID)
+	}
+	if !since.IsZero() {
+		values.Set("since", since.UTC().Format(time.RFC3339))
+	}
+	path := "/breakglass"
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	var requests []cert.SigningRequest
+	if err := c.do(ctx, http.MethodGet, path, nil, &requests); err != nil {
+		return nil, fmt.Errorf("failed to list break-glass certificates: %w", err)
+	}
+	return requests, nil
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+// CreateCA creates a new Certificate Authority
+func (c *Client) CreateCA(ctx context.Context, body cert.CaRequest) (*cert.CaResponse, error) {
+	var ca cert.CaResponse
+	if err := c.do(ctx, http.MethodPost, "/CA", body, &ca); err != nil {
+		return nil, fmt.Errorf("failed to create CA: %w", err)
+	}
+	return &ca, nil
+}
+
+// GetCA retrieves a Certificate Authority by its ID
+func (c *Client) GetCA(ctx context.Context, id string) (*cert.CaResponse, error) {
+	var ca cert.CaResponse
+	if err := c.do(ctx, http.MethodGet, "/CA/"+url.PathEscape(id), nil, &ca); err != nil {
+		return nil, fmt.Errorf("failed to get CA: %w", err)
+	}
+	return &ca, nil
+}
+
+// ListCAs lists all Certificate Authorities
+func (c *Client) ListCAs(ctx context.Context) ([]cert.CaResponse, error) {
+	var cas []cert.CaResponse
+	if err := c.do(ctx, http.MethodGet, "/CA", nil, &cas); err != nil {
+		return nil, fmt.Errorf("failed to retrieve CA list: %w", err)
+	}
+	return cas, nil
+}
+
+// Sign signs a public key with the CA identified by id
+func (c *Client) Sign(ctx context.Context, id string, body cert.SignRequest) (*cert.SignResponse, error) {
+	var signed cert.SignResponse
+	if err := c.do(ctx, http.MethodPost, "/CA/"+url.PathEscape(id)+"/Sign", body, &signed); err != nil {
+		return nil, fmt.Errorf("failed to sign key: %w", err)
+	}
+	return &signed, nil
+}
+
+// Revoke revokes the certificate with serial issued by the CA identified by id
+func (c *Client) Revoke(ctx context.Context, id string, serial uint64) error {
+	body := cert.RevokeRequest{Serial: serial}
+	if err := c.do(ctx, http.MethodPost, "/CA/"+url.PathEscape(id)+"/revoke", body, nil); err != nil {
+		return fmt.Errorf("failed to revoke certificate: %w", err)
+	}
+	return nil
+}
+
+// Verify checks a user certificate for principal against the CA identified
+// by id. A rejected certificate is not an error, check Valid on the result.
+func (c *Client) Verify(ctx context.Context, id string, body cert.VerifyRequest) (*cert.VerifyResponse, error) {
+	var result cert.VerifyResponse
+	if err := c.do(ctx, http.MethodPost, "/CA/"+url.PathEscape(id)+"/verify", body, &result); err != nil {
+		return nil, fmt.Errorf("failed to verify certificate: %w", err)
+	}
+	return &result, nil
+}
+
+// KRL returns the OpenSSH key revocation list of the CA identified by id
+func (c *Client) KRL(ctx context.Context, id string) ([]byte, error) {
+	data, err := c.send(ctx, http.MethodGet, "/CA/"+url.PathEscape(id)+"/krl", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KRL: %w", err)
+	}
+	return data, nil
+}
+
+// RotateCA replaces the key of the CA identified by id, keeping the old key
+// trusted as retiring until CompleteRotation
+func (c *Client) RotateCA(ctx context.Context, id string) (*cert.CaResponse, error) {
+	var ca cert.CaResponse
+	if err := c.do(ctx, http.MethodPost, "/CA/"+url.PathEscape(id)+"/rotate", nil, &ca); err != nil {
+		return nil, fmt.Errorf("failed to rotate CA: %w", err)
+	}
+	return &ca, nil
+}
+
+// CompleteRotation stops trusting the retiring keys of the CA identified by id
+func (c *Client) CompleteRotation(ctx context.Context, id string) (*cert.CaResponse, error) {
+	var ca cert.CaResponse
+	if err := c.do(ctx, http.MethodPost, "/CA/"+url.PathEscape(id)+"/rotate/complete", nil, &ca); err != nil {
+		return nil, fmt.Errorf("failed to complete CA rotation: %w", err)
+	}
+	return &ca, nil
+}
@@ -0,0 +1,20 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+// InspectCertificate asks the server to describe a certificate, including
+// which CA in its store signed it.
+func (c *Client) InspectCertificate(ctx context.Context, certificate string) (*cert.InspectResponse, error) {
+	var info cert.InspectResponse
+	body := cert.InspectRequest{Certificate: certificate}
+	if err := c.do(ctx, http.MethodPost, "/certs/inspect", body, &info); err != nil {
+		return nil, fmt.Errorf("failed to inspect certificate: %w", err)
+	}
+	return &info, nil
+}
@@ -0,0 +1,154 @@
+// Package client is a Go SDK for the SSHTrust HTTP API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/lukegriffith/SSHTrust/pkg/handlers"
+)
+
+// TokenSource supplies the bearer token sent with each request. An empty
+// token sends the request unauthenticated.
+type TokenSource interface {
+	Token() (string, error)
+}
+
+// TokenFunc adapts a function to a TokenSource
+type TokenFunc func() (string, error)
+
+func (f TokenFunc) Token() (string, error) {
+	return f()
+}
+
+// StaticToken is a TokenSource that always returns the same token
+type StaticToken string
+
+func (t StaticToken) Token() (string, error) {
+	return string(t), nil
+}
+
+// Client talks to a single SSHTrust server
+type Client struct {
+	// Base URL of the server, e.g. https://sshtrust.example.com
+	BaseURL string
+	// HTTP client used for all requests
+	HTTPClient *http.Client
+	// Source of the bearer token, nil for unauthenticated requests
+	TokenSource TokenSource
+}
+
+// New returns a Client for the server at baseURL
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{},
+	}
+}
+
+// APIError is returned when the server responds with a non 2xx status
+type APIError struct {
+	StatusCode int
+	handlers.ErrorResponse
+}
+
+func (e *APIError) Error() string {
+	if e.ErrorResponse.Error == "" {
+		return fmt.Sprintf("sshtrust api error: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("sshtrust api error: %d - %s", e.StatusCode, e.ErrorResponse.Error)
+}
+
+// IsStatus reports whether err is an APIError with the given status code
+func IsStatus(err error, code int) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == code
+}
+
+// newRequest builds a JSON request against path, attaching the bearer token
+func (c *Client) newRequest(ctx context.Context, method, path string, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		jsonValue, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request body: %w", err)
+		}
+		reader = bytes.NewReader(jsonValue)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if c.TokenSource == nil {
+		return req, nil
+	}
+	token, err := c.TokenSource.Token()
+	if err != nil {
+		return nil, fmt.Errorf("error reading token: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+	}
+	return req, nil
+}
+
+// do sends the request and decodes a successful response into out, which
+// may be nil when the response body is not needed.
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	bodyBytes, err := c.send(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(bodyBytes, out); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	return nil
+}
+
+// send sends the request and returns the raw body of a successful response
+func (c *Client) send(ctx context.Context, method, path string, body interface{}) ([]byte, error) {
+	req, err := c.newRequest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		// Echo's own errors use "message" rather than "error"
+		var errorBody struct {
+			Error   string `json:"error"`
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(bodyBytes, &errorBody)
+		if errorBody.Error == "" {
+			errorBody.Error = errorBody.Message
+		}
+		return nil, &APIError{StatusCode: resp.StatusCode, ErrorResponse: handlers.ErrorResponse{Error: errorBody.Error}}
+	}
+
+	return bodyBytes, nil
+}
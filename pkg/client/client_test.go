@@ -0,0 +1,180 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lukegriffith/SSHTrust/internal/server"
+	"github.com/lukegriffith/SSHTrust/pkg/auth"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/stretchr/testify/assert"
+)
+
+var testPublicKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIAJI+V4/0d5xJTDvOuvR/2ZqahzceFbz00IDIFBEaKvc test@testserver.com"
+
+func TestNewRequestSuccess(t *testing.T) {
+	c := New("http://example.com/")
+	c.TokenSource = StaticToken("mocked_token")
+
+	req, err := c.newRequest(context.Background(), http.MethodPost, "/api", map[string]string{"key": "value"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+	assert.Equal(t, "Bearer mocked_token", req.Header.Get("Authorization"))
+	assert.Equal(t, http.MethodPost, req.Method)
+	assert.Equal(t, "http://example.com/api", req.URL.String())
+}
+
+func TestNewRequestErrorCreatingRequest(t *testing.T) {
+	c := New(string([]byte{0x7f})) // Invalid URL will cause error
+
+	req, err := c.newRequest(context.Background(), http.MethodGet, "/api", nil)
+
+	assert.Nil(t, req)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "error creating request")
+}
+
+func TestNewRequestEmptyTokenIsUnauthenticated(t *testing.T) {
+	c := New("http://example.com")
+	c.TokenSource = StaticToken("")
+
+	req, err := c.newRequest(context.Background(), http.MethodGet, "/api", nil)
+
+	assert.NoError(t, err)
+	assert.Empty(t, req.Header.Get("Authorization"))
+}
+
+func TestNewRequestTokenError(t *testing.T) {
+	c := New("http://example.com")
+	c.TokenSource = TokenFunc(func() (string, error) {
+		return "", errors.New("token read error")
+	})
+
+	req, err := c.newRequest(context.Background(), http.MethodGet, "/api", nil)
+
+	assert.Nil(t, req)
+	assert.ErrorContains(t, err, "token read error")
+}
+
+func TestClientAgainstServer(t *testing.T) {
+	ts := httptest.NewServer(server.SetupServer(false))
+	defer ts.Close()
+	ctx := context.Background()
+
+	c := New(ts.URL)
+
+	// Unauthenticated requests are rejected with a typed error
+	_, err := c.ListCAs(ctx)
+	assert.True(t, IsStatus(err, http.StatusUnauthorized), "expected 401, got %v", err)
+
+	user := auth.User{Username: "test", Password: "1234"}
+	assert.NoError(t, c.Register(ctx, user))
+	token, err := c.Login(ctx, user)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	c.TokenSource = StaticToken(token)
+
+	created, err := c.CreateCA(ctx, cert.CaRequest{CommonCa: cert.CommonCa{
+		Name: "myca", Type: cert.ED25519, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 60,
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, "myca", created.Name)
+
+	ca, err := c.GetCA(ctx, "myca")
+	assert.NoError(t, err)
+	assert.Equal(t, created.PublicKey, ca.PublicKey)
+
+	cas, err := c.ListCAs(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, cas, 1)
+
+	signed, err := c.Sign(ctx, "myca", cert.SignRequest{PublicKey: testPublicKey, Principals: []string{"testuser"}, TTLMinutes: 5})
+	assert.NoError(t, err)
+	assert.Contains(t, signed.SignedKey, "ssh-ed25519-cert-v01@openssh.com")
+
+	// Errors from handlers carry the ErrorResponse message
+	_, err = c.GetCA(ctx, "missing")
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+		assert.Equal(t, "CA not found", apiErr.ErrorResponse.Error)
+	}
+
+	_, err = c.Sign(ctx, "myca", cert.SignRequest{PublicKey: testPublicKey, Principals: []string{"root"}, TTLMinutes: 5})
+	assert.True(t, IsStatus(err, http.StatusBadRequest))
+	// Revoked certificates no longer verify
+	verifyRequest := cert.VerifyRequest{Certificate: signed.SignedKey, Principal: "testuser"}
+	result, err := c.Verify(ctx, "myca", verifyRequest)
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, signed.Serial, result.Serial)
+
+	assert.NoError(t, c.Revoke(ctx, "myca", signed.Serial))
+	result, err = c.Verify(ctx, "myca", verifyRequest)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Contains(t, result.Reason, "revoked")
+
+	// Certificates from a retiring key still verify until the rotation completes
+	signed, err = c.Sign(ctx, "myca", cert.SignRequest{PublicKey: testPublicKey, Principals: []string{"testuser"}, TTLMinutes: 5})
+	assert.NoError(t, err)
+	_, err = c.RotateCA(ctx, "myca")
+	assert.NoError(t, err)
+	verifyRequest.Certificate = signed.SignedKey
+	result, err = c.Verify(ctx, "myca", verifyRequest)
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	bundle, err := c.UserCAKeys(ctx, TrustFilter{CAs: []string{"myca"}})
+	assert.NoError(t, err)
+	assert.Contains(t, string(bundle), "myca (retiring)")
+
+	_, err = c.CompleteRotation(ctx, "myca")
+	assert.NoError(t, err)
+	result, err = c.Verify(ctx, "myca", verifyRequest)
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+}
+
+func TestHostEnrollment(t *testing.T) {
+	ts := httptest.NewServer(server.SetupServer(false))
+	defer ts.Close()
+	ctx := context.Background()
+
+	admin := New(ts.URL)
+	user := auth.User{Username: "admin", Password: "1234"}
+	assert.NoError(t, admin.Register(ctx, user))
+	token, err := admin.Login(ctx, user)
+	assert.NoError(t, err)
+	admin.TokenSource = StaticToken(token)
+
+	_, err = admin.CreateCA(ctx, cert.CaRequest{CommonCa: cert.CommonCa{
+		Name: "hostca", Type: cert.ED25519, Kind: cert.HostCA, HostPatterns: []string{"*.example.com"},
+		ValidPrincipals: []string{"web1.example.com"}, MaxTTLMinutes: 1440,
+	}})
+	assert.NoError(t, err)
+	joinToken, err := admin.CreateJoinToken(ctx, cert.JoinTokenRequest{CA: "hostca", Hostnames: []string{"web1.example.com"}, TTLMinutes: 1440})
+	assert.NoError(t, err)
+
+	// Hosts enroll with the join token alone
+	host := New(ts.URL)
+	enrolled, err := host.Enroll(ctx, cert.EnrollRequest{
+		Token: joinToken.Token, Hostnames: []string{"web1.example.com"}, HostKeys: []string{testPublicKey},
+	})
+	assert.NoError(t, err)
+	assert.Len(t, enrolled.Certificates, 1)
+
+	// The registry is only available to logged in users
+	_, err = host.ListHosts(ctx)
+	assert.True(t, IsStatus(err, http.StatusUnauthorized), "expected 401, got %v", err)
+	hosts, err := admin.ListHosts(ctx)
+	assert.NoError(t, err)
+	if assert.Len(t, hosts, 1) {
+		assert.Equal(t, enrolled.Host.Keys, hosts[0].Keys)
+	}
+	_, err = admin.GetHost(ctx, "db1.example.com")
+	assert.True(t, IsStatus(err, http.StatusNotFound), "expected 404, got %v", err)
+}
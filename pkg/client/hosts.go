@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+// CreateJoinToken issues a one time token for a host to enroll with
+func (c *Client) CreateJoinToken(ctx context.Context, body cert.JoinTokenRequest) (*cert.JoinTokenResponse, error) {
+	var token cert.JoinTokenResponse
+	if err := c.do(ctx, http.MethodPost, "/hosts/tokens", body, &token); err != nil {
+		return nil, fmt.Errorf("failed to issue join token: %w", err)
+	}
+	return &token, nil
+}
+
+// Enroll exchanges a join token for host certificates. It is authenticated
+// by the join token, no login is needed.
+func (c *Client) Enroll(ctx context.Context, body cert.EnrollRequest) (*cert.EnrollResponse, error) {
+	var enrolled cert.EnrollResponse
+	if err := c.do(ctx, http.MethodPost, "/hosts/enroll", body, &enrolled); err != nil {
+		return nil, fmt.Errorf("failed to enroll host: %w", err)
+	}
+	return &enrolled, nil
+}
+
+// ListHosts lists the enrolled hosts
+func (c *Client) ListHosts(ctx context.Context) ([]cert.Host, error) {
+	var hosts []cert.Host
+	if err := c.do(ctx, http.MethodGet, "/hosts", nil, &hosts); err != nil {
+		return nil, fmt.Errorf("failed to list hosts: %w", err)
+	}
+	return hosts, nil
+}
+
+// GetHost retrieves an enrolled host by name
+func (c *Client) GetHost(ctx context.Context, name string) (*cert.Host, error) {
+	var host cert.Host
+	if err := c.do(ctx, http.MethodGet, "/hosts/"+url.PathEscape(name), nil, &host); err != nil {
+		return nil, fmt.Errorf("failed to get host: %w", err)
+	}
+	return &host, nil
+}
+
+// RenewHost renews an enrolled host key's certificate. It is authenticated
+// by the proof of possession in the request, see cert.NewRenewHostRequest.
+func (c *Client) RenewHost(ctx context.Context, body cert.RenewHostRequest) (*cert.RenewHostResponse, error) {
+	var renewed cert.RenewHostResponse
+	if err := c.do(ctx, http.MethodPost, "/hosts/renew", body, &renewed); err != nil {
+		return nil, fmt.Errorf("failed to renew host certificate: %w", err)
+	}
+	return &renewed, nil
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+// CreateSigningRequest submits a signing request to the CA identified by id.
+// Requests needing approval come back pending, others already signed.
+func (c *Client) CreateSigningRequest(ctx context.Context, id string, body cert.CreateSigningRequest) (*cert.SigningRequest, error) {
+	var request cert.SigningRequest
+	if err := c.do(ctx, http.MethodPost, "/CA/"+url.PathEscape(id)+"/requests", body, &request); err != nil {
+		return nil, fmt.Errorf("failed to create signing request: %w", err)
+	}
+	return &request, nil
+}
+
+// ListSigningRequests lists signing requests, only those with status and to
+// the CA identified by caID unless they are empty
+func (c *Client) ListSigningRequests(ctx context.Context, status cert.SigningRequestStatus, caID string) ([]cert.SigningRequest, error) {
+	values := url.Values{}
+	if status != "" {
+		values.Set("status", string(status))
+	}
+	if caID != "" {
+		values.Set("ca", caID)
+	}
+	path := "/requests"
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	var requests []cert.SigningRequest
+	if err := c.do(ctx, http.MethodGet, path, nil, &requests); err != nil {
+		return nil, fmt.Errorf("failed to list signing requests: %w", err)
+	}
+	return requests, nil
+}
+
+// GetSigningRequest retrieves a signing request, with its certificate once
+// it is approved
+func (c *Client) GetSigningRequest(ctx context.Context, id string) (*cert.SigningRequest, error) {
+	var request cert.SigningRequest
+	if err := c.do(ctx, http.MethodGet, "/requests/"+url.PathEscape(id), nil, &request); err != nil {
+		return nil, fmt.Errorf("failed to get signing request: %w", err)
+	}
+	return &request, nil
+}
+
+// ApproveSigningRequest approves a pending signing request as the logged in
+// user. The last approval needed signs it.
+func (c *Client) ApproveSigningRequest(ctx context.Context, id, comment string) (*cert.SigningRequest, error) {
+	var request cert.SigningRequest
+	body := cert.ReviewRequest{Comment: comment}
+	if err := c.do(ctx, http.MethodPost, "/requests/"+url.PathEscape(id)+"/approve", body, &request); err != nil {
+		return nil, fmt.Errorf("failed to approve signing request: %w", err)
+	}
+	return &request, nil
+}
+
+// DenySigningRequest denies a pending signing request as the logged in user
+func (c *Client) DenySigningRequest(ctx context.Context, id, comment string) (*cert.SigningRequest, error) {
+	var request cert.SigningRequest
+	body := cert.ReviewRequest{Comment: comment}
+	if err := c.do(ctx, http.MethodPost, "/requests/"+url.PathEscape(id)+"/deny", body, &request); err != nil {
+		return nil, fmt.Errorf("failed to deny signing request: %w", err)
+	}
+	return &request, nil
+}
@@ -0,0 +1,74 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TrustFilter selects the CAs included in a trust bundle. The zero value
+// selects every CA.
+type TrustFilter struct {
+	// Only include CAs with this tag
+	Tag string
+	// Only include these CAs
+	CAs []string
+}
+
+func (f TrustFilter) query() string {
+	values := url.Values{}
+	if f.Tag != "" {
+		values.Set("tag", f.Tag)
+	}
+	if len(f.CAs) > 0 {
+		values.Set("ca", strings.Join(f.CAs, ","))
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	return "?" + values.Encode()
+}
+
+// UserCAKeys returns a TrustedUserCAKeys file for the CAs selected by filter
+func (c *Client) UserCAKeys(ctx context.Context, filter TrustFilter) ([]byte, error) {
+	data, err := c.send(ctx, http.MethodGet, "/trust/user-ca-keys"+filter.query(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user CA keys: %w", err)
+	}
+	return data, nil
+}
+
+// KnownHosts returns @cert-authority known_hosts lines for the host CAs
+// selected by filter
+func (c *Client) KnownHosts(ctx context.Context, filter TrustFilter) ([]byte, error) {
+	data, err := c.send(ctx, http.MethodGet, "/trust/known_hosts"+filter.query(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get known hosts: %w", err)
+	}
+	return data, nil
+}
+
+// Principals returns the principals allowed to log in to account on a host
+// with tags, one per line as AuthorizedPrincipalsCommand expects
+func (c *Client) Principals(ctx context.Context, account string, tags []string) ([]byte, error) {
+	values := url.Values{"user": {account}}
+	if len(tags) > 0 {
+		values.Set("tag", strings.Join(tags, ","))
+	}
+	data, err := c.send(ctx, http.MethodGet, "/trust/principals?"+values.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get principals: %w", err)
+	}
+	return data, nil
+}
+
+// TrustKRL returns a single OpenSSH KRL for the user CAs selected by filter
+func (c *Client) TrustKRL(ctx context.Context, filter TrustFilter) ([]byte, error) {
+	data, err := c.send(ctx, http.MethodGet, "/trust/krl"+filter.query(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get KRL: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,19 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/lukegriffith/SSHTrust/pkg/webhook"
+)
+
+// TestWebhooks has the server send a test event to every webhook, returning
+// how each receiver answered
+func (c *Client) TestWebhooks(ctx context.Context) ([]webhook.Result, error) {
+	var results []webhook.Result
+	if err := c.do(ctx, http.MethodPost, "/webhooks/test", nil, &results); err != nil {
+		return nil, fmt.Errorf("failed to test webhooks: %w", err)
+	}
+	return results, nil
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/auth"
+)
+
+// Audited records every request to the route in the audit log as action,
+// publishes the webhook events it leads to and counts logins. Handlers fill in the CA,
+// principals and serial with audit.FromContext, the result and reason for
+// denials come from the response.
+func (a *App) Audited(action string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			event := &audit.Event{Action: action}
+			c.Set(audit.ContextKey, event)
+			recorder := &errorRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+			err := next(c)
+			c.Response().Writer = recorder.ResponseWriter
+
+			status, reason := c.Response().Status, recorder.reason()
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status, reason = httpErr.Code, fmt.Sprint(httpErr.Message)
+			} else if err != nil {
+				status, reason = http.StatusInternalServerError, err.Error()
+			}
+			switch {
+			case status >= http.StatusInternalServerError:
+				event.Result = audit.Failed
+			case status >= http.StatusBadRequest:
+				event.Result = audit.Denied
+			default:
+				event.Result = audit.Success
+			}
+			if event.Result != audit.Success && event.Reason == "" {
+				event.Reason = reason
+			}
+			if event.Actor == "" {
+				event.Actor = auth.Username(c)
+			}
+			event.SourceIP = c.RealIP()
+			if logErr := a.Audit.Log(*event); logErr != nil {
+				c.Logger().Errorf("Failed to audit %s: %v", action, logErr)
+			}
+			a.notify(c, *event)
+			if action == "user.login" {
+				a.Metrics.ObserveLogin(event.Result == audit.Success)
+			}
+			return err
+		}
+	}
+}
+
+// errorRecorder keeps the body of error responses, for the reason they give
+type errorRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *errorRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+func (r *errorRecorder) Write(b []byte) (int, error) {
+	if r.status >= http.StatusBadRequest && r.body.Len() < 4096 {
+		r.body.Write(b)
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *errorRecorder) reason() string {
+	var response ErrorResponse
+	if err := json.Unmarshal(r.body.Bytes(), &response); err != nil {
+		return ""
+	}
+	return response.Error
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+var testAuditKey = []byte(strings.Repeat("k", 32))
+
+func TestAuditedSign(t *testing.T) {
+	app := newRequestsApp(t)
+	var buf bytes.Buffer
+	app.Audit = audit.NewLogger(&buf, testAuditKey)
+
+	e := echo.New()
+	loggedIn := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set("user", jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": "alice"}))
+			return next(c)
+		}
+	}
+	e.POST("/CA/:id/Sign", app.Sign, loggedIn, app.Audited("cert.sign"))
+	sign := func(body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/CA/prod-ca/Sign", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.RemoteAddr = "192.0.2.10:52000"
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusCreated, sign(`{"public_key":"`+testPublicKey+`","principals":["deploy"],"ttl_minutes":30}`))
+	assert.Equal(t, http.StatusBadRequest, sign(`{"public_key":"`+testPublicKey+`","principals":["nobody"],"ttl_minutes":30}`))
+	assert.Equal(t, http.StatusForbidden, sign(`{"public_key":"`+testPublicKey+`","principals":["root"],"ttl_minutes":30}`))
+
+	var events []audit.Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event audit.Event
+		assert.NoError(t, json.Unmarshal([]byte(line), &event))
+		events = append(events, event)
+	}
+	if assert.Len(t, events, 3) {
+		signed := events[0]
+		assert.Equal(t, "cert.sign", signed.Action)
+		assert.Equal(t, "alice", signed.Actor)
+		assert.Equal(t, "192.0.2.10", signed.SourceIP)
+		assert.Equal(t, "prod-ca", signed.CA)
+		assert.Equal(t, []string{"deploy"}, signed.Principals)
+		assert.Equal(t, audit.Success, signed.Result)
+		assert.NotZero(t, signed.Serial)
+		assert.Empty(t, signed.Reason)
+
+		assert.Equal(t, audit.Denied, events[1].Result)
+		assert.Equal(t, "Requested principals not in valid principal list", events[1].Reason)
+		assert.Equal(t, []string{"nobody"}, events[1].Principals)
+		assert.Equal(t, audit.Denied, events[2].Result)
+		assert.Contains(t, events[2].Reason, "needs approval")
+	}
+	count, _, err := audit.Verify(&buf, testAuditKey)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+}
+
+func TestAuditedPublishesWebhooks(t *testing.T) {
+	app := newRequestsApp(t)
+	queue := webhook.NewMemoryQueue()
+	app.Webhooks = webhook.NewDispatcher([]webhook.Hook{{URL: "https://chat.example.com/hook"}}, queue)
+
+	e := echo.New()
+	e.POST("/CA/:id/Sign", app.Sign, app.Audited("cert.sign"))
+	e.POST("/CA/:id/requests", app.CreateSigningRequest, app.Audited("request.create"))
+	post := func(target, body string) int {
+		req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	assert.Equal(t, http.StatusCreated, post("/CA/prod-ca/Sign", `{"public_key":"`+testPublicKey+`","principals":["deploy"],"ttl_minutes":30}`))
+	// Denials are not published
+	assert.Equal(t, http.StatusBadRequest, post("/CA/prod-ca/Sign", `{"public_key":"`+testPublicKey+`","principals":["nobody"],"ttl_minutes":30}`))
+	assert.Equal(t, http.StatusCreated, post("/CA/prod-ca/requests", `{"public_key":"`+testPublicKey+`","principals":["root"],"ttl_minutes":30}`))
+
+	deliveries, err := queue.List()
+	assert.NoError(t, err)
+	types := map[string]webhook.Event{}
+	for _, delivery := range deliveries {
+		var event webhook.Event
+		assert.NoError(t, json.Unmarshal(delivery.Payload, &event))
+		types[event.Type] = event
+	}
+	assert.Len(t, deliveries, 2)
+	if assert.Contains(t, types, webhook.CertIssued) {
+		data := types[webhook.CertIssued].Data.(map[string]any)
+		assert.Equal(t, "prod-ca", data["ca"])
+		assert.NotZero(t, data["serial"])
+	}
+	assert.Contains(t, types, webhook.RequestCreated)
+}
+
+func TestWebhookEvents(t *testing.T) {
+	tests := []struct {
+		event audit.Event
+		want  []string
+	}{
+		{audit.Event{Action: "user.login", Result: audit.Denied}, []string{webhook.LoginFailed}},
+		{audit.Event{Action: "user.login", Result: audit.Success}, nil},
+		{audit.Event{Action: "ca.create", Result: audit.Success}, []string{webhook.CACreated}},
+		{audit.Event{Action: "ca.create", Result: audit.Failed}, nil},
+		{audit.Event{Action: "ca.rotate", Result: audit.Success}, []string{webhook.CARotated}},
+		{audit.Event{Action: "cert.revoke", Result: audit.Success}, []string{webhook.CertRevoked}},
+		{audit.Event{Action: "cert.breakglass", Result: audit.Success}, []string{webhook.CertBreakGlass, webhook.CertIssued}},
+		{audit.Event{Action: "host.enroll", Result: audit.Success}, []string{webhook.CertIssued}},
+		{audit.Event{Action: "request.create", Result: audit.Success, Details: map[string]string{"status": "approved"}}, []string{webhook.CertIssued}},
+		{audit.Event{Action: "request.approve", Result: audit.Success, Details: map[string]string{"status": "pending"}}, nil},
+		{audit.Event{Action: "request.approve", Result: audit.Success, Details: map[string]string{"status": "approved"}}, []string{webhook.RequestApproved, webhook.CertIssued}},
+		{audit.Event{Action: "request.deny", Result: audit.Success}, []string{webhook.RequestDenied}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, webhookEvents(tt.event), tt.event.Action)
+	}
+}
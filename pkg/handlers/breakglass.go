@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/auth"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+)
+
+// BreakGlassSign signs a public key in an emergency, without approval
+// @Summary Sign a public key in an emergency
+// @Description Sign a user key without waiting for the CA's approval rules, e.g. during an incident. Only the CA's break-glass responders may, nobody when it has none. A reason and ticket reference are mandatory, the certificate is valid for at most an hour and its key ID starts with BREAK-GLASS. The key policy and valid principals still apply. Every break-glass certificate is logged as a warning and kept for the post-incident review listing.
+// @Tags CAs
+// @Accept  json
+// @Produce  json
+// @Param id path string true "CA ID"
+// @Param request body cert.BreakGlassRequest true "Public key to be signed, the reason and ticket reference"
+// @Success 201 {object} cert.SigningRequest
+// @Failure 400 {object} ErrorResponse "Missing reason or ticket, invalid request or failed to parse public key"
+// @Failure 403 {object} ErrorResponse "Not a break-glass responder for the CA"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Failure 500 {object} ErrorResponse "Failed to sign public key"
+// @Router /CA/{id}/breakglass [post]
+func (a *App) BreakGlassSign(c echo.Context) error {
+	CaID := c.Param("id")
+	event := audit.FromContext(c)
+	event.CA = CaID
+	ca, err := a.Store.GetCAByID(CaID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	signer, err := a.Store.GetSignerByID(CaID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA signer not found"})
+	}
+	var requestBody cert.BreakGlassRequest
+	if err := c.Bind(&requestBody); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	event.Principals = requestBody.Principals
+	event.Details = map[string]string{"priority": "high", "ticket": requestBody.Ticket, "justification": requestBody.Reason}
+	if ca.IsHostCA() {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Break-glass certificates are only issued by user CAs"})
+	}
+	if !ca.CanBreakGlass(auth.Username(c)) {
+		return c.JSON(http.StatusForbidden, ErrorResponse{"Not a break-glass responder for this CA"})
+	}
+	if err := requestBody.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{err.Error()})
+	}
+	if requestBody.TTLMinutes == 0 {
+		requestBody.TTLMinutes = ca.BreakGlassTTL()
+	}
+
+	now := time.Now()
+	plan, rejected := planSign(ca, requestBody.SignRequest, now)
+	if rejected != nil {
+		return c.JSON(rejected.status, ErrorResponse{rejected.message})
+	}
+	id, err := newRequestID()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to sign public key"})
+	}
+	request := cert.SigningRequest{
+		ID:         id,
+		CA:         ca.Name,
+		Requester:  auth.Username(c),
+		Request:    requestBody.SignRequest,
+		Reason:     requestBody.Reason,
+		Status:     cert.RequestPending,
+		CreatedAt:  now.UTC().Truncate(time.Second),
+		BreakGlass: true,
+		Ticket:     requestBody.Ticket,
+		KeyID:      cert.BreakGlassKeyID(requestBody.Ticket, auth.Username(c), now),
+	}
+	plan.keyID = request.KeyID
+	if err := issueRequest(&request, plan, signer); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to sign public key"})
+	}
+	event.Serial = request.Serial
+	event.Details["request"] = request.ID
+	if err := a.Requests.AddRequest(request); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to record break-glass certificate"})
+	}
+	c.Logger().Warnf("BREAK-GLASS certificate %d issued by %s to %q for %v, ticket %s: %s",
+		request.Serial, CaID, request.Requester, request.Request.Principals, request.Ticket, request.Reason)
+	return c.JSON(http.StatusCreated, request)
+}
+
+// ListBreakGlass lists break-glass certificates for post-incident review
+// @Summary List break-glass certificates
+// @Description List the certificates issued with break-glass signing, oldest first, with who asked for them, why and under which ticket.
+// @Tags CAs
+// @Produce  json
+// @Param ca query string false "Only certificates from this CA"
+// @Param since query string false "Only certificates issued at or after this time, RFC3339"
+// @Success 200 {array} cert.SigningRequest
+// @Failure 400 {object} ErrorResponse "Invalid since time"
+// @Failure 500 {object} ErrorResponse "Failed to list break-glass certificates"
+// @Router /breakglass [get]
+func (a *App) ListBreakGlass(c echo.Context) error {
+	var since time.Time
+	if value := c.QueryParam("since"); value != "" {
+		var err error
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid since time, use RFC3339"})
+		}
+	}
+	requests, err := a.Requests.ListRequests(cert.RequestApproved)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to list break-glass certificates"})
+	}
+	caID := c.QueryParam("ca")
+	issued := []cert.SigningRequest{}
+	for _, request := range requests {
+		if request.BreakGlass && (caID == "" || request.CA == caID) && !request.CreatedAt.Before(since) {
+			issued = append(issued, request)
+		}
+	}
+	return c.JSON(http.StatusOK, issued)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func breakGlassAs(t *testing.T, app *App, user, body string) (cert.SigningRequest, int, string) {
+	c, rec := requestAs(user, http.MethodPost, "/CA/prod-ca/breakglass", body)
+	c.SetParamNames("id")
+	c.SetParamValues("prod-ca")
+	var request cert.SigningRequest
+	if assert.NoError(t, app.BreakGlassSign(c)) && rec.Code == http.StatusCreated {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &request))
+	}
+	return request, rec.Code, rec.Body.String()
+}
+
+func TestBreakGlassSign(t *testing.T) {
+	app := newRequestsApp(t)
+
+	// root needs two approvals through /requests, break-glass skips them
+	request, code, _ := breakGlassAs(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["root"],"reason":"prod is down","ticket":"INC-42"}`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Equal(t, cert.RequestApproved, request.Status)
+	assert.True(t, request.BreakGlass)
+	assert.Equal(t, "alice", request.Requester)
+	assert.Equal(t, "INC-42", request.Ticket)
+
+	pub, _, _, _, err := ssh.ParseAuthorizedKey([]byte(request.SignedKey))
+	assert.NoError(t, err)
+	signed := pub.(*ssh.Certificate)
+	assert.Equal(t, request.KeyID, signed.KeyId)
+	assert.True(t, strings.HasPrefix(signed.KeyId, cert.BreakGlassKeyIDPrefix+" ticket=INC-42 user=alice"))
+	assert.Equal(t, []string{"root"}, signed.ValidPrincipals)
+	// Short-lived by default, whatever the CA's max TTL
+	assert.Equal(t, uint64(cert.BreakGlassMaxTTLMinutes*60), signed.ValidBefore-signed.ValidAfter)
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"no reason", `{"public_key":"` + testPublicKey + `","principals":["root"],"ticket":"INC-42"}`, "need a reason"},
+		{"no ticket", `{"public_key":"` + testPublicKey + `","principals":["root"],"reason":"prod is down"}`, "need a ticket"},
+		{"too long", `{"public_key":"` + testPublicKey + `","principals":["root"],"ttl_minutes":120,"reason":"prod is down","ticket":"INC-42"}`, "at most 60 minutes"},
+		{"scheduled", `{"public_key":"` + testPublicKey + `","principals":["root"],"valid_after":"2030-01-01T00:00:00Z","reason":"prod is down","ticket":"INC-42"}`, "start now"},
+		{"invalid principal", `{"public_key":"` + testPublicKey + `","principals":["nobody"],"reason":"prod is down","ticket":"INC-42"}`, "not in valid principal list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, code, body := breakGlassAs(t, app, "alice", tt.body)
+			assert.Equal(t, http.StatusBadRequest, code)
+			assert.Contains(t, body, tt.want)
+		})
+	}
+}
+
+func TestBreakGlassNeedsResponder(t *testing.T) {
+	app := newRequestsApp(t)
+	body := `{"public_key":"` + testPublicKey + `","principals":["root"],"reason":"prod is down","ticket":"INC-42"}`
+
+	// Any registered user could otherwise skip the approval rules
+	_, code, response := breakGlassAs(t, app, "mallory", body)
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, response, "Not a break-glass responder")
+	_, code, _ = breakGlassAs(t, app, "", body)
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// CAs without responders let nobody break glass
+	ca, _ := app.Store.GetCAByID("prod-ca")
+	ca.BreakGlassResponders = nil
+	_, code, _ = breakGlassAs(t, app, "alice", body)
+	assert.Equal(t, http.StatusForbidden, code)
+}
+
+func TestListBreakGlass(t *testing.T) {
+	app := newRequestsApp(t)
+	_, _, _ = breakGlassAs(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["root"],"ttl_minutes":15,"reason":"prod is down","ticket":"INC-42"}`)
+	// Ordinary requests are not listed
+	submitRequest(t, app, "bob", `{"public_key":"`+testPublicKey+`","principals":["deploy"],"ttl_minutes":30}`)
+
+	list := func(query string) ([]cert.SigningRequest, int) {
+		c, rec := requestAs("carol", http.MethodGet, "/breakglass"+query, "")
+		assert.NoError(t, app.ListBreakGlass(c))
+		var issued []cert.SigningRequest
+		if rec.Code == http.StatusOK {
+			assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &issued))
+		}
+		return issued, rec.Code
+	}
+
+	issued, code := list("")
+	assert.Equal(t, http.StatusOK, code)
+	if assert.Len(t, issued, 1) {
+		assert.Equal(t, "INC-42", issued[0].Ticket)
+		assert.Equal(t, "prod is down", issued[0].Reason)
+	}
+	issued, _ = list("?ca=other-ca")
+	assert.Empty(t, issued)
+	issued, _ = list("?since=" + time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+	assert.Empty(t, issued)
+	_, code = list("?since=yesterday")
+	assert.Equal(t, http.StatusBadRequest, code)
+}
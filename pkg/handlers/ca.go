@@ -3,13 +3,31 @@ package handlers
 import (
 	"fmt"
 	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
 	"github.com/lukegriffith/SSHTrust/pkg/cert"
 	"github.com/lukegriffith/SSHTrust/pkg/certStore"
+	"github.com/lukegriffith/SSHTrust/pkg/metrics"
+	"github.com/lukegriffith/SSHTrust/pkg/webhook"
 	"net/http"
 )
 
 type App struct {
-	Store certStore.CAStore
+	Store       certStore.CAStore
+	Revocations certStore.RevocationStore
+	Hosts       certStore.HostStore
+	Requests    certStore.SigningRequestStore
+	// Security relevant events are recorded here, none when nil
+	Audit *audit.Logger
+	// Webhook events are published here, none when nil
+	Webhooks *webhook.Dispatcher
+	// Signing and login metrics are counted here, none when nil
+	Metrics *metrics.Metrics
+	// Hosts may fetch /trust/principals with this token instead of a login,
+	// none when empty
+	PrincipalsToken string
+	// Prometheus may scrape /metrics with this token instead of a login,
+	// none when empty
+	MetricsToken string
 }
 
 type MessageResponse struct {
@@ -59,6 +77,7 @@ func (a *App) CreateCA(c echo.Context) error {
 	if err := c.Bind(&newCA); err != nil {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
 	}
+	audit.FromContext(c).CA = newCA.Name
 
 	c.Logger().Info("new ca requested ", newCA.Name, newCA.MaxTTLMinutes)
 	// Call the service to create the CA
@@ -84,3 +103,43 @@ func (a *App) ListCA(c echo.Context) error {
 	caList, _ := a.Store.ListCAs()
 	return c.JSON(http.StatusOK, caList)
 }
+
+// RotateCA replaces a CA's key
+// @Summary Rotate a SSH Certificate Authority's key
+// @Description Generate a new key for the CA. The previous key is kept as a retiring key, still trusted by the trust bundles and verification until the rotation is completed.
+// @Tags CAs
+// @Produce  json
+// @Param id path string true "CA ID"
+// @Success 200 {object} cert.CaResponse "The CA with its new key"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Router /CA/{id}/rotate [post]
+func (a *App) RotateCA(c echo.Context) error {
+	CaID := c.Param("id")
+	audit.FromContext(c).CA = CaID
+	CA, err := a.Store.RotateCA(CaID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	c.Logger().Infof("rotated key %s", CaID)
+	return c.JSON(http.StatusOK, CA)
+}
+
+// CompleteRotation stops trusting a CA's retiring keys
+// @Summary Complete a SSH Certificate Authority's key rotation
+// @Description Drop the CA's retiring keys once hosts trust the new key and certificates from the old key have expired.
+// @Tags CAs
+// @Produce  json
+// @Param id path string true "CA ID"
+// @Success 200 {object} cert.CaResponse "The CA without retiring keys"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Router /CA/{id}/rotate/complete [post]
+func (a *App) CompleteRotation(c echo.Context) error {
+	CaID := c.Param("id")
+	audit.FromContext(c).CA = CaID
+	CA, err := a.Store.CompleteRotation(CaID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	c.Logger().Infof("completed rotation of %s", CaID)
+	return c.JSON(http.StatusOK, CA)
+}
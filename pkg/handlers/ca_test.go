@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"github.com/labstack/echo/v4"
@@ -55,6 +57,31 @@ func (m *MockStore) GetSignerByID(ID string) (ssh.Signer, error) {
 	return m.signers[ID], nil
 }
 
+func (m *MockStore) RotateCA(ID string) (*cert.CaResponse, error) {
+	ca, exists := m.caMap[ID]
+	if !exists {
+		return nil, errors.New("CA not found")
+	}
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signer, _ := ssh.NewSignerFromKey(priv)
+	ca.RetiringPublicKeys = append(ca.RetiringPublicKeys, ca.PublicKey)
+	ca.PublicKey = string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	if m.signers == nil {
+		m.signers = map[string]ssh.Signer{}
+	}
+	m.signers[ID] = signer
+	return ca, nil
+}
+
+func (m *MockStore) CompleteRotation(ID string) (*cert.CaResponse, error) {
+	ca, exists := m.caMap[ID]
+	if !exists {
+		return nil, errors.New("CA not found")
+	}
+	ca.RetiringPublicKeys = nil
+	return ca, nil
+}
+
 // Test for GetCA handler
 func TestGetCAHandler(t *testing.T) {
 	e := echo.New()
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/certStore"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// DefaultJoinTokenExpiry is how long join tokens can be used for by default
+	DefaultJoinTokenExpiry = time.Hour
+	// RenewProofMaxAge bounds the clock skew, and replay window, of host
+	// renewal proofs
+	RenewProofMaxAge = 5 * time.Minute
+)
+
+var errHostKeyIsCertificate = errors.New("host key is a certificate")
+
+// CreateJoinToken issues a one time token a new host enrolls with
+// @Summary Issue a host join token
+// @Description Issue a one time token a host uses to enroll with a host CA, for at least one of its host names. The token is only returned once, the server keeps a hash of it. Hosts already in the registry can only enroll again with a reenroll token.
+// @Tags Hosts
+// @Accept  json
+// @Produce  json
+// @Param token body cert.JoinTokenRequest true "Host CA and host names the token allows"
+// @Success 201 {object} cert.JoinTokenResponse
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 400 {object} ErrorResponse "Requested TTL longer than configured max"
+// @Failure 400 {object} ErrorResponse "Requested principals not in valid principal list"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Failure 500 {object} ErrorResponse "Failed to issue join token"
+// @Router /hosts/tokens [post]
+func (a *App) CreateJoinToken(c echo.Context) error {
+	var requestBody cert.JoinTokenRequest
+	if err := c.Bind(&requestBody); err != nil || requestBody.TTLMinutes <= 0 || requestBody.ExpiresInMinutes < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	event := audit.FromContext(c)
+	event.CA = requestBody.CA
+	event.Principals = requestBody.Hostnames
+	ca, err := a.Store.GetCAByID(requestBody.CA)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	if !ca.IsHostCA() {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Join tokens can only be issued for host CAs"})
+	}
+	if requestBody.TTLMinutes > ca.MaxHostTTL() {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Requested TTL longer than configured max"})
+	}
+	if len(requestBody.Hostnames) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Join tokens need at least one host name"})
+	}
+	if !isSubset(requestBody.Hostnames, ca.ValidPrincipals) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Requested principals not in valid principal list"})
+	}
+
+	secret, err := newJoinTokenSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to issue join token"})
+	}
+	expiry := DefaultJoinTokenExpiry
+	if requestBody.ExpiresInMinutes > 0 {
+		expiry = time.Duration(requestBody.ExpiresInMinutes) * time.Minute
+	}
+	token := certStore.JoinToken{
+		ID:         certStore.JoinTokenID(secret),
+		CA:         ca.Name,
+		Hostnames:  requestBody.Hostnames,
+		TTLMinutes: requestBody.TTLMinutes,
+		ExpiresAt:  time.Now().Add(expiry).UTC().Truncate(time.Second),
+		Reenroll:   requestBody.Reenroll,
+	}
+	if err := a.Hosts.AddJoinToken(secret, token); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to issue join token"})
+	}
+	event.Details = map[string]string{"join_token": token.ID}
+	c.Logger().Infof("Issued join token %s for %s", token.ID, ca.Name)
+	return c.JSON(http.StatusCreated, cert.JoinTokenResponse{
+		Token:     secret,
+		ID:        token.ID,
+		CA:        token.CA,
+		Hostnames: token.Hostnames,
+		Reenroll:  token.Reenroll,
+		ExpiresAt: token.ExpiresAt,
+	})
+}
+
+func newJoinTokenSecret() (string, error) {
+	var b [32]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b[:]), nil
+}
+
+// Enroll signs a new host's keys in exchange for a join token
+// @Summary Enroll a host
+// @Description Exchange a join token for host certificates for each of the host's keys, and record the host in the registry. Authenticated by the join token rather than a login, each token can only be used once. A host already in the registry is only replaced with a reenroll token.
+// @Tags Hosts
+// @Accept  json
+// @Produce  json
+// @Param enrollment body cert.EnrollRequest true "Join token, host names and host keys"
+// @Success 201 {object} cert.EnrollResponse
+// @Failure 400 {object} ErrorResponse "Invalid request or failed to parse public key"
+// @Failure 401 {object} ErrorResponse "Invalid or expired join token"
+// @Failure 403 {object} ErrorResponse "Host names not allowed by the join token"
+// @Failure 409 {object} ErrorResponse "Host already enrolled"
+// @Failure 500 {object} ErrorResponse "Failed to sign host key"
+// @Router /hosts/enroll [post]
+func (a *App) Enroll(c echo.Context) error {
+	var requestBody cert.EnrollRequest
+	if err := c.Bind(&requestBody); err != nil || requestBody.Token == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	event := audit.FromContext(c)
+	event.Principals = requestBody.Hostnames
+	token, err := a.Hosts.GetJoinToken(requestBody.Token)
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{"Invalid join token"})
+	}
+	event.CA = token.CA
+	event.Details = map[string]string{"join_token": token.ID}
+	if time.Now().After(token.ExpiresAt) {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{"Join token expired"})
+	}
+
+	if len(requestBody.Hostnames) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Host certificates need at least one host name"})
+	}
+	if len(requestBody.HostKeys) == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"At least one host key is required"})
+	}
+	hostKeys, err := parseHostKeys(requestBody.HostKeys)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Failed to parse public key"})
+	}
+	if !isSubset(requestBody.Hostnames, token.Hostnames) {
+		return c.JSON(http.StatusForbidden, ErrorResponse{"Host names not allowed by the join token"})
+	}
+	// Checked before the token is used up, and again when recording the host
+	if _, err := a.Hosts.GetHost(requestBody.Hostnames[0]); err == nil && !token.Reenroll {
+		return c.JSON(http.StatusConflict, ErrorResponse{"Host already enrolled, issue a reenroll token to replace it"})
+	}
+	ca, err := a.Store.GetCAByID(token.CA)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	signer, err := a.Store.GetSignerByID(token.CA)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA signer not found"})
+	}
+	if !isSubset(requestBody.Hostnames, ca.ValidPrincipals) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Requested principals not in valid principal list"})
+	}
+
+	// Only one enrollment wins a race for the same token
+	if err := a.Hosts.RedeemJoinToken(requestBody.Token); err != nil {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{"Invalid join token"})
+	}
+
+	keys, certificates, err := signHostKeys(signer, hostKeys, requestBody.Hostnames, token.TTLMinutes, ca.Backdate())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to sign host key"})
+	}
+	host := cert.Host{
+		Name:       requestBody.Hostnames[0],
+		Hostnames:  requestBody.Hostnames,
+		CA:         ca.Name,
+		TokenID:    token.ID,
+		TTLMinutes: token.TTLMinutes,
+		EnrolledAt: time.Now().UTC().Truncate(time.Second),
+		Keys:       keys,
+	}
+	if err := a.Hosts.RecordHost(host, token.Reenroll); errors.Is(err, certStore.ErrHostExists) {
+		return c.JSON(http.StatusConflict, ErrorResponse{"Host already enrolled, issue a reenroll token to replace it"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to record host"})
+	}
+	event.Details["host"] = host.Name
+	c.Logger().Infof("Enrolled host %s with %s using join token %s", host.Name, ca.Name, token.ID)
+	return c.JSON(http.StatusCreated, cert.EnrollResponse{Host: host, Certificates: certificates})
+}
+
+// parseHostKeys parses host public keys, refusing certificates
+func parseHostKeys(authorizedKeys []string) ([]ssh.PublicKey, error) {
+	keys := []ssh.PublicKey{}
+	for _, authorizedKey := range authorizedKeys {
+		key, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := key.(*ssh.Certificate); ok {
+			return nil, errHostKeyIsCertificate
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// signHostKeys issues a host certificate for each key, returning the
+// registry entries for the keys and the certificates in authorized key format.
+func signHostKeys(signer ssh.Signer, hostKeys []ssh.PublicKey, hostnames []string, ttlMinutes int, backdate time.Duration) ([]cert.HostKey, []string, error) {
+	keys := []cert.HostKey{}
+	certificates := []string{}
+	for _, hostKey := range hostKeys {
+		signedCert, err := cert.SignHostKey(signer, hostKey, hostnames, ttlMinutes, cert.WithBackdate(backdate))
+		if err != nil {
+			return nil, nil, err
+		}
+		keys = append(keys, cert.HostKey{
+			Type:        hostKey.Type(),
+			Fingerprint: ssh.FingerprintSHA256(hostKey),
+			PublicKey:   string(ssh.MarshalAuthorizedKey(hostKey)),
+			Serial:      signedCert.Serial,
+			ValidBefore: time.Unix(int64(signedCert.ValidBefore), 0).UTC(),
+		})
+		certificates = append(certificates, string(ssh.MarshalAuthorizedKey(signedCert)))
+	}
+	return keys, certificates, nil
+}
+
+// RenewHost renews the certificate of an enrolled host's key
+// @Summary Renew a host certificate
+// @Description Sign a new certificate for an enrolled host key. Authenticated by proof of possession, a signature by the host key over the host name, key and a timestamp within five minutes of the server's clock. The TTL defaults to the one the host enrolled with, bounded by the CA's max host TTL. Hosts whose certificate has expired, or whose host names the CA no longer allows, have to enroll again.
+// @Tags Hosts
+// @Accept  json
+// @Produce  json
+// @Param renewal body cert.RenewHostRequest true "Host key and proof of possession"
+// @Success 201 {object} cert.RenewHostResponse
+// @Failure 400 {object} ErrorResponse "Invalid request or failed to parse public key"
+// @Failure 400 {object} ErrorResponse "Requested TTL longer than configured max"
+// @Failure 400 {object} ErrorResponse "Requested principals not in valid principal list"
+// @Failure 401 {object} ErrorResponse "Invalid or expired proof of possession"
+// @Failure 403 {object} ErrorResponse "Host key not enrolled, or certificate revoked or expired"
+// @Failure 404 {object} ErrorResponse "Host not found"
+// @Failure 500 {object} ErrorResponse "Failed to sign host key"
+// @Router /hosts/renew [post]
+func (a *App) RenewHost(c echo.Context) error {
+	var requestBody cert.RenewHostRequest
+	if err := c.Bind(&requestBody); err != nil || requestBody.TTLMinutes < 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	event := audit.FromContext(c)
+	event.Details = map[string]string{"host": requestBody.Name}
+	host, err := a.Hosts.GetHost(requestBody.Name)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"Host not found"})
+	}
+	event.CA = host.CA
+	event.Principals = host.Hostnames
+	publicKey, err := requestBody.VerifyProof()
+	if errors.Is(err, cert.ErrInvalidProof) {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{"Invalid proof of possession"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Failed to parse public key"})
+	}
+	signedAt := time.Unix(requestBody.Timestamp, 0)
+	if time.Since(signedAt).Abs() > RenewProofMaxAge {
+		return c.JSON(http.StatusUnauthorized, ErrorResponse{"Proof of possession expired, check the host's clock"})
+	}
+
+	var enrolledKey *cert.HostKey
+	fingerprint := ssh.FingerprintSHA256(publicKey)
+	for i := range host.Keys {
+		if host.Keys[i].Fingerprint == fingerprint {
+			enrolledKey = &host.Keys[i]
+		}
+	}
+	if enrolledKey == nil {
+		return c.JSON(http.StatusForbidden, ErrorResponse{"Host key not enrolled"})
+	}
+	// Revoking a host's certificate stops it renewing, it has to enroll again
+	if revoked, err := a.Revocations.IsRevoked(host.CA, &ssh.Certificate{Serial: enrolledKey.Serial}); err != nil || revoked {
+		return c.JSON(http.StatusForbidden, ErrorResponse{"Host certificate revoked"})
+	}
+	// A certificate that has run out is not renewed, or a stolen key would
+	// stay good for ever
+	if time.Now().After(enrolledKey.ValidBefore) {
+		return c.JSON(http.StatusForbidden, ErrorResponse{"Host certificate expired, enroll the host again"})
+	}
+
+	ca, err := a.Store.GetCAByID(host.CA)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	signer, err := a.Store.GetSignerByID(host.CA)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA signer not found"})
+	}
+	// The CA may have changed since the host enrolled
+	if !ca.IsHostCA() {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Host certificates can only be renewed by host CAs"})
+	}
+	if !isSubset(host.Hostnames, ca.ValidPrincipals) {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Requested principals not in valid principal list"})
+	}
+	ttl := requestBody.TTLMinutes
+	if ttl > ca.MaxHostTTL() {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Requested TTL longer than configured max"})
+	}
+	if ttl == 0 {
+		// The max may have been lowered since the host enrolled
+		ttl = min(host.TTLMinutes, ca.MaxHostTTL())
+	}
+
+	keys, certificates, err := signHostKeys(signer, []ssh.PublicKey{publicKey}, host.Hostnames, ttl, ca.Backdate())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to sign host key"})
+	}
+	if err := a.Hosts.UpdateHostKey(host.Name, keys[0]); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to record host"})
+	}
+	event.Serial = keys[0].Serial
+	c.Logger().Infof("Renewed %s host certificate for %s", keys[0].Type, host.Name)
+	return c.JSON(http.StatusCreated, cert.RenewHostResponse{
+		Certificate: certificates[0],
+		Serial:      keys[0].Serial,
+		ValidBefore: keys[0].ValidBefore,
+	})
+}
+
+// ListHosts lists the enrolled hosts
+// @Summary List enrolled hosts
+// @Description List the hosts in the registry, with their host keys and when their certificates expire.
+// @Tags Hosts
+// @Produce  json
+// @Success 200 {array} cert.Host
+// @Router /hosts [get]
+func (a *App) ListHosts(c echo.Context) error {
+	hosts, err := a.Hosts.ListHosts()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to list hosts"})
+	}
+	return c.JSON(http.StatusOK, hosts)
+}
+
+// GetHost retrieves an enrolled host by name
+// @Summary Get an enrolled host
+// @Description Retrieve a host from the registry by the first host name it enrolled with.
+// @Tags Hosts
+// @Produce  json
+// @Param name path string true "Host name"
+// @Success 200 {object} cert.Host
+// @Failure 404 {object} ErrorResponse "Host not found"
+// @Router /hosts/{name} [get]
+func (a *App) GetHost(c echo.Context) error {
+	host, err := a.Hosts.GetHost(c.Param("name"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"Host not found"})
+	}
+	return c.JSON(http.StatusOK, host)
+}
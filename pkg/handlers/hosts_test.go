@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/certStore"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func newHostsApp(t *testing.T) *App {
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	publicKey := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	return &App{
+		Store: &MockStore{
+			caMap: map[string]*cert.CaResponse{
+				"host-ca": {
+					CommonCa:  cert.CommonCa{Name: "host-ca", Kind: cert.HostCA, MaxTTLMinutes: 1440, ValidPrincipals: []string{"web1.example.com", "web1", "db1.example.com"}},
+					PublicKey: publicKey,
+				},
+				"user-ca": {CommonCa: cert.CommonCa{Name: "user-ca", MaxTTLMinutes: 60, ValidPrincipals: []string{"alice"}}, PublicKey: publicKey},
+			},
+			signers: map[string]ssh.Signer{"host-ca": signer, "user-ca": signer},
+		},
+		Hosts: certStore.NewInMemoryHostStore(),
+	}
+}
+
+func postHosts(e *echo.Echo, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPost, "/hosts", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func issueJoinToken(t *testing.T, app *App, body string) cert.JoinTokenResponse {
+	c, rec := postHosts(echo.New(), body)
+	var token cert.JoinTokenResponse
+	if assert.NoError(t, app.CreateJoinToken(c)) && assert.Equal(t, http.StatusCreated, rec.Code) {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &token))
+	}
+	return token
+}
+
+func TestCreateJoinTokenHandler(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		expectedCode int
+		expectedBody string
+	}{
+		{"Token for host CA", `{"ca":"host-ca","hostnames":["web1.example.com"],"ttl_minutes":1440}`, http.StatusCreated, `"ca":"host-ca"`},
+		{"Missing TTL", `{"ca":"host-ca"}`, http.StatusBadRequest, "Invalid request"},
+		{"Unknown CA", `{"ca":"nonexistent","ttl_minutes":60}`, http.StatusNotFound, "CA not found"},
+		{"User CA", `{"ca":"user-ca","ttl_minutes":60}`, http.StatusBadRequest, "Join tokens can only be issued for host CAs"},
+		{"TTL over max", `{"ca":"host-ca","ttl_minutes":1441}`, http.StatusBadRequest, "Requested TTL longer than configured max"},
+		{"No host names", `{"ca":"host-ca","ttl_minutes":60}`, http.StatusBadRequest, "Join tokens need at least one host name"},
+		{"Host name not in CA", `{"ca":"host-ca","hostnames":["evil.example.com"],"ttl_minutes":60}`, http.StatusBadRequest, "Requested principals not in valid principal list"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newHostsApp(t)
+			c, rec := postHosts(echo.New(), tt.body)
+			if assert.NoError(t, app.CreateJoinToken(c)) {
+				assert.Equal(t, tt.expectedCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestEnrollHandler(t *testing.T) {
+	e := echo.New()
+	app := newHostsApp(t)
+	token := issueJoinToken(t, app, `{"ca":"host-ca","hostnames":["web1.example.com","web1"],"ttl_minutes":1440}`)
+
+	enroll := func(body cert.EnrollRequest) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(body)
+		c, rec := postHosts(e, string(b))
+		assert.NoError(t, app.Enroll(c))
+		return rec
+	}
+
+	rec := enroll(cert.EnrollRequest{Token: "wrong", Hostnames: []string{"web1.example.com"}, HostKeys: []string{testPublicKey}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Rejected requests leave the token usable
+	rec = enroll(cert.EnrollRequest{Token: token.Token, Hostnames: []string{"db1.example.com"}, HostKeys: []string{testPublicKey}})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	rec = enroll(cert.EnrollRequest{Token: token.Token, Hostnames: []string{"web1.example.com"}, HostKeys: []string{"not a key"}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = enroll(cert.EnrollRequest{Token: token.Token, Hostnames: []string{"web1.example.com", "web1"}, HostKeys: []string{testPublicKey}})
+	if assert.Equal(t, http.StatusCreated, rec.Code) {
+		var response cert.EnrollResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		if assert.Len(t, response.Certificates, 1) {
+			hostCert, err := cert.ParseCertificate([]byte(response.Certificates[0]))
+			assert.NoError(t, err)
+			assert.Equal(t, uint32(ssh.HostCert), hostCert.CertType)
+			assert.Equal(t, []string{"web1.example.com", "web1"}, hostCert.ValidPrincipals)
+			assert.Equal(t, hostCert.Serial, response.Host.Keys[0].Serial)
+		}
+		assert.Equal(t, token.ID, response.Host.TokenID)
+	}
+
+	// Tokens are single use
+	rec = enroll(cert.EnrollRequest{Token: token.Token, Hostnames: []string{"web1.example.com"}, HostKeys: []string{testPublicKey}})
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The host is in the registry
+	req := httptest.NewRequest(http.MethodGet, "/hosts/web1.example.com", nil)
+	rec = httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("name")
+	c.SetParamValues("web1.example.com")
+	if assert.NoError(t, app.GetHost(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"ca":"host-ca"`)
+	}
+	rec = httptest.NewRecorder()
+	if assert.NoError(t, app.ListHosts(e.NewContext(httptest.NewRequest(http.MethodGet, "/hosts", nil), rec))) {
+		assert.Contains(t, rec.Body.String(), `"name":"web1.example.com"`)
+	}
+}
+
+func TestEnrollExistingHost(t *testing.T) {
+	e := echo.New()
+	app := newHostsApp(t)
+	enroll := func(token cert.JoinTokenResponse, hostKey string) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(cert.EnrollRequest{Token: token.Token, Hostnames: []string{"web1.example.com"}, HostKeys: []string{hostKey}})
+		c, rec := postHosts(e, string(b))
+		assert.NoError(t, app.Enroll(c))
+		return rec
+	}
+	otherKey, _ := createMockSigner()
+	otherPublicKey := string(ssh.MarshalAuthorizedKey(otherKey.PublicKey()))
+
+	rec := enroll(issueJoinToken(t, app, `{"ca":"host-ca","hostnames":["web1.example.com"],"ttl_minutes":60}`), testPublicKey)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	enrolled, _ := app.Hosts.GetHost("web1.example.com")
+
+	// Another token for the same host does not replace it
+	token := issueJoinToken(t, app, `{"ca":"host-ca","hostnames":["web1.example.com"],"ttl_minutes":60}`)
+	rec = enroll(token, otherPublicKey)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Host already enrolled")
+	host, _ := app.Hosts.GetHost("web1.example.com")
+	assert.Equal(t, enrolled.Keys, host.Keys)
+	_, err := app.Hosts.GetJoinToken(token.Token)
+	assert.NoError(t, err, "Expected the refused token to stay usable")
+
+	// A reenroll token does
+	rec = enroll(issueJoinToken(t, app, `{"ca":"host-ca","hostnames":["web1.example.com"],"ttl_minutes":60,"reenroll":true}`), otherPublicKey)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	host, _ = app.Hosts.GetHost("web1.example.com")
+	if assert.Len(t, host.Keys, 1) {
+		assert.Equal(t, ssh.FingerprintSHA256(otherKey.PublicKey()), host.Keys[0].Fingerprint)
+	}
+}
+
+func TestEnrollExpiredJoinToken(t *testing.T) {
+	app := newHostsApp(t)
+	assert.NoError(t, app.Hosts.AddJoinToken("expired", certStore.JoinToken{
+		ID: certStore.JoinTokenID("expired"), CA: "host-ca", TTLMinutes: 60, ExpiresAt: time.Now().Add(-time.Minute),
+	}))
+	c, rec := postHosts(echo.New(), `{"token":"expired","hostnames":["web1"],"host_keys":["`+testPublicKey+`"]}`)
+	if assert.NoError(t, app.Enroll(c)) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Join token expired")
+	}
+}
+
+func TestRenewHostHandler(t *testing.T) {
+	e := echo.New()
+	app := newHostsApp(t)
+	app.Revocations = certStore.NewInMemoryRevocationStore()
+	_, hostPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	hostKey, _ := ssh.NewSignerFromKey(hostPrivateKey)
+
+	token := issueJoinToken(t, app, `{"ca":"host-ca","hostnames":["web1.example.com"],"ttl_minutes":600}`)
+	b, _ := json.Marshal(cert.EnrollRequest{
+		Token: token.Token, Hostnames: []string{"web1.example.com"}, HostKeys: []string{string(ssh.MarshalAuthorizedKey(hostKey.PublicKey()))},
+	})
+	c, rec := postHosts(e, string(b))
+	assert.NoError(t, app.Enroll(c))
+	assert.Equal(t, http.StatusCreated, rec.Code)
+
+	renew := func(request *cert.RenewHostRequest) *httptest.ResponseRecorder {
+		b, _ := json.Marshal(request)
+		c, rec := postHosts(e, string(b))
+		assert.NoError(t, app.RenewHost(c))
+		return rec
+	}
+	newRequest := func(signer ssh.Signer, name string, ttl int, at time.Time) *cert.RenewHostRequest {
+		request, err := cert.NewRenewHostRequest(signer, name, ttl, at)
+		assert.NoError(t, err)
+		return request
+	}
+
+	rec = renew(newRequest(hostKey, "web1.example.com", 0, time.Now()))
+	var renewed cert.RenewHostResponse
+	if assert.Equal(t, http.StatusCreated, rec.Code) {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &renewed))
+		hostCert, err := cert.ParseCertificate([]byte(renewed.Certificate))
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(ssh.HostCert), hostCert.CertType)
+		assert.Equal(t, []string{"web1.example.com"}, hostCert.ValidPrincipals)
+		assert.Equal(t, uint64(600*60), hostCert.ValidBefore-hostCert.ValidAfter)
+		host, _ := app.Hosts.GetHost("web1.example.com")
+		assert.Equal(t, renewed.Serial, host.Keys[0].Serial)
+	}
+
+	// The signature has to come from the enrolled key
+	otherKey, _ := createMockSigner()
+	rec = renew(newRequest(otherKey, "web1.example.com", 0, time.Now()))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	forged := newRequest(otherKey, "web1.example.com", 0, time.Now())
+	forged.PublicKey = string(ssh.MarshalAuthorizedKey(hostKey.PublicKey()))
+	rec = renew(forged)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// Old proofs are refused
+	rec = renew(newRequest(hostKey, "web1.example.com", 0, time.Now().Add(-time.Hour)))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	rec = renew(newRequest(hostKey, "web1.example.com", 1441, time.Now()))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = renew(newRequest(hostKey, "db1.example.com", 0, time.Now()))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Host names the CA no longer allows are not renewed
+	store := app.Store.(*MockStore)
+	store.caMap["host-ca"].ValidPrincipals = []string{"web1", "db1.example.com"}
+	rec = renew(newRequest(hostKey, "web1.example.com", 0, time.Now()))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Requested principals not in valid principal list")
+	store.caMap["host-ca"].ValidPrincipals = []string{"web1.example.com", "web1", "db1.example.com"}
+
+	// Nor are certificates that have expired
+	host, _ := app.Hosts.GetHost("web1.example.com")
+	expired := host.Keys[0]
+	expired.ValidBefore = time.Now().Add(-time.Minute)
+	assert.NoError(t, app.Hosts.UpdateHostKey("web1.example.com", expired))
+	rec = renew(newRequest(hostKey, "web1.example.com", 0, time.Now()))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Host certificate expired")
+	assert.NoError(t, app.Hosts.UpdateHostKey("web1.example.com", host.Keys[0]))
+
+	// Revoked hosts have to enroll again
+	assert.NoError(t, app.Revocations.Revoke("host-ca", renewed.Serial))
+	rec = renew(newRequest(hostKey, "web1.example.com", 0, time.Now()))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Host certificate revoked")
+}
+
+func TestMaxHostTTL(t *testing.T) {
+	app := newHostsApp(t)
+	store := app.Store.(*MockStore)
+	store.caMap["host-ca"].MaxTTLMinutes = 60
+	store.caMap["host-ca"].MaxHostTTLMinutes = 43200
+
+	// Host certificates are bounded by the max host TTL instead of the max TTL
+	c, rec := postHosts(echo.New(), `{"ca":"host-ca","hostnames":["web1"],"ttl_minutes":43200}`)
+	if assert.NoError(t, app.CreateJoinToken(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+	c, rec = postHosts(echo.New(), `{"ca":"host-ca","hostnames":["web1"],"ttl_minutes":43201}`)
+	if assert.NoError(t, app.CreateJoinToken(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	}
+	c, rec = postJSON(echo.New(), "host-ca", `{"public_key":"`+testPublicKey+`","principals":["web1"],"ttl_minutes":43200}`)
+	if assert.NoError(t, app.Sign(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+}
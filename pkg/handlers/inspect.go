@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"net/http"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"golang.org/x/crypto/ssh"
+)
+
+// InspectCert describes the contents of a certificate
+// @Summary Inspect a certificate
+// @Description Parse a SSH certificate and report its contents, including whether it was signed by a CA in the store.
+// @Tags Certificates
+// @Accept  json
+// @Produce  json
+// @Param certificate body cert.InspectRequest true "Certificate to inspect"
+// @Success 200 {object} cert.InspectResponse
+// @Failure 400 {object} ErrorResponse "Invalid request or failed to parse certificate"
+// @Router /certs/inspect [post]
+func (a *App) InspectCert(c echo.Context) error {
+	var requestBody cert.InspectRequest
+	if err := c.Bind(&requestBody); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+
+	parsedCert, err := cert.ParseCertificate([]byte(requestBody.Certificate))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Failed to parse certificate"})
+	}
+
+	info := cert.Inspect(parsedCert)
+	info.MatchedCA = a.findSigningCA(parsedCert)
+	return c.JSON(http.StatusOK, info)
+}
+
+// findSigningCA returns the name of the CA whose current or retiring key
+// signed the certificate, or an empty string when no CA in the store matches.
+func (a *App) findSigningCA(signedCert *ssh.Certificate) string {
+	caList, err := a.Store.ListCAs()
+	if err != nil {
+		return ""
+	}
+	for _, ca := range caList {
+		caKeys, err := ca.TrustedKeys()
+		if err != nil {
+			continue
+		}
+		for _, caKey := range caKeys {
+			if cert.SignedBy(signedCert, caKey) {
+				return ca.Name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestInspectCertHandler(t *testing.T) {
+	e := echo.New()
+
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	unknownSigner, err := cert.GenerateSSHKey(cert.ED25519, 0)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	retiringSigner, err := cert.GenerateSSHKey(cert.ED25519, 0)
+	if err != nil {
+		t.Fatalf("Failed to create signer: %v", err)
+	}
+	userKey, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(testPublicKey))
+
+	mockStore := &MockStore{
+		caMap: map[string]*cert.CaResponse{
+			"test-ca": {
+				CommonCa:           cert.CommonCa{Name: "test-ca"},
+				PublicKey:          string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+				RetiringPublicKeys: []string{string(ssh.MarshalAuthorizedKey(retiringSigner.PublicKey()))},
+			},
+		},
+	}
+	app := &App{Store: mockStore}
+
+	knownCert, _ := cert.SignUserKey(signer, userKey, []string{"user1"}, 30)
+	unknownCert, _ := cert.SignUserKey(unknownSigner, userKey, []string{"user1"}, 30)
+	retiringCert, _ := cert.SignUserKey(retiringSigner, userKey, []string{"user1"}, 30)
+	// Signed by an unknown key but naming the CA's key as its signer
+	forgedCert, _ := cert.SignUserKey(unknownSigner, userKey, []string{"user1"}, 30)
+	forgedCert.SignatureKey = signer.PublicKey()
+
+	tests := []struct {
+		name           string
+		certificate    string
+		expectedStatus int
+		expectedCA     string
+	}{
+		{"Known CA", string(ssh.MarshalAuthorizedKey(knownCert)), http.StatusOK, "test-ca"},
+		{"Unknown CA", string(ssh.MarshalAuthorizedKey(unknownCert)), http.StatusOK, ""},
+		{"Retiring CA key", string(ssh.MarshalAuthorizedKey(retiringCert)), http.StatusOK, "test-ca"},
+		{"Forged signer", string(ssh.MarshalAuthorizedKey(forgedCert)), http.StatusOK, ""},
+		{"Not a certificate", testPublicKey, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqBody, _ := json.Marshal(cert.InspectRequest{Certificate: tt.certificate})
+			req := httptest.NewRequest(http.MethodPost, "/certs/inspect", strings.NewReader(string(reqBody)))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			err := app.InspectCert(c)
+			if assert.NoError(t, err) {
+				assert.Equal(t, tt.expectedStatus, rec.Code)
+				if tt.expectedStatus != http.StatusOK {
+					assert.Contains(t, rec.Body.String(), "Failed to parse certificate")
+					return
+				}
+				var info cert.InspectResponse
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+				assert.Equal(t, tt.expectedCA, info.MatchedCA)
+				assert.Equal(t, []string{"user1"}, info.Principals)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"net/http"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/krl"
+)
+
+// KRL returns the revoked certificates of a specific CA as an OpenSSH KRL
+// @Summary Get a CA's key revocation list
+// @Description Get the certificates revoked by a CA in the OpenSSH KRL format, for use with sshd's RevokedKeys.
+// @Tags CAs
+// @Produce  octet-stream
+// @Param id path string true "CA ID"
+// @Success 200 {file} binary "OpenSSH KRL"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Failure 500 {object} ErrorResponse "Failed to build KRL"
+// @Router /CA/{id}/krl [get]
+func (a *App) KRL(c echo.Context) error {
+	CaID := c.Param("id")
+	ca, err := a.Store.GetCAByID(CaID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	list, err := a.buildKRL([]*cert.CaResponse{ca})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to build KRL"})
+	}
+	return cachedBlob(c, "application/octet-stream", list.Marshal())
+}
+
+// TrustKRL returns the revoked certificates of the selected user CAs as an OpenSSH KRL
+// @Summary Get a KRL for the selected user CAs
+// @Description Get the certificates revoked by the selected user CAs, including under retiring keys, as a single OpenSSH KRL for sshd's RevokedKeys. Responses carry an ETag, send it back in If-None-Match to get a 304 when nothing changed.
+// @Tags Trust
+// @Produce  octet-stream
+// @Param tag query string false "Only include CAs with this tag"
+// @Param ca query []string false "Only include these CAs, comma separated or repeated" collectionFormat(multi)
+// @Success 200 {file} binary "OpenSSH KRL"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Failure 500 {object} ErrorResponse "Failed to build KRL"
+// @Router /trust/krl [get]
+func (a *App) TrustKRL(c echo.Context) error {
+	cas, err := a.selectCAs(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{err.Error()})
+	}
+	userCAs := []*cert.CaResponse{}
+	for _, ca := range cas {
+		if !ca.IsHostCA() {
+			userCAs = append(userCAs, ca)
+		}
+	}
+	list, err := a.buildKRL(userCAs)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to build KRL"})
+	}
+	return cachedBlob(c, "application/octet-stream", list.Marshal())
+}
+
+// buildKRL revokes each CA's revoked serials under all of its keys. The
+// KRL only changes when revocations do, so it can be cached by ETag.
+func (a *App) buildKRL(cas []*cert.CaResponse) (*krl.KRL, error) {
+	list := &krl.KRL{Comment: "sshtrust"}
+	for _, ca := range cas {
+		caKeys, err := ca.TrustedKeys()
+		if err != nil {
+			return nil, err
+		}
+		serials, err := a.Revocations.ListRevoked(ca.Name)
+		if err != nil {
+			return nil, err
+		}
+		// Revocations only ever grow, so their count orders KRL versions
+		list.Version += uint64(len(serials))
+		// Serials are per CA, so they are revoked under retiring keys as well
+		for _, key := range caKeys {
+			list.Certificates = append(list.Certificates, krl.CertificateSection{CAKey: key, Serials: serials})
+		}
+	}
+	return list, nil
+}
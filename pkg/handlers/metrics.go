@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"time"
+)
+
+// CACount returns how many CAs there are, for the metrics
+func (a *App) CACount() int {
+	cas, err := a.Store.ListCAs()
+	if err != nil {
+		return 0
+	}
+	return len(cas)
+}
+
+// ExpiringHostCerts returns how many enrolled host certificates expire
+// within window, counting those already expired, for the metrics
+func (a *App) ExpiringHostCerts(window time.Duration) int {
+	hosts, err := a.Hosts.ListHosts()
+	if err != nil {
+		return 0
+	}
+	deadline := time.Now().Add(window)
+	expiring := 0
+	for _, host := range hosts {
+		for _, key := range host.Keys {
+			if key.ValidBefore.Before(deadline) {
+				expiring++
+			}
+		}
+	}
+	return expiring
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignMetrics(t *testing.T) {
+	app := newRequestsApp(t)
+	app.Metrics = metrics.New(metrics.Sources{CAs: app.CACount})
+
+	for _, tt := range []struct {
+		caID string
+		body string
+	}{
+		{"prod-ca", `{"public_key":"` + testPublicKey + `","principals":["deploy"],"ttl_minutes":30}`},
+		{"prod-ca", `{"public_key":"` + testPublicKey + `","principals":["deploy"],"ttl_minutes":30}`},
+		{"prod-ca", `{"public_key":"` + testPublicKey + `","principals":["deploy"],"ttl_minutes":5000}`},
+		{"prod-ca", `{"public_key":"` + testPublicKey + `","principals":["nobody"],"ttl_minutes":30}`},
+		{"prod-ca", `{"public_key":"not a key","principals":["deploy"],"ttl_minutes":30}`},
+		{"prod-ca", `{"public_key":"` + testPublicKey + `","principals":["root"],"ttl_minutes":30}`},
+		{"missing-ca", `{"public_key":"` + testPublicKey + `","principals":["deploy"],"ttl_minutes":30}`},
+	} {
+		c, _ := postJSON(echo.New(), tt.caID, tt.body)
+		assert.NoError(t, app.Sign(c))
+	}
+
+	rec := httptest.NewRecorder()
+	app.Metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	assert.Contains(t, body, `sshtrust_sign_requests_total{ca="prod-ca",outcome="issued"} 2`)
+	assert.Contains(t, body, `sshtrust_sign_requests_total{ca="prod-ca",outcome="ttl_too_long"} 1`)
+	assert.Contains(t, body, `sshtrust_sign_requests_total{ca="prod-ca",outcome="invalid_principals"} 1`)
+	assert.Contains(t, body, `sshtrust_sign_requests_total{ca="prod-ca",outcome="parse_failed"} 1`)
+	assert.Contains(t, body, `sshtrust_sign_requests_total{ca="prod-ca",outcome="needs_approval"} 1`)
+	assert.Contains(t, body, `sshtrust_sign_requests_total{ca="",outcome="ca_not_found"} 1`)
+	assert.NotContains(t, body, "missing-ca")
+	assert.Contains(t, body, "sshtrust_cas 1")
+}
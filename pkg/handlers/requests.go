@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/auth"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/certStore"
+	"golang.org/x/crypto/ssh"
+)
+
+// CreateSigningRequest submits a signing request for approval
+// @Summary Request a certificate that needs approval
+// @Description Submit a signing request to the CA. Requests matching one of the CA's approval rules wait for approval and are signed once enough approvers have approved them, other requests are signed straight away. The request is checked as it would be by Sign.
+// @Tags Requests
+// @Accept  json
+// @Produce  json
+// @Param id path string true "CA ID"
+// @Param request body cert.CreateSigningRequest true "Public key to be signed and the reason for the request"
+// @Success 201 {object} cert.SigningRequest
+// @Failure 400 {object} ErrorResponse "Invalid request or failed to parse public key"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Failure 500 {object} ErrorResponse "Failed to sign public key"
+// @Router /CA/{id}/requests [post]
+func (a *App) CreateSigningRequest(c echo.Context) error {
+	CaID := c.Param("id")
+	event := audit.FromContext(c)
+	event.CA = CaID
+	ca, err := a.Store.GetCAByID(CaID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	var requestBody cert.CreateSigningRequest
+	if err := c.Bind(&requestBody); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	event.Principals = requestBody.Principals
+
+	now := time.Now()
+	plan, rejected := planSign(ca, requestBody.SignRequest, now)
+	if rejected != nil {
+		return c.JSON(rejected.status, ErrorResponse{rejected.message})
+	}
+	required, approvers, err := ca.ApprovalsRequired(plan.principals, plan.ttl, auth.Username(c))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Not enough approvers are allowed by every matching approval rule"})
+	}
+	id, err := newRequestID()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to create signing request"})
+	}
+	request := cert.SigningRequest{
+		ID:                id,
+		CA:                ca.Name,
+		Requester:         auth.Username(c),
+		Request:           requestBody.SignRequest,
+		Reason:            requestBody.Reason,
+		Status:            cert.RequestPending,
+		CreatedAt:         now.UTC().Truncate(time.Second),
+		ApprovalsRequired: required,
+		Approvers:         approvers,
+	}
+	if required == 0 {
+		signer, err := a.Store.GetSignerByID(CaID)
+		if err != nil {
+			return c.JSON(http.StatusNotFound, ErrorResponse{"CA signer not found"})
+		}
+		if err := issueRequest(&request, plan, signer); err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to sign public key"})
+		}
+	}
+	event.Serial = request.Serial
+	event.Details = map[string]string{"request": request.ID, "status": string(request.Status)}
+	if err := a.Requests.AddRequest(request); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to create signing request"})
+	}
+	c.Logger().Infof("Signing request %s for %s is %s", request.ID, CaID, request.Status)
+	return c.JSON(http.StatusCreated, request)
+}
+
+// ListSigningRequests lists signing requests
+// @Summary List signing requests
+// @Description List signing requests oldest first, e.g. the pending requests waiting for approval.
+// @Tags Requests
+// @Produce  json
+// @Param status query string false "Only requests with this status: pending, approved or denied"
+// @Param ca query string false "Only requests to this CA"
+// @Success 200 {array} cert.SigningRequest
+// @Failure 500 {object} ErrorResponse "Failed to list signing requests"
+// @Router /requests [get]
+func (a *App) ListSigningRequests(c echo.Context) error {
+	requests, err := a.Requests.ListRequests(cert.SigningRequestStatus(c.QueryParam("status")))
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to list signing requests"})
+	}
+	if caID := c.QueryParam("ca"); caID != "" {
+		filtered := []cert.SigningRequest{}
+		for _, request := range requests {
+			if request.CA == caID {
+				filtered = append(filtered, request)
+			}
+		}
+		requests = filtered
+	}
+	return c.JSON(http.StatusOK, requests)
+}
+
+// GetSigningRequest retrieves a signing request
+// @Summary Get a signing request
+// @Description Retrieve a signing request, with its certificate once it is approved.
+// @Tags Requests
+// @Produce  json
+// @Param id path string true "Request ID"
+// @Success 200 {object} cert.SigningRequest
+// @Failure 404 {object} ErrorResponse "Signing request not found"
+// @Router /requests/{id} [get]
+func (a *App) GetSigningRequest(c echo.Context) error {
+	request, err := a.Requests.GetRequest(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"Signing request not found"})
+	}
+	return c.JSON(http.StatusOK, request)
+}
+
+// ApproveSigningRequest approves a pending signing request
+// @Summary Approve a signing request
+// @Description Approve a pending signing request. Approvers need a login, can not approve their own requests and are limited by the CA's approval rules. The request is signed once it has all the approvals it needs, checked against the CA as it is then.
+// @Tags Requests
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Request ID"
+// @Param review body cert.ReviewRequest false "Comment on the approval"
+// @Success 200 {object} cert.SigningRequest
+// @Failure 400 {object} ErrorResponse "Request can no longer be signed"
+// @Failure 403 {object} ErrorResponse "Not allowed to review the request"
+// @Failure 404 {object} ErrorResponse "Signing request not found"
+// @Failure 409 {object} ErrorResponse "Request already reviewed"
+// @Router /requests/{id}/approve [post]
+func (a *App) ApproveSigningRequest(c echo.Context) error {
+	return a.reviewSigningRequest(c, func(request *cert.SigningRequest, review cert.Review) error {
+		if request.ApprovedBy(review.By) {
+			return &requestError{http.StatusConflict, "Request already approved by " + review.By}
+		}
+		request.Approvals = append(request.Approvals, review)
+		if len(request.Approvals) < request.ApprovalsRequired {
+			return nil
+		}
+		ca, err := a.Store.GetCAByID(request.CA)
+		if err != nil {
+			return &requestError{http.StatusNotFound, "CA not found"}
+		}
+		signer, err := a.Store.GetSignerByID(request.CA)
+		if err != nil {
+			return &requestError{http.StatusNotFound, "CA signer not found"}
+		}
+		// The CA may have changed since the request was made
+		plan, rejected := planSign(ca, request.Request, review.At)
+		if rejected != nil {
+			return &requestError{http.StatusBadRequest, "Request can no longer be signed: " + rejected.message}
+		}
+		if err := issueRequest(request, plan, signer); err != nil {
+			return &requestError{http.StatusInternalServerError, "Failed to sign public key"}
+		}
+		return nil
+	})
+}
+
+// DenySigningRequest denies a pending signing request
+// @Summary Deny a signing request
+// @Description Deny a pending signing request, it will not be signed. Anyone who may approve the request may deny it.
+// @Tags Requests
+// @Accept  json
+// @Produce  json
+// @Param id path string true "Request ID"
+// @Param review body cert.ReviewRequest false "Why the request is denied"
+// @Success 200 {object} cert.SigningRequest
+// @Failure 403 {object} ErrorResponse "Not allowed to review the request"
+// @Failure 404 {object} ErrorResponse "Signing request not found"
+// @Failure 409 {object} ErrorResponse "Request already reviewed"
+// @Router /requests/{id}/deny [post]
+func (a *App) DenySigningRequest(c echo.Context) error {
+	return a.reviewSigningRequest(c, func(request *cert.SigningRequest, review cert.Review) error {
+		request.Status = cert.RequestDenied
+		request.Denial = &review
+		return nil
+	})
+}
+
+// reviewSigningRequest applies a review by the logged in user to a pending
+// request, once they are checked as an approver
+func (a *App) reviewSigningRequest(c echo.Context, apply func(*cert.SigningRequest, cert.Review) error) error {
+	var requestBody cert.ReviewRequest
+	if err := c.Bind(&requestBody); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	review := cert.Review{By: auth.Username(c), At: time.Now().UTC().Truncate(time.Second), Comment: requestBody.Comment}
+	event := audit.FromContext(c)
+	event.Details = map[string]string{"request": c.Param("id")}
+
+	updated, err := a.Requests.UpdateRequest(c.Param("id"), func(request *cert.SigningRequest) error {
+		switch {
+		case request.Status != cert.RequestPending:
+			return &requestError{http.StatusConflict, "Request already " + string(request.Status)}
+		case review.By == "":
+			return &requestError{http.StatusForbidden, "Reviewing signing requests needs a login"}
+		case review.By == request.Requester:
+			return &requestError{http.StatusForbidden, "Requesters can not review their own requests"}
+		case !request.CanReview(review.By):
+			return &requestError{http.StatusForbidden, review.By + " is not an approver for this request"}
+		}
+		event.CA = request.CA
+		event.Principals = request.Request.Principals
+		return apply(request, review)
+	})
+	var rejected *requestError
+	switch {
+	case errors.Is(err, certStore.ErrRequestNotFound):
+		return c.JSON(http.StatusNotFound, ErrorResponse{"Signing request not found"})
+	case errors.As(err, &rejected):
+		return c.JSON(rejected.status, ErrorResponse{rejected.message})
+	case err != nil:
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to review signing request"})
+	}
+	event.Serial = updated.Serial
+	event.Details["status"] = string(updated.Status)
+	c.Logger().Infof("Signing request %s reviewed by %s, %s", updated.ID, review.By, updated.Status)
+	return c.JSON(http.StatusOK, updated)
+}
+
+// issueRequest signs the planned certificate and marks the request approved
+func issueRequest(request *cert.SigningRequest, plan *signPlan, signer ssh.Signer) error {
+	signedCert, err := plan.sign(signer)
+	if err != nil {
+		return err
+	}
+	request.Status = cert.RequestApproved
+	request.SignedKey = string(ssh.MarshalAuthorizedKey(signedCert))
+	request.Serial = signedCert.Serial
+	return nil
+}
+
+func newRequestID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/certStore"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func newRequestsApp(t *testing.T) *App {
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	return &App{
+		Store: &MockStore{
+			caMap: map[string]*cert.CaResponse{
+				"prod-ca": {CommonCa: cert.CommonCa{
+					Name: "prod-ca", MaxTTLMinutes: 1440, ValidPrincipals: []string{"root", "deploy", "alice"},
+					ApprovalRules: []cert.ApprovalRule{
+						{Principals: []string{"root"}, Approvals: 2, Approvers: []string{"alice", "bob", "carol", "dave"}},
+						{TTLOverMinutes: 480, Approvers: []string{"carol", "dave"}},
+						{Principals: []string{"alice"}, Approvers: []string{"erin"}},
+					},
+					BreakGlassResponders: []string{"alice"},
+				}},
+			},
+			signers: map[string]ssh.Signer{"prod-ca": signer},
+		},
+		Requests: certStore.NewInMemorySigningRequestStore(),
+	}
+}
+
+// requestAs makes a request as the logged in user, as echojwt leaves it
+func requestAs(user, method, target, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if user != "" {
+		c.Set("user", jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user": user}))
+	}
+	return c, rec
+}
+
+func submitRequest(t *testing.T, app *App, user, body string) (cert.SigningRequest, *httptest.ResponseRecorder) {
+	c, rec := requestAs(user, http.MethodPost, "/CA/prod-ca/requests", body)
+	c.SetParamNames("id")
+	c.SetParamValues("prod-ca")
+	var request cert.SigningRequest
+	if assert.NoError(t, app.CreateSigningRequest(c)) && rec.Code == http.StatusCreated {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &request))
+	}
+	return request, rec
+}
+
+func reviewRequest(t *testing.T, app *App, user, id, action string) (cert.SigningRequest, *httptest.ResponseRecorder) {
+	c, rec := requestAs(user, http.MethodPost, "/requests/"+id+"/"+action, `{"comment":"ok"}`)
+	c.SetParamNames("id")
+	c.SetParamValues(id)
+	handler := app.ApproveSigningRequest
+	if action == "deny" {
+		handler = app.DenySigningRequest
+	}
+	var request cert.SigningRequest
+	if assert.NoError(t, handler(c)) && rec.Code == http.StatusOK {
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &request))
+	}
+	return request, rec
+}
+
+func TestSignNeedsApproval(t *testing.T) {
+	app := newRequestsApp(t)
+	c, rec := postJSON(echo.New(), "prod-ca", `{"public_key":"`+testPublicKey+`","principals":["root"],"ttl_minutes":30}`)
+	if assert.NoError(t, app.Sign(c)) {
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		assert.Contains(t, rec.Body.String(), "needs approval")
+	}
+	c, rec = postJSON(echo.New(), "prod-ca", `{"public_key":"`+testPublicKey+`","principals":["deploy"],"ttl_minutes":30}`)
+	if assert.NoError(t, app.Sign(c)) {
+		assert.Equal(t, http.StatusCreated, rec.Code)
+	}
+}
+
+func TestApproveSigningRequest(t *testing.T) {
+	app := newRequestsApp(t)
+	request, rec := submitRequest(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["root"],"ttl_minutes":30,"reason":"INC-42"}`)
+	if !assert.Equal(t, http.StatusCreated, rec.Code) {
+		return
+	}
+	assert.Equal(t, cert.RequestPending, request.Status)
+	assert.Equal(t, "alice", request.Requester)
+	assert.Equal(t, 2, request.ApprovalsRequired)
+	assert.Empty(t, request.SignedKey)
+
+	_, rec = reviewRequest(t, app, "alice", request.ID, "approve")
+	assert.Equal(t, http.StatusForbidden, rec.Code, "Requesters can not approve their own requests")
+	_, rec = reviewRequest(t, app, "", request.ID, "approve")
+	assert.Equal(t, http.StatusForbidden, rec.Code, "Approvals need a login")
+
+	approved, rec := reviewRequest(t, app, "bob", request.ID, "approve")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, cert.RequestPending, approved.Status)
+	_, rec = reviewRequest(t, app, "bob", request.ID, "approve")
+	assert.Equal(t, http.StatusConflict, rec.Code, "Each user approves once")
+
+	// The last approval signs the request
+	approved, rec = reviewRequest(t, app, "carol", request.ID, "approve")
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, cert.RequestApproved, approved.Status)
+		assert.Len(t, approved.Approvals, 2)
+		signed, err := cert.ParseCertificate([]byte(approved.SignedKey))
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"root"}, signed.ValidPrincipals)
+			assert.Equal(t, approved.Serial, signed.Serial)
+		}
+	}
+	_, rec = reviewRequest(t, app, "dave", request.ID, "deny")
+	assert.Equal(t, http.StatusConflict, rec.Code, "Approved requests can not be denied")
+
+	c, rec := requestAs("alice", http.MethodGet, "/requests/"+request.ID, "")
+	c.SetParamNames("id")
+	c.SetParamValues(request.ID)
+	if assert.NoError(t, app.GetSigningRequest(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"status":"approved"`)
+	}
+}
+
+func TestApprovalRuleApprovers(t *testing.T) {
+	app := newRequestsApp(t)
+	request, _ := submitRequest(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["deploy"],"ttl_minutes":600}`)
+	assert.Equal(t, []string{"carol", "dave"}, request.Approvers)
+
+	_, rec := reviewRequest(t, app, "bob", request.ID, "approve")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "not an approver")
+
+	denied, rec := reviewRequest(t, app, "dave", request.ID, "deny")
+	if assert.Equal(t, http.StatusOK, rec.Code) {
+		assert.Equal(t, cert.RequestDenied, denied.Status)
+		assert.Equal(t, "dave", denied.Denial.By)
+		assert.Empty(t, denied.SignedKey)
+	}
+	_, rec = reviewRequest(t, app, "carol", request.ID, "approve")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// Root for longer than eight hours needs two approvals both rules allow
+	_, rec = submitRequest(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["root"],"ttl_minutes":600}`)
+	if assert.Equal(t, http.StatusCreated, rec.Code) {
+		assert.Contains(t, rec.Body.String(), `"approvals_required":2`)
+	}
+	// Rules whose approvers have no names in common leave nobody to approve
+	_, rec = submitRequest(t, app, "bob", `{"public_key":"`+testPublicKey+`","principals":["alice"],"ttl_minutes":600}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Not enough approvers")
+	// Nor can requesters count themselves
+	_, rec = submitRequest(t, app, "erin", `{"public_key":"`+testPublicKey+`","principals":["alice"],"ttl_minutes":30}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestSigningRequestWithoutApproval(t *testing.T) {
+	app := newRequestsApp(t)
+	request, rec := submitRequest(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["deploy"],"ttl_minutes":30}`)
+	if assert.Equal(t, http.StatusCreated, rec.Code) {
+		assert.Equal(t, cert.RequestApproved, request.Status)
+		assert.NotEmpty(t, request.SignedKey)
+	}
+	_, rec = submitRequest(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["mallory"],"ttl_minutes":30}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	_, rec = reviewRequest(t, app, "bob", "missing", "approve")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestListSigningRequests(t *testing.T) {
+	app := newRequestsApp(t)
+	submitRequest(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["deploy"],"ttl_minutes":30}`)
+	pending, _ := submitRequest(t, app, "alice", `{"public_key":"`+testPublicKey+`","principals":["root"],"ttl_minutes":30}`)
+
+	c, rec := requestAs("bob", http.MethodGet, "/requests?status=pending", "")
+	if assert.NoError(t, app.ListSigningRequests(c)) {
+		var requests []cert.SigningRequest
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &requests))
+		if assert.Len(t, requests, 1) {
+			assert.Equal(t, pending.ID, requests[0].ID)
+		}
+	}
+	c, rec = requestAs("bob", http.MethodGet, "/requests?ca=other-ca", "")
+	if assert.NoError(t, app.ListSigningRequests(c)) {
+		assert.JSONEq(t, `[]`, rec.Body.String())
+	}
+}
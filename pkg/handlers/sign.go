@@ -1,15 +1,20 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
+	"time"
+
 	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
 	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/metrics"
 	"golang.org/x/crypto/ssh"
-	"net/http"
 )
 
 // Sign a public key using a specific CA
 // @Summary Sign a public key with a specific CA
-// @Description Use the specified CA to sign a provided public key and return the signed key.
+// @Description Use the specified CA to sign a provided public key and return the signed key. Host CAs sign host certificates, with the principals as host names. User keys have to pass the CA's key policy. Certificates start now, backdated by the CA's allowance, unless valid_after schedules them; valid_before can replace ttl_minutes. The window is bounded by the CA's max TTL and max future start.
 // @Tags CAs
 // @Accept  json
 // @Produce  json
@@ -17,17 +22,26 @@ import (
 // @Param public_key body cert.SignRequest true "Public key to be signed"
 // @Success 201 {object} cert.SignResponse "The signed public key will be returned under the 'signed_key' field"
 // @Failure 400 {object} ErrorResponse "Invalid request or failed to parse public key"
+// @Failure 400 {object} ErrorResponse "Public key refused by the CA's key policy, naming the rule"
 // @Failure 404 {object} ErrorResponse "CA not found"
 // @Failure 404 {object} ErrorResponse "Requested TTL longer than configured max"
+// @Failure 400 {object} ErrorResponse "Invalid validity window"
+// @Failure 403 {object} ErrorResponse "Signing request needs approval"
 // @Failure 404 {object} ErrorResponse "Requested principals not in valid principal list"
 // @Failure 500 {object} ErrorResponse "Failed to sign public key"
 // @Router /CA/{id}/Sign [post]
 func (a *App) Sign(c echo.Context) error {
 	CaID := c.Param("id")
+	event := audit.FromContext(c)
+	event.CA = CaID
 	ca, err := a.Store.GetCAByID(CaID)
 	if err != nil {
+		// Unknown IDs are not used as labels, or each would add a series
+		a.Metrics.ObserveSign("", metrics.SignCANotFound)
 		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
 	}
+	outcome := metrics.SignError
+	defer func() { a.Metrics.ObserveSign(CaID, outcome) }()
 	signer, err := a.Store.GetSignerByID(CaID)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, ErrorResponse{"CA signer not found"})
@@ -36,34 +50,125 @@ func (a *App) Sign(c echo.Context) error {
 	var requestBody = &cert.SignRequest{}
 
 	if err := c.Bind(requestBody); err != nil {
+		outcome = metrics.SignInvalidRequest
 		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
 	}
+	event.Principals = requestBody.Principals
 
-	parsedPublicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(requestBody.PublicKey))
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{"Failed to parse public key"})
+	plan, rejected := planSign(ca, *requestBody, time.Now())
+	if rejected != nil {
+		outcome = rejected.outcome
+		return c.JSON(rejected.status, ErrorResponse{rejected.message})
 	}
-
-	if requestBody.TTLMinutes > ca.MaxTTLMinutes {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{"Requested TTL longer than configured max"})
+	if required, _, _ := ca.ApprovalsRequired(plan.principals, plan.ttl, ""); required > 0 {
+		outcome = metrics.SignNeedsApproval
+		return c.JSON(http.StatusForbidden, ErrorResponse{"Signing request needs approval, submit it to /CA/" + CaID + "/requests"})
 	}
 
-	if !isSubset(requestBody.Principals, ca.ValidPrincipals) {
-		return c.JSON(http.StatusBadRequest, ErrorResponse{"Requested principals not in valid principal list"})
-	}
-
-	signedCert, err := cert.SignUserKey(signer, parsedPublicKey, requestBody.Principals, requestBody.TTLMinutes)
+	signedCert, err := plan.sign(signer)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to sign public key"})
 	}
-	c.Logger().Infof("Signed public key %s for %s", comment, CaID)
+	outcome = metrics.SignIssued
+	event.Serial = signedCert.Serial
+	c.Logger().Infof("Signed public key %s for %s", plan.comment, CaID)
 	response := cert.SignResponse{
 		SignedKey: string(ssh.MarshalAuthorizedKey(signedCert)),
+		Serial:    signedCert.Serial,
 	}
 
 	return c.JSON(http.StatusCreated, response)
 }
 
+// requestError is a request refused with an HTTP status
+type requestError struct {
+	status  int
+	message string
+}
+
+func (e *requestError) Error() string {
+	return e.message
+}
+
+// signRejection is a signing request refused by planSign, with the outcome
+// counted by the metrics
+type signRejection struct {
+	requestError
+	outcome string
+}
+
+func rejectSign(status int, message, outcome string) *signRejection {
+	return &signRejection{requestError{status, message}, outcome}
+}
+
+// signPlan is a signing request checked against its CA, ready to sign
+type signPlan struct {
+	ca         *cert.CaResponse
+	key        ssh.PublicKey
+	comment    string
+	principals []string
+	// Requested lifetime, without the CA's backdate allowance
+	ttl         time.Duration
+	validAfter  time.Time
+	validBefore time.Time
+	// Key ID of the certificate, none when empty
+	keyID string
+}
+
+// planSign checks request against the CA's key policy, TTL and principals
+func planSign(ca *cert.CaResponse, request cert.SignRequest, now time.Time) (*signPlan, *signRejection) {
+	parsedPublicKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(request.PublicKey))
+	if err != nil {
+		return nil, rejectSign(http.StatusBadRequest, "Failed to parse public key", metrics.SignParseFailed)
+	}
+	if !ca.IsHostCA() {
+		if err := ca.KeyPolicy.Check(parsedPublicKey); err != nil {
+			return nil, rejectSign(http.StatusBadRequest, err.Error(), metrics.SignKeyPolicy)
+		}
+	}
+
+	validAfter, validBefore, err := ca.ValidityWindow(request, now)
+	if errors.Is(err, cert.ErrTTLTooLong) {
+		return nil, rejectSign(http.StatusBadRequest, "Requested TTL longer than configured max", metrics.SignTTLTooLong)
+	} else if err != nil {
+		return nil, rejectSign(http.StatusBadRequest, err.Error(), metrics.SignInvalidValidity)
+	}
+
+	if !isSubset(request.Principals, ca.ValidPrincipals) {
+		return nil, rejectSign(http.StatusBadRequest, "Requested principals not in valid principal list", metrics.SignInvalidPrincipals)
+	}
+	// A host certificate without principals is valid for any host
+	if ca.IsHostCA() && len(request.Principals) == 0 {
+		return nil, rejectSign(http.StatusBadRequest, "Host certificates need at least one host name", metrics.SignInvalidPrincipals)
+	}
+
+	start := validAfter
+	if request.ValidAfter == nil {
+		start = now
+	}
+	return &signPlan{
+		ca:          ca,
+		key:         parsedPublicKey,
+		comment:     comment,
+		principals:  request.Principals,
+		ttl:         validBefore.Sub(start),
+		validAfter:  validAfter,
+		validBefore: validBefore,
+	}, nil
+}
+
+// sign issues the planned certificate, a host certificate for host CAs
+func (p *signPlan) sign(signer ssh.Signer) (*ssh.Certificate, error) {
+	options := []cert.SignOption{cert.WithKeyPolicy(p.ca.KeyPolicy), cert.WithValidity(p.validAfter, p.validBefore)}
+	if p.keyID != "" {
+		options = append(options, cert.WithKeyID(p.keyID))
+	}
+	if p.ca.IsHostCA() {
+		return cert.SignHostKey(signer, p.key, p.principals, 0, options...)
+	}
+	return cert.SignUserKey(signer, p.key, p.principals, 0, options...)
+}
+
 // IsSubset checks if list1 is a subset of list2
 func isSubset(list1, list2 []string) bool {
 	// Create a map to store elements of list2
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"encoding/json"
 	"github.com/labstack/echo/v4"
 	"github.com/lukegriffith/SSHTrust/pkg/cert"
 	"github.com/stretchr/testify/assert"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 var testPrivateKey = `
@@ -110,6 +112,13 @@ func TestSignHandler(t *testing.T) {
 			expectedBody:   "Requested TTL longer than configured max",
 			caID:           "test-ca",
 		},
+		{
+			name:           "Negative TTL",
+			requestBody:    `{"public_key":"` + testPublicKey + `","principals":["user1"],"ttl_minutes":-30}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "ttl_minutes must be positive",
+			caID:           "test-ca",
+		},
 		{
 			name:           "Invalid Principals",
 			requestBody:    `{"public_key":"` + testPublicKey + `","principals":["invalid"],"ttl_minutes":30}`,
@@ -136,3 +145,166 @@ func TestSignHandler(t *testing.T) {
 		})
 	}
 }
+
+// Test host CAs sign host certificates
+func TestSignHostHandler(t *testing.T) {
+	e := echo.New()
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	app := &App{Store: &MockStore{
+		caMap: map[string]*cert.CaResponse{
+			"host-ca": {CommonCa: cert.CommonCa{
+				Name:            "host-ca",
+				MaxTTLMinutes:   60,
+				ValidPrincipals: []string{"web1.example.com"},
+				Kind:            cert.HostCA,
+				HostPatterns:    []string{"*.example.com"},
+			}},
+		},
+		signers: map[string]ssh.Signer{"host-ca": signer},
+	}}
+
+	c, rec := postJSON(e, "host-ca", `{"public_key":"`+testPublicKey+`","principals":["web1.example.com"],"ttl_minutes":30}`)
+	if assert.NoError(t, app.Sign(c)) && assert.Equal(t, http.StatusCreated, rec.Code) {
+		var response cert.SignResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		signed, err := cert.ParseCertificate([]byte(response.SignedKey))
+		assert.NoError(t, err)
+		assert.Equal(t, uint32(ssh.HostCert), signed.CertType)
+		assert.Equal(t, []string{"web1.example.com"}, signed.ValidPrincipals)
+	}
+
+	c, rec = postJSON(e, "host-ca", `{"public_key":"`+testPublicKey+`","principals":[],"ttl_minutes":30}`)
+	if assert.NoError(t, app.Sign(c)) {
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Contains(t, rec.Body.String(), "at least one host name")
+	}
+}
+
+// Test user keys are checked against the CA's key policy
+func TestSignKeyPolicy(t *testing.T) {
+	e := echo.New()
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	app := &App{Store: &MockStore{
+		caMap: map[string]*cert.CaResponse{
+			"test-ca": {CommonCa: cert.CommonCa{Name: "test-ca", MaxTTLMinutes: 60, ValidPrincipals: []string{"user1"}}},
+			"sk-ca": {CommonCa: cert.CommonCa{
+				Name: "sk-ca", MaxTTLMinutes: 60, ValidPrincipals: []string{"user1"},
+				KeyPolicy: &cert.KeyPolicy{RequireSecurityKey: true},
+			}},
+			"verify-ca": {CommonCa: cert.CommonCa{
+				Name: "verify-ca", MaxTTLMinutes: 60, ValidPrincipals: []string{"user1"},
+				KeyPolicy: &cert.KeyPolicy{RequireUserVerification: true},
+			}},
+		},
+		signers: map[string]ssh.Signer{"test-ca": signer, "sk-ca": signer, "verify-ca": signer},
+	}}
+	skKey := "sk-ssh-ed25519@openssh.com AAAAGnNrLXNzaC1lZDI1NTE5QG9wZW5zc2guY29tAAAAIBYn2e7wnMiZEmCARxhfZLGVHCynH3bHJDV4nbF9SBTlAAAABHNzaDo="
+	rsa1024Key := "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAAgQDFiiH09E/KHO2WKtl46JRWUdKJa0vfajI5FA2/ZHknqwKeKKi7/6HY2YA2RABQ5AoC5Rh6LSsdqkME2YoRYrukLJ9n+Q39PD3LdtBg3kEeXTliefoWLDtS+yptCMqFvtMNk5V1y3T7Qwkv1Z6hQla0t8XxiQUz/iwJ7tM97XjRKQ=="
+
+	tests := []struct {
+		name         string
+		caID         string
+		publicKey    string
+		expectedCode int
+		expectedBody string
+	}{
+		{"Weak RSA key", "test-ca", rsa1024Key, http.StatusBadRequest, "key policy min_bits: ssh-rsa key is 1024 bits"},
+		{"Security key not required", "test-ca", skKey, http.StatusCreated, "signed_key"},
+		{"Security key required", "sk-ca", testPublicKey, http.StatusBadRequest, "key policy require_security_key"},
+		{"Security key", "sk-ca", skKey, http.StatusCreated, "signed_key"},
+		{"User verification required", "verify-ca", testPublicKey, http.StatusBadRequest, "key policy require_user_verification"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := postJSON(e, tt.caID, `{"public_key":"`+tt.publicKey+`","principals":["user1"],"ttl_minutes":30}`)
+			if assert.NoError(t, app.Sign(c)) {
+				assert.Equal(t, tt.expectedCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+// Test CAs requiring user verification issue verify-required certificates
+func TestSignUserVerification(t *testing.T) {
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	app := &App{Store: &MockStore{
+		caMap: map[string]*cert.CaResponse{
+			"verify-ca": {CommonCa: cert.CommonCa{
+				Name: "verify-ca", MaxTTLMinutes: 60, ValidPrincipals: []string{"user1"},
+				KeyPolicy: &cert.KeyPolicy{RequireUserVerification: true},
+			}},
+		},
+		signers: map[string]ssh.Signer{"verify-ca": signer},
+	}}
+	skKey := "sk-ssh-ed25519@openssh.com AAAAGnNrLXNzaC1lZDI1NTE5QG9wZW5zc2guY29tAAAAIBYn2e7wnMiZEmCARxhfZLGVHCynH3bHJDV4nbF9SBTlAAAABHNzaDo="
+
+	c, rec := postJSON(echo.New(), "verify-ca", `{"public_key":"`+skKey+`","principals":["user1"],"ttl_minutes":30}`)
+	if assert.NoError(t, app.Sign(c)) && assert.Equal(t, http.StatusCreated, rec.Code) {
+		var response cert.SignResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		signed, err := cert.ParseCertificate([]byte(response.SignedKey))
+		assert.NoError(t, err)
+		assert.Contains(t, signed.CriticalOptions, cert.VerifyRequired)
+		assert.NotContains(t, signed.Extensions, cert.NoTouchRequired)
+	}
+}
+
+// Test the validity window of signed certificates
+func TestSignValidityWindow(t *testing.T) {
+	e := echo.New()
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	app := &App{Store: &MockStore{
+		caMap: map[string]*cert.CaResponse{
+			"test-ca": {CommonCa: cert.CommonCa{Name: "test-ca", MaxTTLMinutes: 120, ValidPrincipals: []string{"user1"}, BackdateSeconds: 60}},
+		},
+		signers: map[string]ssh.Signer{"test-ca": signer},
+	}}
+	sign := func(body string) (*httptest.ResponseRecorder, *ssh.Certificate) {
+		c, rec := postJSON(e, "test-ca", body)
+		assert.NoError(t, app.Sign(c))
+		if rec.Code != http.StatusCreated {
+			return rec, nil
+		}
+		var response cert.SignResponse
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		signed, err := cert.ParseCertificate([]byte(response.SignedKey))
+		assert.NoError(t, err)
+		return rec, signed
+	}
+
+	// Certificates start before now by the CA's backdate allowance
+	before := time.Now()
+	_, signed := sign(`{"public_key":"` + testPublicKey + `","principals":["user1"],"ttl_minutes":30}`)
+	if assert.NotNil(t, signed) {
+		assert.LessOrEqual(t, signed.ValidAfter, uint64(before.Add(-time.Minute).Unix()+1))
+		assert.Equal(t, uint64(31*60), signed.ValidBefore-signed.ValidAfter)
+	}
+
+	// A maintenance window tomorrow
+	start := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	_, signed = sign(`{"public_key":"` + testPublicKey + `","principals":["user1"],"valid_after":"` + start.Format(time.RFC3339) + `","valid_before":"` + start.Add(2*time.Hour).Format(time.RFC3339) + `"}`)
+	if assert.NotNil(t, signed) {
+		assert.Equal(t, uint64(start.Unix()), signed.ValidAfter)
+		assert.Equal(t, uint64(start.Add(2*time.Hour).Unix()), signed.ValidBefore)
+	}
+
+	rec, _ := sign(`{"public_key":"` + testPublicKey + `","principals":["user1"],"valid_after":"` + start.Format(time.RFC3339) + `","valid_before":"` + start.Add(3*time.Hour).Format(time.RFC3339) + `"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Requested TTL longer than configured max")
+	rec, _ = sign(`{"public_key":"` + testPublicKey + `","principals":["user1"],"ttl_minutes":30,"valid_after":"` + time.Now().Add(30*24*time.Hour).Format(time.RFC3339) + `"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "further ahead than the CA allows")
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"golang.org/x/crypto/ssh"
+)
+
+// UserCAKeys returns a TrustedUserCAKeys file for the selected CAs
+// @Summary Get a TrustedUserCAKeys bundle
+// @Description Get the public keys of the selected user CAs, including retiring keys during a rotation, as a file ready for sshd's TrustedUserCAKeys. Responses carry an ETag, send it back in If-None-Match to get a 304 when nothing changed.
+// @Tags Trust
+// @Produce  plain
+// @Param tag query string false "Only include CAs with this tag"
+// @Param ca query []string false "Only include these CAs, comma separated or repeated" collectionFormat(multi)
+// @Success 200 {string} string "TrustedUserCAKeys file"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Router /trust/user-ca-keys [get]
+func (a *App) UserCAKeys(c echo.Context) error {
+	cas, err := a.selectCAs(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{err.Error()})
+	}
+
+	var b strings.Builder
+	b.WriteString("# TrustedUserCAKeys generated by sshtrust\n")
+	for _, ca := range cas {
+		if ca.IsHostCA() {
+			continue
+		}
+		keys, err := ca.TrustedKeys()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to parse CA public key"})
+		}
+		for i, key := range keys {
+			comment := ca.Name
+			if i > 0 {
+				comment += " (retiring)"
+			}
+			fmt.Fprintf(&b, "%s %s\n", strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))), comment)
+		}
+	}
+	return cachedBlob(c, echo.MIMETextPlainCharsetUTF8, []byte(b.String()))
+}
+
+// KnownHosts returns @cert-authority known_hosts lines for the host CAs
+// @Summary Get known_hosts lines for host CAs
+// @Description Get a @cert-authority line for each selected host CA and its host patterns, including retiring keys during a rotation, ready to add to known_hosts. Responses carry an ETag, send it back in If-None-Match to get a 304 when nothing changed.
+// @Tags Trust
+// @Produce  plain
+// @Param tag query string false "Only include CAs with this tag"
+// @Param ca query []string false "Only include these CAs, comma separated or repeated" collectionFormat(multi)
+// @Success 200 {string} string "known_hosts lines"
+// @Success 304 "Not modified"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Router /trust/known_hosts [get]
+func (a *App) KnownHosts(c echo.Context) error {
+	cas, err := a.selectCAs(c)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{err.Error()})
+	}
+
+	var b strings.Builder
+	for _, ca := range cas {
+		if !ca.IsHostCA() {
+			continue
+		}
+		keys, err := ca.TrustedKeys()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to parse CA public key"})
+		}
+		patterns := strings.Join(ca.HostPatterns, ",")
+		for i, key := range keys {
+			comment := ca.Name
+			if i > 0 {
+				comment += " (retiring)"
+			}
+			fmt.Fprintf(&b, "@cert-authority %s %s %s\n", patterns, strings.TrimSpace(string(ssh.MarshalAuthorizedKey(key))), comment)
+		}
+	}
+	return cachedBlob(c, echo.MIMETextPlainCharsetUTF8, []byte(b.String()))
+}
+
+// Principals returns the principals allowed to log in to a local account
+// @Summary Get the principals allowed to log in to a local account
+// @Description Get the principals that may log in to a local account on a host, one per line, for sshd's AuthorizedPrincipalsCommand. User CAs sharing any of the host's tags are consulted, all user CAs when no tags are given. A CA's accounts map principals to accounts, without one each valid principal maps to the account of the same name. Needs a login, or the server's principals token as the bearer token.
+// @Tags Trust
+// @Produce  plain
+// @Param user query string true "Local account being logged in to"
+// @Param tag query []string false "Tags of the host, comma separated or repeated" collectionFormat(multi)
+// @Success 200 {string} string "Principals, one per line"
+// @Success 304 "Not modified"
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 401 "Missing or invalid login or principals token"
+// @Router /trust/principals [get]
+func (a *App) Principals(c echo.Context) error {
+	account := c.QueryParam("user")
+	if account == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	tags := splitQuery(c, "tag")
+
+	cas, _ := a.Store.ListCAs()
+	seen := map[string]bool{}
+	principals := []string{}
+	for _, ca := range cas {
+		if ca.IsHostCA() || (len(tags) > 0 && !hasAnyTag(ca, tags)) {
+			continue
+		}
+		for _, principal := range ca.AccountPrincipals(account) {
+			if !seen[principal] {
+				seen[principal] = true
+				principals = append(principals, principal)
+			}
+		}
+	}
+	sort.Strings(principals)
+
+	var b strings.Builder
+	for _, principal := range principals {
+		b.WriteString(principal + "\n")
+	}
+	return cachedBlob(c, echo.MIMETextPlainCharsetUTF8, []byte(b.String()))
+}
+
+func hasAnyTag(ca *cert.CaResponse, tags []string) bool {
+	for _, tag := range tags {
+		if ca.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitQuery returns a query parameter given comma separated, repeated or both
+func splitQuery(c echo.Context, name string) []string {
+	values := []string{}
+	for _, value := range c.QueryParams()[name] {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// selectCAs returns the CAs chosen by the tag and ca query parameters,
+// sorted by name so bundles are stable. Unknown CA names are an error, as an
+// empty bundle would lock everyone out or accept revoked certificates.
+func (a *App) selectCAs(c echo.Context) ([]*cert.CaResponse, error) {
+	tag := c.QueryParam("tag")
+	names := splitQuery(c, "ca")
+
+	var cas []*cert.CaResponse
+	if len(names) > 0 {
+		for _, name := range names {
+			ca, err := a.Store.GetCAByID(name)
+			if err != nil {
+				return nil, fmt.Errorf("CA not found: %s", name)
+			}
+			cas = append(cas, ca)
+		}
+	} else {
+		cas, _ = a.Store.ListCAs()
+	}
+
+	selected := []*cert.CaResponse{}
+	for _, ca := range cas {
+		if tag == "" || ca.HasTag(tag) {
+			selected = append(selected, ca)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool { return selected[i].Name < selected[j].Name })
+	return selected, nil
+}
+
+// cachedBlob writes body with an ETag, answering 304 when the client
+// already has it.
+func cachedBlob(c echo.Context, contentType string, body []byte) error {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-cache")
+	c.Response().Header().Set("ETag", etag)
+	for _, candidate := range strings.Split(c.Request().Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+	return c.Blob(http.StatusOK, contentType, body)
+}
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/certStore"
+	"github.com/lukegriffith/SSHTrust/pkg/krl"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func newTrustApp(t *testing.T) *App {
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	publicKey := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	return &App{Store: &MockStore{caMap: map[string]*cert.CaResponse{
+		"prod-ca":    {CommonCa: cert.CommonCa{Name: "prod-ca", Tags: []string{"prod"}}, PublicKey: publicKey},
+		"staging-ca": {CommonCa: cert.CommonCa{Name: "staging-ca", Tags: []string{"staging"}}, PublicKey: publicKey},
+		"host-ca": {
+			CommonCa:  cert.CommonCa{Name: "host-ca", Tags: []string{"prod"}, Kind: cert.HostCA, HostPatterns: []string{"*.example.com", "10.0.0.*"}},
+			PublicKey: publicKey,
+		},
+	}}}
+}
+
+func getTrust(e *echo.Echo, target, ifNoneMatch string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if ifNoneMatch != "" {
+		req.Header.Set("If-None-Match", ifNoneMatch)
+	}
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestUserCAKeysHandler(t *testing.T) {
+	e := echo.New()
+	app := newTrustApp(t)
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		contains       []string
+		excludes       []string
+	}{
+		{"All user CAs", "/trust/user-ca-keys", http.StatusOK, []string{"prod-ca\n", "staging-ca\n"}, []string{"host-ca"}},
+		{"By tag", "/trust/user-ca-keys?tag=prod", http.StatusOK, []string{"prod-ca\n"}, []string{"staging-ca"}},
+		{"By CA", "/trust/user-ca-keys?ca=staging-ca", http.StatusOK, []string{"staging-ca\n"}, []string{"prod-ca"}},
+		{"CA and tag", "/trust/user-ca-keys?ca=staging-ca,prod-ca&tag=prod", http.StatusOK, []string{"prod-ca\n"}, []string{"staging-ca"}},
+		{"Unknown CA", "/trust/user-ca-keys?ca=missing", http.StatusNotFound, []string{"CA not found: missing"}, nil},
+		{"Known and unknown CA", "/trust/user-ca-keys?ca=prod-ca,missing", http.StatusNotFound, []string{"CA not found: missing"}, []string{"ssh-"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := getTrust(e, tt.target, "")
+			if assert.NoError(t, app.UserCAKeys(c)) {
+				assert.Equal(t, tt.expectedStatus, rec.Code)
+				for _, s := range tt.contains {
+					assert.Contains(t, rec.Body.String(), s)
+				}
+				for _, s := range tt.excludes {
+					assert.NotContains(t, rec.Body.String(), s)
+				}
+			}
+		})
+	}
+}
+
+func TestUserCAKeysRotationAndETag(t *testing.T) {
+	e := echo.New()
+	app := newTrustApp(t)
+
+	c, rec := getTrust(e, "/trust/user-ca-keys?tag=prod", "")
+	assert.NoError(t, app.UserCAKeys(c))
+	etag := rec.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	// Unchanged bundles are not sent again
+	c, rec = getTrust(e, "/trust/user-ca-keys?tag=prod", etag)
+	assert.NoError(t, app.UserCAKeys(c))
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+	assert.Empty(t, rec.Body.String())
+
+	// During a rotation both keys are trusted and the ETag changes
+	_, err := app.Store.RotateCA("prod-ca")
+	assert.NoError(t, err)
+	c, rec = getTrust(e, "/trust/user-ca-keys?tag=prod", etag)
+	assert.NoError(t, app.UserCAKeys(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEqual(t, etag, rec.Header().Get("ETag"))
+	assert.Contains(t, rec.Body.String(), "prod-ca\n")
+	assert.Contains(t, rec.Body.String(), "prod-ca (retiring)\n")
+
+	_, err = app.Store.CompleteRotation("prod-ca")
+	assert.NoError(t, err)
+	c, rec = getTrust(e, "/trust/user-ca-keys?tag=prod", "")
+	assert.NoError(t, app.UserCAKeys(c))
+	assert.NotContains(t, rec.Body.String(), "retiring")
+}
+
+func TestKnownHostsHandler(t *testing.T) {
+	e := echo.New()
+	app := newTrustApp(t)
+	ca, _ := app.Store.GetCAByID("host-ca")
+	key := strings.TrimSpace(ca.PublicKey)
+
+	c, rec := getTrust(e, "/trust/known_hosts?tag=prod", "")
+	if assert.NoError(t, app.KnownHosts(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "@cert-authority *.example.com,10.0.0.* "+key+" host-ca\n", rec.Body.String())
+		assert.NotEmpty(t, rec.Header().Get("ETag"))
+	}
+
+	_, err := app.Store.RotateCA("host-ca")
+	assert.NoError(t, err)
+	c, rec = getTrust(e, "/trust/known_hosts", "")
+	if assert.NoError(t, app.KnownHosts(c)) {
+		assert.Contains(t, rec.Body.String(), key+" host-ca (retiring)\n")
+		assert.NotContains(t, rec.Body.String(), "prod-ca")
+	}
+}
+
+func TestPrincipalsHandler(t *testing.T) {
+	e := echo.New()
+	app := &App{Store: &MockStore{caMap: map[string]*cert.CaResponse{
+		"prod-ca": {CommonCa: cert.CommonCa{
+			Name:            "prod-ca",
+			Tags:            []string{"prod"},
+			ValidPrincipals: []string{"alice", "bob", "carol"},
+			Accounts:        map[string][]string{"root": {"alice", "bob"}, "deploy": {"carol"}},
+		}},
+		"staging-ca": {CommonCa: cert.CommonCa{
+			Name:            "staging-ca",
+			Tags:            []string{"staging"},
+			ValidPrincipals: []string{"alice", "dave"},
+		}},
+		"host-ca": {CommonCa: cert.CommonCa{
+			Name:            "host-ca",
+			Tags:            []string{"prod"},
+			Kind:            cert.HostCA,
+			ValidPrincipals: []string{"root"},
+		}},
+	}}}
+
+	tests := []struct {
+		name           string
+		target         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Mapped account", "/trust/principals?user=root&tag=prod", http.StatusOK, "alice\nbob\n"},
+		{"Identity mapping", "/trust/principals?user=dave&tag=staging", http.StatusOK, "dave\n"},
+		{"Any tag matches", "/trust/principals?user=alice&tag=prod,staging", http.StatusOK, "alice\n"},
+		{"Unmapped account", "/trust/principals?user=alice&tag=prod", http.StatusOK, ""},
+		{"All CAs without tags", "/trust/principals?user=root", http.StatusOK, "alice\nbob\n"},
+		{"Missing user", "/trust/principals?tag=prod", http.StatusBadRequest, "Invalid request"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := getTrust(e, tt.target, "")
+			if assert.NoError(t, app.Principals(c)) {
+				assert.Equal(t, tt.expectedStatus, rec.Code)
+				if tt.expectedStatus == http.StatusOK {
+					assert.Equal(t, tt.expectedBody, rec.Body.String())
+				} else {
+					assert.Contains(t, rec.Body.String(), tt.expectedBody)
+				}
+			}
+		})
+	}
+}
+
+func TestTrustKRLHandler(t *testing.T) {
+	e := echo.New()
+	app := newTrustApp(t)
+	app.Revocations = certStore.NewInMemoryRevocationStore()
+	assert.NoError(t, app.Revocations.Revoke("prod-ca", 42))
+	assert.NoError(t, app.Revocations.Revoke("host-ca", 43))
+
+	c, rec := getTrust(e, "/trust/krl?tag=prod", "")
+	if assert.NoError(t, app.TrustKRL(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		list, err := krl.Parse(rec.Body.Bytes())
+		if assert.NoError(t, err) {
+			// Host CAs are left out, sshd only checks user certificates against it
+			assert.Len(t, list.Certificates, 1)
+			assert.Equal(t, []uint64{42}, list.Certificates[0].Serials)
+		}
+	}
+
+	// Nothing changed, so the ETag still matches
+	etag := rec.Header().Get("ETag")
+	c, rec = getTrust(e, "/trust/krl?tag=prod", etag)
+	if assert.NoError(t, app.TrustKRL(c)) {
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+	}
+
+	assert.NoError(t, app.Revocations.Revoke("prod-ca", 44))
+	c, rec = getTrust(e, "/trust/krl?tag=prod", etag)
+	if assert.NoError(t, app.TrustKRL(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+
+	// A mistyped CA is not served as an empty KRL
+	c, rec = getTrust(e, "/trust/krl?ca=prod-cs", "")
+	if assert.NoError(t, app.TrustKRL(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.Contains(t, rec.Body.String(), "CA not found: prod-cs")
+	}
+}
@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"net/http"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/verify"
+)
+
+// Revoke revokes a certificate issued by a specific CA
+// @Summary Revoke a certificate
+// @Description Revoke a certificate by serial number, it will fail verification from then on.
+// @Tags CAs
+// @Accept  json
+// @Produce  json
+// @Param id path string true "CA ID"
+// @Param serial body cert.RevokeRequest true "Certificate to revoke"
+// @Success 200 {object} MessageResponse
+// @Failure 400 {object} ErrorResponse "Invalid request"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Failure 500 {object} ErrorResponse "Failed to revoke certificate"
+// @Router /CA/{id}/revoke [post]
+func (a *App) Revoke(c echo.Context) error {
+	CaID := c.Param("id")
+	event := audit.FromContext(c)
+	event.CA = CaID
+	if _, err := a.Store.GetCAByID(CaID); err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+
+	var requestBody cert.RevokeRequest
+	if err := c.Bind(&requestBody); err != nil || requestBody.Serial == 0 {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	event.Serial = requestBody.Serial
+
+	if err := a.Revocations.Revoke(CaID, requestBody.Serial); err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to revoke certificate"})
+	}
+	c.Logger().Infof("Revoked certificate %d for %s", requestBody.Serial, CaID)
+	return c.JSON(http.StatusOK, MessageResponse{"Certificate revoked"})
+}
+
+// Verify checks a user certificate against a specific CA
+// @Summary Verify a certificate with a specific CA
+// @Description Check a user certificate was signed by the CA, is valid now for the principal, is permitted by the CA's principal list and has not been revoked. Certificates with critical options the caller does not list in supported_critical_options, such as verify-required, are rejected.
+// @Tags CAs
+// @Accept  json
+// @Produce  json
+// @Param id path string true "CA ID"
+// @Param certificate body cert.VerifyRequest true "Certificate and login principal"
+// @Success 200 {object} cert.VerifyResponse "valid is false with a reason when the certificate is rejected"
+// @Failure 400 {object} ErrorResponse "Invalid request or failed to parse certificate"
+// @Failure 404 {object} ErrorResponse "CA not found"
+// @Router /CA/{id}/verify [post]
+func (a *App) Verify(c echo.Context) error {
+	CaID := c.Param("id")
+	ca, err := a.Store.GetCAByID(CaID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, ErrorResponse{"CA not found"})
+	}
+	caKeys, err := ca.TrustedKeys()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to parse CA public key"})
+	}
+
+	var requestBody cert.VerifyRequest
+	if err := c.Bind(&requestBody); err != nil || requestBody.Principal == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Invalid request"})
+	}
+	parsedCert, err := cert.ParseCertificate([]byte(requestBody.Certificate))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{"Failed to parse certificate"})
+	}
+
+	// Certificates from a retiring key stay valid until the rotation completes
+	authorities := verify.AuthorityList{}
+	for _, key := range caKeys {
+		authorities = append(authorities, verify.Authority{Name: ca.Name, Key: key, ValidPrincipals: ca.ValidPrincipals})
+	}
+	verifier := &verify.Verifier{
+		Authorities: authorities,
+		Revocations: a.Revocations,
+		// Only the caller knows whether it enforces options like verify-required
+		SupportedCriticalOptions: requestBody.SupportedCriticalOptions,
+	}
+	response := cert.VerifyResponse{
+		Valid:  true,
+		Serial: parsedCert.Serial,
+		KeyID:  parsedCert.KeyId,
+	}
+	if _, err := verifier.VerifyUser(parsedCert, requestBody.Principal); err != nil {
+		response.Valid = false
+		response.Reason = err.Error()
+	}
+	return c.JSON(http.StatusOK, response)
+}
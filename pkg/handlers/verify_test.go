@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/certStore"
+	"github.com/lukegriffith/SSHTrust/pkg/krl"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func newVerifyApp(t *testing.T) (*App, ssh.Signer) {
+	signer, err := createMockSigner()
+	if err != nil {
+		t.Fatalf("Failed to create mock signer: %v", err)
+	}
+	mockStore := &MockStore{
+		caMap: map[string]*cert.CaResponse{
+			"test-ca": {
+				CommonCa:  cert.CommonCa{Name: "test-ca", MaxTTLMinutes: 60, ValidPrincipals: []string{"user1", "user2"}},
+				PublicKey: string(ssh.MarshalAuthorizedKey(signer.PublicKey())),
+			},
+		},
+		signers: map[string]ssh.Signer{"test-ca": signer},
+	}
+	return &App{Store: mockStore, Revocations: certStore.NewInMemoryRevocationStore()}, signer
+}
+
+func postJSON(e *echo.Echo, caID, body string) (echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(http.MethodPost, "/ca/"+caID, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(caID)
+	return c, rec
+}
+
+func TestVerifyHandler(t *testing.T) {
+	e := echo.New()
+	app, signer := newVerifyApp(t)
+
+	userKey, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(testPublicKey))
+	signed, _ := cert.SignUserKey(signer, userKey, []string{"user1"}, 30)
+	certificate := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signed)))
+
+	verifyBody := func(principal string) string {
+		body, _ := json.Marshal(cert.VerifyRequest{Certificate: certificate, Principal: principal})
+		return string(body)
+	}
+
+	// Certificates from CAs requiring user verification on security keys
+	skKey, _, _, _, _ := ssh.ParseAuthorizedKey([]byte("sk-ssh-ed25519@openssh.com AAAAGnNrLXNzaC1lZDI1NTE5QG9wZW5zc2guY29tAAAAIDkkhfB5X3SOPBiu+pSOSjQn6xs6M1dAJrgTtPjYoX3mAAAABHNzaDo="))
+	verified, _ := cert.SignUserKey(signer, skKey, []string{"user1"}, 30, cert.WithKeyPolicy(&cert.KeyPolicy{RequireUserVerification: true}))
+	assert.Contains(t, verified.CriticalOptions, cert.VerifyRequired)
+	verifiedBody, _ := json.Marshal(cert.VerifyRequest{Certificate: string(ssh.MarshalAuthorizedKey(verified)), Principal: "user1"})
+	enforcedBody, _ := json.Marshal(cert.VerifyRequest{Certificate: string(ssh.MarshalAuthorizedKey(verified)), Principal: "user1", SupportedCriticalOptions: []string{cert.VerifyRequired}})
+
+	tests := []struct {
+		name           string
+		caID           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Valid certificate", "test-ca", verifyBody("user1"), http.StatusOK, `"valid":true`},
+		{"Wrong principal", "test-ca", verifyBody("user2"), http.StatusOK, `"valid":false`},
+		{"User verification not enforced", "test-ca", string(verifiedBody), http.StatusOK, `unsupported critical option`},
+		{"User verification enforced", "test-ca", string(enforcedBody), http.StatusOK, `"valid":true`},
+		{"CA Not Found", "nonexistent-ca", verifyBody("user1"), http.StatusNotFound, "CA not found"},
+		{"Missing principal", "test-ca", verifyBody(""), http.StatusBadRequest, "Invalid request"},
+		{"Invalid certificate", "test-ca", `{"certificate":"` + testPublicKey + `","principal":"user1"}`, http.StatusBadRequest, "Failed to parse certificate"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := postJSON(e, tt.caID, tt.body)
+			if assert.NoError(t, app.Verify(c)) {
+				assert.Equal(t, tt.expectedStatus, rec.Code)
+				assert.Contains(t, rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+
+	// Once revoked the same certificate fails verification
+	c, rec := postJSON(e, "test-ca", fmt.Sprintf(`{"serial":%d}`, signed.Serial))
+	if assert.NoError(t, app.Revoke(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+	c, rec = postJSON(e, "test-ca", verifyBody("user1"))
+	if assert.NoError(t, app.Verify(c)) {
+		assert.Contains(t, rec.Body.String(), `"valid":false`)
+		assert.Contains(t, rec.Body.String(), "revoked")
+	}
+}
+
+func TestRevokeHandler(t *testing.T) {
+	e := echo.New()
+	app, _ := newVerifyApp(t)
+
+	tests := []struct {
+		name           string
+		caID           string
+		body           string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Revoked", "test-ca", `{"serial":42}`, http.StatusOK, "Certificate revoked"},
+		{"CA Not Found", "nonexistent-ca", `{"serial":42}`, http.StatusNotFound, "CA not found"},
+		{"Missing serial", "test-ca", `{}`, http.StatusBadRequest, "Invalid request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := postJSON(e, tt.caID, tt.body)
+			if assert.NoError(t, app.Revoke(c)) {
+				assert.Equal(t, tt.expectedStatus, rec.Code)
+				assert.Contains(t, rec.Body.String(), tt.expectedBody)
+			}
+		})
+	}
+}
+
+func TestKRLHandler(t *testing.T) {
+	e := echo.New()
+	app, signer := newVerifyApp(t)
+
+	userKey, _, _, _, _ := ssh.ParseAuthorizedKey([]byte(testPublicKey))
+	revoked, _ := cert.SignUserKey(signer, userKey, []string{"user1"}, 30)
+	valid, _ := cert.SignUserKey(signer, userKey, []string{"user1"}, 30)
+	assert.NoError(t, app.Revocations.Revoke("test-ca", revoked.Serial))
+
+	c, rec := postJSON(e, "test-ca", "")
+	if assert.NoError(t, app.KRL(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		list, err := krl.Parse(rec.Body.Bytes())
+		if assert.NoError(t, err) {
+			assert.True(t, list.IsRevoked(revoked))
+			assert.False(t, list.IsRevoked(valid))
+		}
+	}
+
+	c, rec = postJSON(e, "nonexistent-ca", "")
+	if assert.NoError(t, app.KRL(c)) {
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	}
+}
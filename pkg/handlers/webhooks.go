@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/lukegriffith/SSHTrust/pkg/audit"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/lukegriffith/SSHTrust/pkg/webhook"
+)
+
+// webhookData is the data of webhook events, the audited event without its
+// place in the audit log
+type webhookData struct {
+	Actor      string            `json:"actor,omitempty"`
+	SourceIP   string            `json:"source_ip,omitempty"`
+	CA         string            `json:"ca,omitempty"`
+	Principals []string          `json:"principals,omitempty"`
+	Serial     uint64            `json:"serial,omitempty"`
+	Reason     string            `json:"reason,omitempty"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// webhookEvents returns the webhook events for an audited action
+func webhookEvents(event audit.Event) []string {
+	if event.Action == "user.login" {
+		if event.Result != audit.Success {
+			return []string{webhook.LoginFailed}
+		}
+		return nil
+	}
+	if event.Result != audit.Success {
+		return nil
+	}
+	status := cert.SigningRequestStatus(event.Details["status"])
+	switch event.Action {
+	case "ca.create":
+		return []string{webhook.CACreated}
+	case "ca.rotate":
+		return []string{webhook.CARotated}
+	case "cert.sign", "host.enroll", "host.renew":
+		return []string{webhook.CertIssued}
+	case "cert.breakglass":
+		return []string{webhook.CertBreakGlass, webhook.CertIssued}
+	case "cert.revoke":
+		return []string{webhook.CertRevoked}
+	case "request.create":
+		if status == cert.RequestPending {
+			return []string{webhook.RequestCreated}
+		}
+		return []string{webhook.CertIssued}
+	case "request.approve":
+		if status == cert.RequestApproved {
+			return []string{webhook.RequestApproved, webhook.CertIssued}
+		}
+	case "request.deny":
+		return []string{webhook.RequestDenied}
+	}
+	return nil
+}
+
+// notify publishes the webhook events for an audited action
+func (a *App) notify(c echo.Context, event audit.Event) {
+	data := webhookData{
+		Actor:      event.Actor,
+		SourceIP:   event.SourceIP,
+		CA:         event.CA,
+		Principals: event.Principals,
+		Serial:     event.Serial,
+		Reason:     event.Reason,
+		Details:    event.Details,
+	}
+	for _, eventType := range webhookEvents(event) {
+		if err := a.Webhooks.Publish(eventType, data); err != nil {
+			c.Logger().Errorf("Failed to publish %s webhook: %v", eventType, err)
+		}
+	}
+}
+
+// TestWebhooks sends a test event to every webhook
+// @Summary Test the webhooks
+// @Description Send a webhook.test event to every configured webhook straight away, without retrying, and report how each receiver answered.
+// @Tags Webhooks
+// @Produce  json
+// @Success 200 {array} webhook.Result
+// @Failure 500 {object} ErrorResponse "Failed to send test event"
+// @Router /webhooks/test [post]
+func (a *App) TestWebhooks(c echo.Context) error {
+	results, err := a.Webhooks.Test(c.Request().Context())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{"Failed to send test event"})
+	}
+	return c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,118 @@
+package krl
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newSigner(t *testing.T) ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	return signer
+}
+
+func newCert(t *testing.T, ca ssh.Signer, serial uint64, keyID string) *ssh.Certificate {
+	cert := &ssh.Certificate{
+		Key:             newSigner(t).PublicKey(),
+		Serial:          serial,
+		CertType:        ssh.UserCert,
+		KeyId:           keyID,
+		ValidPrincipals: []string{"testuser"},
+		ValidBefore:     ssh.CertTimeInfinity,
+	}
+	require.NoError(t, cert.SignCert(rand.Reader, ca))
+	return cert
+}
+
+func TestMarshalParseRoundTrip(t *testing.T) {
+	ca := newSigner(t)
+	otherCA := newSigner(t)
+	revokedKey := newSigner(t).PublicKey()
+
+	k := &KRL{
+		Version:     3,
+		GeneratedAt: time.Unix(1700000000, 0),
+		Comment:     "sshtrust",
+		Certificates: []CertificateSection{{
+			CAKey:   ca.PublicKey(),
+			Serials: []uint64{42, 7},
+			Ranges:  []SerialRange{{Min: 100, Max: 200}},
+			KeyIDs:  []string{"alice"},
+		}},
+		Keys: []ssh.PublicKey{revokedKey},
+	}
+
+	parsed, err := Parse(k.Marshal())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), parsed.Version)
+	assert.Equal(t, "sshtrust", parsed.Comment)
+	assert.Equal(t, []uint64{7, 42}, parsed.Certificates[0].Serials)
+
+	assert.True(t, parsed.IsRevoked(newCert(t, ca, 42, "bob")))
+	assert.True(t, parsed.IsRevoked(newCert(t, ca, 150, "bob")))
+	assert.True(t, parsed.IsRevoked(newCert(t, ca, 1, "alice")))
+	assert.False(t, parsed.IsRevoked(newCert(t, ca, 43, "bob")))
+	// Serials are scoped to the CA that issued them
+	assert.False(t, parsed.IsRevoked(newCert(t, otherCA, 42, "bob")))
+	assert.True(t, parsed.IsRevoked(revokedKey))
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse([]byte("not a krl"))
+	assert.ErrorIs(t, err, ErrInvalid)
+
+	data := (&KRL{Certificates: []CertificateSection{{Serials: []uint64{1}}}}).Marshal()
+	_, err = Parse(data[:len(data)-3])
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+// TestOpenSSHInterop checks ssh-keygen accepts our KRLs and we read its own
+func TestOpenSSHInterop(t *testing.T) {
+	sshKeygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen not available")
+	}
+	dir := t.TempDir()
+	ca := newSigner(t)
+	revoked := newCert(t, ca, 42, "alice")
+	valid := newCert(t, ca, 43, "bob")
+
+	writeFile := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0600))
+		return path
+	}
+	revokedPath := writeFile("revoked-cert.pub", ssh.MarshalAuthorizedKey(revoked))
+	validPath := writeFile("valid-cert.pub", ssh.MarshalAuthorizedKey(valid))
+
+	ours := writeFile("ours.krl", (&KRL{Certificates: []CertificateSection{{
+		CAKey: ca.PublicKey(), Serials: []uint64{42},
+	}}}).Marshal())
+	assert.Error(t, exec.Command(sshKeygen, "-Q", "-f", ours, revokedPath).Run(), "ssh-keygen should report the certificate revoked")
+	assert.NoError(t, exec.Command(sshKeygen, "-Q", "-f", ours, validPath).Run())
+
+	caPath := writeFile("ca.pub", ssh.MarshalAuthorizedKey(ca.PublicKey()))
+	spec := writeFile("spec", []byte("serial: 42\nserial: 1000-2000\nid: carol\n"))
+	theirs := filepath.Join(dir, "theirs.krl")
+	out, err := exec.Command(sshKeygen, "-k", "-f", theirs, "-s", caPath, spec).CombinedOutput()
+	require.NoError(t, err, string(out))
+	data, err := os.ReadFile(theirs)
+	require.NoError(t, err)
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.True(t, parsed.IsRevoked(revoked))
+	assert.True(t, parsed.IsRevoked(newCert(t, ca, 1500, "dave")))
+	assert.True(t, parsed.IsRevoked(newCert(t, ca, 1, "carol")))
+	assert.False(t, parsed.IsRevoked(valid))
+}
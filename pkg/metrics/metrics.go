@@ -0,0 +1,139 @@
+// Package metrics exposes SSHTrust's signing, login and request metrics for
+// Prometheus
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	echo "github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Sign outcomes, the outcome label of sshtrust_sign_requests_total
+const (
+	SignIssued            = "issued"
+	SignTTLTooLong        = "ttl_too_long"
+	SignInvalidPrincipals = "invalid_principals"
+	SignParseFailed       = "parse_failed"
+	SignKeyPolicy         = "key_policy"
+	SignInvalidValidity   = "invalid_validity"
+	SignNeedsApproval     = "needs_approval"
+	SignCANotFound        = "ca_not_found"
+	SignInvalidRequest    = "invalid_request"
+	SignError             = "error"
+)
+
+// ExpiringWithin is how close to expiry a host certificate counts as soon to
+// expire
+const ExpiringWithin = 24 * time.Hour
+
+// Sources are read when the gauges are scraped
+type Sources struct {
+	CAs   func() int
+	Users func() int
+	// Host certificates expiring within ExpiringWithin
+	ExpiringHostCerts func() int
+}
+
+// Metrics holds the server's collectors. A nil Metrics records nothing.
+type Metrics struct {
+	registry        *prometheus.Registry
+	signs           *prometheus.CounterVec
+	logins          *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+}
+
+// New registers the collectors, with gauges read from sources
+func New(sources Sources) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		signs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sshtrust_sign_requests_total",
+			Help: "Signing requests to /CA/{id}/Sign by CA and outcome.",
+		}, []string{"ca", "outcome"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "sshtrust_logins_total",
+			Help: "Login attempts by result, success or failure.",
+		}, []string{"result"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "sshtrust_http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "code"}),
+	}
+	m.registry.MustRegister(
+		m.signs,
+		m.logins,
+		m.requestDuration,
+		gauge("sshtrust_cas", "Certificate authorities.", sources.CAs),
+		gauge("sshtrust_users", "Registered users.", sources.Users),
+		gauge("sshtrust_host_certificates_expiring", "Enrolled host certificates expiring within 24 hours, or already expired.", sources.ExpiringHostCerts),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+func gauge(name, help string, source func() int) prometheus.Collector {
+	return prometheus.NewGaugeFunc(prometheus.GaugeOpts{Name: name, Help: help}, func() float64 {
+		if source == nil {
+			return 0
+		}
+		return float64(source())
+	})
+}
+
+// ObserveSign counts a signing request to ca with outcome
+func (m *Metrics) ObserveSign(ca, outcome string) {
+	if m == nil {
+		return
+	}
+	m.signs.WithLabelValues(ca, outcome).Inc()
+}
+
+// ObserveLogin counts a login attempt
+func (m *Metrics) ObserveLogin(success bool) {
+	if m == nil {
+		return
+	}
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	m.logins.WithLabelValues(result).Inc()
+}
+
+// Middleware records the latency of every request by its route, so paths
+// with IDs do not each get their own series
+func (m *Metrics) Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if m == nil {
+				return next(c)
+			}
+			start := time.Now()
+			err := next(c)
+			status := c.Response().Status
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				status = httpErr.Code
+			} else if err != nil {
+				status = http.StatusInternalServerError
+			}
+			route := c.Path()
+			if route == "" {
+				route = "unmatched"
+			}
+			m.requestDuration.WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+			return err
+		}
+	}
+}
+
+// Handler serves the metrics in the Prometheus text format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
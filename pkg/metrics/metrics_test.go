@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	return rec.Body.String()
+}
+
+func TestMetrics(t *testing.T) {
+	m := New(Sources{
+		CAs:               func() int { return 3 },
+		Users:             func() int { return 7 },
+		ExpiringHostCerts: func() int { return 2 },
+	})
+	m.ObserveLogin(true)
+	m.ObserveLogin(false)
+	m.ObserveLogin(false)
+
+	e := echo.New()
+	e.Use(m.Middleware())
+	e.GET("/CA/:id", func(c echo.Context) error { return c.NoContent(http.StatusOK) })
+	e.GET("/hosts/:name", func(c echo.Context) error { return echo.ErrNotFound })
+	for _, target := range []string{"/CA/one", "/CA/two", "/hosts/web1"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `sshtrust_logins_total{result="success"} 1`)
+	assert.Contains(t, body, `sshtrust_logins_total{result="failure"} 2`)
+	assert.Contains(t, body, "sshtrust_cas 3")
+	assert.Contains(t, body, "sshtrust_users 7")
+	assert.Contains(t, body, "sshtrust_host_certificates_expiring 2")
+	// Requests are labelled by route, not path
+	assert.Contains(t, body, `sshtrust_http_request_duration_seconds_count{code="200",method="GET",route="/CA/:id"} 2`)
+	assert.Contains(t, body, `sshtrust_http_request_duration_seconds_count{code="404",method="GET",route="/hosts/:name"} 1`)
+	assert.NotContains(t, body, "/CA/one")
+
+	// A nil Metrics records nothing
+	var discard *Metrics
+	discard.ObserveSign("prod-ca", SignIssued)
+	discard.ObserveLogin(true)
+}
@@ -0,0 +1,245 @@
+// Package sshserver authenticates users presenting SSHTrust certificates
+// to SSH servers built on golang.org/x/crypto/ssh, with the same checks
+// sshd makes for TrustedUserCAKeys and RevokedKeys.
+package sshserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/lukegriffith/SSHTrust/pkg/krl"
+	"github.com/lukegriffith/SSHTrust/pkg/verify"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	DefaultRefreshInterval = 5 * time.Minute
+	DefaultMaxStaleness    = time.Hour
+
+	// Extensions added to the returned ssh.Permissions describing the
+	// certificate that authenticated the connection.
+	ExtensionCA     = "sshtrust-ca@sshtrust"
+	ExtensionKeyID  = "sshtrust-key-id@sshtrust"
+	ExtensionSerial = "sshtrust-serial@sshtrust"
+)
+
+// DefaultCriticalOptions are the critical options a server is assumed to
+// enforce. x/crypto/ssh enforces source-address itself, force-command is
+// passed through in Permissions.CriticalOptions for the server to honour.
+//
+// verify-required, added by CAs whose key policy requires user verification
+// on security keys, is left out: x/crypto/ssh does not check the security
+// key's flags, so such certificates are refused with ErrUserVerification.
+var DefaultCriticalOptions = []string{"force-command", "source-address"}
+
+var ErrNotLoaded = errors.New("sshtrust CA keys could not be loaded")
+
+// ErrUserVerification is returned for certificates requiring security key
+// user verification when the server does not list cert.VerifyRequired in
+// its supported critical options
+var ErrUserVerification = errors.New("certificate requires security key user verification (verify-required), which this server does not enforce")
+
+// Config configures an Authenticator
+type Config struct {
+	// Client used to fetch CA keys and revocation lists
+	Client *sshtrust.Client
+	// Names of the SSHTrust CAs to trust
+	CAs []string
+	// How long CA keys and KRLs are used before being fetched again,
+	// defaults to DefaultRefreshInterval
+	RefreshInterval time.Duration
+	// How long cached keys keep being used while the server can not be
+	// reached, after which logins are refused. Defaults to DefaultMaxStaleness.
+	MaxStaleness time.Duration
+	// Critical options the server enforces, certificates with any other
+	// critical option are rejected. Defaults to DefaultCriticalOptions.
+	SupportedCriticalOptions []string
+	// Clock used for validity checks, defaults to time.Now
+	Clock func() time.Time
+	// Logger for refresh failures, defaults to the standard logger
+	Logger *log.Logger
+}
+
+// Authenticator verifies certificates against cached SSHTrust CA keys and
+// KRLs, refreshing them from the API as they go stale.
+type Authenticator struct {
+	config   Config
+	verifier *verify.Verifier
+
+	// refresh serialises fetches so concurrent logins share one refresh
+	refresh sync.Mutex
+
+	mu          sync.RWMutex
+	authorities verify.AuthorityList
+	krls        map[string]*krl.KRL
+	fetchedAt   time.Time
+}
+
+// New returns an Authenticator for the CAs in config. Keys are fetched on
+// first use, call Refresh to load them up front.
+func New(config Config) (*Authenticator, error) {
+	if config.Client == nil {
+		return nil, errors.New("sshserver: a client is required")
+	}
+	if len(config.CAs) == 0 {
+		return nil, errors.New("sshserver: at least one CA is required")
+	}
+	if config.RefreshInterval == 0 {
+		config.RefreshInterval = DefaultRefreshInterval
+	}
+	if config.MaxStaleness == 0 {
+		config.MaxStaleness = DefaultMaxStaleness
+	}
+	if config.SupportedCriticalOptions == nil {
+		config.SupportedCriticalOptions = DefaultCriticalOptions
+	}
+	if config.Clock == nil {
+		config.Clock = time.Now
+	}
+	if config.Logger == nil {
+		config.Logger = log.Default()
+	}
+
+	a := &Authenticator{config: config}
+	a.verifier = &verify.Verifier{
+		Authorities:              a,
+		Revocations:              a,
+		SupportedCriticalOptions: config.SupportedCriticalOptions,
+		Clock:                    config.Clock,
+	}
+	return a, nil
+}
+
+// ServerConfig returns an ssh.ServerConfig authenticating users with
+// certificates. Host keys still need adding with AddHostKey.
+func (a *Authenticator) ServerConfig() *ssh.ServerConfig {
+	return &ssh.ServerConfig{PublicKeyCallback: a.PublicKeyCallback}
+}
+
+// Refresh fetches the CA keys and KRLs from the API. On failure the
+// previously fetched keys are kept.
+func (a *Authenticator) Refresh(ctx context.Context) error {
+	authorities := verify.AuthorityList{}
+	krls := make(map[string]*krl.KRL)
+	for _, name := range a.config.CAs {
+		ca, err := a.config.Client.GetCA(ctx, name)
+		if err != nil {
+			return err
+		}
+		keys, err := ca.TrustedKeys()
+		if err != nil {
+			return err
+		}
+		data, err := a.config.Client.KRL(ctx, name)
+		if err != nil {
+			return err
+		}
+		list, err := krl.Parse(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse KRL of CA %s: %w", name, err)
+		}
+		// Retiring keys are trusted until the CA's rotation completes
+		for _, key := range keys {
+			authorities = append(authorities, verify.Authority{Name: name, Key: key, ValidPrincipals: ca.ValidPrincipals})
+		}
+		krls[name] = list
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.authorities = authorities
+	a.krls = krls
+	a.fetchedAt = a.config.Clock()
+	return nil
+}
+
+// ensureFresh refreshes the cache once RefreshInterval has passed, and
+// fails once it is older than MaxStaleness.
+func (a *Authenticator) ensureFresh() error {
+	a.refresh.Lock()
+	defer a.refresh.Unlock()
+
+	a.mu.RLock()
+	fetchedAt := a.fetchedAt
+	a.mu.RUnlock()
+	now := a.config.Clock()
+	if !fetchedAt.IsZero() && now.Sub(fetchedAt) < a.config.RefreshInterval {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	err := a.Refresh(ctx)
+	if err == nil {
+		return nil
+	}
+	if fetchedAt.IsZero() || now.Sub(fetchedAt) >= a.config.MaxStaleness {
+		return fmt.Errorf("%w: %v", ErrNotLoaded, err)
+	}
+	a.config.Logger.Printf("sshtrust: using cached CA keys, refresh failed: %v", err)
+	return nil
+}
+
+// LookupAuthority implements verify.Authorities from the cached CA keys
+func (a *Authenticator) LookupAuthority(key ssh.PublicKey) (*verify.Authority, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.authorities.LookupAuthority(key)
+}
+
+// IsRevoked implements verify.RevocationChecker from the cached KRLs
+func (a *Authenticator) IsRevoked(caName string, cert *ssh.Certificate) (bool, error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	list, ok := a.krls[caName]
+	if !ok {
+		return false, fmt.Errorf("no KRL loaded for CA %s", caName)
+	}
+	return list.IsRevoked(cert), nil
+}
+
+// PublicKeyCallback authenticates conn's user with a certificate, for use
+// as ssh.ServerConfig.PublicKeyCallback. The returned Permissions carry the
+// certificate's critical options and extensions along with the ExtensionCA,
+// ExtensionKeyID and ExtensionSerial extensions.
+func (a *Authenticator) PublicKeyCallback(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+	if err := a.ensureFresh(); err != nil {
+		return nil, err
+	}
+	if presented, ok := key.(*ssh.Certificate); ok {
+		if _, required := presented.CriticalOptions[cert.VerifyRequired]; required && !slices.Contains(a.config.SupportedCriticalOptions, cert.VerifyRequired) {
+			return nil, ErrUserVerification
+		}
+	}
+	cert, authority, err := a.verifier.Verify(key, conn.User())
+	if err != nil {
+		return nil, err
+	}
+	return Permissions(cert, authority), nil
+}
+
+// Permissions builds the ssh.Permissions granted by a verified certificate
+func Permissions(cert *ssh.Certificate, authority *verify.Authority) *ssh.Permissions {
+	perms := &ssh.Permissions{
+		CriticalOptions: make(map[string]string, len(cert.CriticalOptions)),
+		Extensions:      make(map[string]string, len(cert.Extensions)+3),
+	}
+	for k, v := range cert.CriticalOptions {
+		perms.CriticalOptions[k] = v
+	}
+	for k, v := range cert.Extensions {
+		perms.Extensions[k] = v
+	}
+	perms.Extensions[ExtensionCA] = authority.Name
+	perms.Extensions[ExtensionKeyID] = cert.KeyId
+	perms.Extensions[ExtensionSerial] = strconv.FormatUint(cert.Serial, 10)
+	return perms
+}
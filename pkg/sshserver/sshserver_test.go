@@ -0,0 +1,229 @@
+package sshserver
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/internal/server"
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	sshtrust "github.com/lukegriffith/SSHTrust/pkg/client"
+	"github.com/lukegriffith/SSHTrust/pkg/krl"
+	"github.com/lukegriffith/SSHTrust/pkg/verify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+)
+
+func newSigner(t *testing.T) ssh.Signer {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(priv)
+	require.NoError(t, err)
+	return signer
+}
+
+// login runs an SSH handshake over loopback, returning the permissions the
+// server granted.
+func login(t *testing.T, config *ssh.ServerConfig, user string, signer ssh.Signer) (*ssh.Permissions, error) {
+	config.AddHostKey(newSigner(t))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	type result struct {
+		perms *ssh.Permissions
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		serverConn, err := listener.Accept()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		defer serverConn.Close()
+		conn, chans, reqs, err := ssh.NewServerConn(serverConn, config)
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		go ssh.DiscardRequests(reqs)
+		go func() {
+			for ch := range chans {
+				_ = ch.Reject(ssh.Prohibited, "no channels")
+			}
+		}()
+		done <- result{perms: conn.Permissions}
+		_ = conn.Close()
+	}()
+
+	clientConn, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	defer clientConn.Close()
+	conn, chans, reqs, err := ssh.NewClientConn(clientConn, listener.Addr().String(), &ssh.ClientConfig{
+		User:            user,
+		Auth:            []ssh.AuthMethod{ssh.PublicKeys(signer)},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+	})
+	if err == nil {
+		_ = ssh.NewClient(conn, chans, reqs).Close()
+	}
+	r := <-done
+	return r.perms, r.err
+}
+
+func signedSigner(t *testing.T, ctx context.Context, c *sshtrust.Client, caID string, principals []string) (ssh.Signer, *ssh.Certificate) {
+	userKey := newSigner(t)
+	signed, err := c.Sign(ctx, caID, cert.SignRequest{
+		PublicKey:  string(ssh.MarshalAuthorizedKey(userKey.PublicKey())),
+		Principals: principals,
+		TTLMinutes: 60,
+	})
+	require.NoError(t, err)
+	parsed, err := cert.ParseCertificate([]byte(signed.SignedKey))
+	require.NoError(t, err)
+	certSigner, err := ssh.NewCertSigner(parsed, userKey)
+	require.NoError(t, err)
+	return certSigner, parsed
+}
+
+func TestAuthenticatorAgainstServer(t *testing.T) {
+	ts := httptest.NewServer(server.SetupServer(true))
+	defer ts.Close()
+	ctx := context.Background()
+	c := sshtrust.New(ts.URL)
+
+	for _, name := range []string{"myca", "otherca"} {
+		_, err := c.CreateCA(ctx, cert.CaRequest{CommonCa: cert.CommonCa{
+			Name: name, Type: cert.ED25519, ValidPrincipals: []string{"testuser", "admin"}, MaxTTLMinutes: 60,
+		}})
+		require.NoError(t, err)
+	}
+
+	auth, err := New(Config{Client: c, CAs: []string{"myca"}, Logger: log.New(io.Discard, "", 0)})
+	require.NoError(t, err)
+
+	signer, signed := signedSigner(t, ctx, c, "myca", []string{"testuser"})
+	perms, err := login(t, auth.ServerConfig(), "testuser", signer)
+	require.NoError(t, err)
+	assert.Equal(t, "myca", perms.Extensions[ExtensionCA])
+	assert.Equal(t, signed.KeyId, perms.Extensions[ExtensionKeyID])
+	assert.Equal(t, strconv.FormatUint(signed.Serial, 10), perms.Extensions[ExtensionSerial])
+	assert.Contains(t, perms.Extensions, "permit-pty")
+
+	// Principals not on the certificate are refused
+	_, err = login(t, auth.ServerConfig(), "admin", signer)
+	assert.Error(t, err)
+
+	// CAs that are not trusted are refused
+	otherSigner, _ := signedSigner(t, ctx, c, "otherca", []string{"testuser"})
+	_, err = login(t, auth.ServerConfig(), "testuser", otherSigner)
+	assert.Error(t, err)
+
+	// Revocations are picked up on the next refresh
+	require.NoError(t, c.Revoke(ctx, "myca", signed.Serial))
+	_, err = login(t, auth.ServerConfig(), "testuser", signer)
+	assert.NoError(t, err, "cached KRL should still be in use")
+	require.NoError(t, auth.Refresh(ctx))
+	_, err = login(t, auth.ServerConfig(), "testuser", signer)
+	assert.Error(t, err)
+}
+
+func TestAuthenticatorStaleness(t *testing.T) {
+	ts := httptest.NewServer(server.SetupServer(true))
+	ctx := context.Background()
+	c := sshtrust.New(ts.URL)
+	_, err := c.CreateCA(ctx, cert.CaRequest{CommonCa: cert.CommonCa{
+		Name: "myca", Type: cert.ED25519, ValidPrincipals: []string{"testuser"}, MaxTTLMinutes: 60,
+	}})
+	require.NoError(t, err)
+
+	now := time.Now()
+	auth, err := New(Config{
+		Client: c,
+		CAs:    []string{"myca"},
+		Clock:  func() time.Time { return now },
+		Logger: log.New(io.Discard, "", 0),
+	})
+	require.NoError(t, err)
+	signer, _ := signedSigner(t, ctx, c, "myca", []string{"testuser"})
+	_, err = login(t, auth.ServerConfig(), "testuser", signer)
+	require.NoError(t, err)
+
+	// With the server gone cached keys are used until MaxStaleness
+	ts.Close()
+	now = now.Add(DefaultRefreshInterval + time.Minute)
+	_, err = login(t, auth.ServerConfig(), "testuser", signer)
+	assert.NoError(t, err)
+
+	now = now.Add(DefaultMaxStaleness)
+	assert.True(t, errors.Is(auth.ensureFresh(), ErrNotLoaded))
+}
+
+func TestPublicKeyCallbackCriticalOptions(t *testing.T) {
+	ca := newSigner(t)
+	auth, err := New(Config{Client: sshtrust.New("http://unused"), CAs: []string{"local"}})
+	require.NoError(t, err)
+	auth.authorities = verify.AuthorityList{{Name: "local", Key: ca.PublicKey()}}
+	auth.krls = nil
+	auth.fetchedAt = time.Now()
+
+	newCert := func(options map[string]string) ssh.Signer {
+		userKey := newSigner(t)
+		c := &ssh.Certificate{
+			Key:             userKey.PublicKey(),
+			Serial:          1,
+			CertType:        ssh.UserCert,
+			ValidPrincipals: []string{"testuser"},
+			ValidBefore:     ssh.CertTimeInfinity,
+			Permissions:     ssh.Permissions{CriticalOptions: options},
+		}
+		require.NoError(t, c.SignCert(rand.Reader, ca))
+		signer, err := ssh.NewCertSigner(c, userKey)
+		require.NoError(t, err)
+		return signer
+	}
+
+	// Without a KRL revocation status is unknown, so logins fail closed
+	_, err = login(t, auth.ServerConfig(), "testuser", newCert(nil))
+	assert.Error(t, err)
+
+	auth.krls = map[string]*krl.KRL{"local": {}}
+	perms, err := login(t, auth.ServerConfig(), "testuser", newCert(map[string]string{"force-command": "/bin/true"}))
+	require.NoError(t, err)
+	assert.Equal(t, "/bin/true", perms.CriticalOptions["force-command"])
+
+	_, err = login(t, auth.ServerConfig(), "testuser", newCert(map[string]string{"unknown-option": "x"}))
+	assert.Error(t, err)
+
+	// verify-required is refused with a reason unless the server enforces it
+	verified := newCert(map[string]string{cert.VerifyRequired: ""}).PublicKey()
+	_, err = auth.PublicKeyCallback(userConn{user: "testuser"}, verified)
+	assert.ErrorIs(t, err, ErrUserVerification)
+
+	supported := []string{"force-command", "source-address", cert.VerifyRequired}
+	auth.config.SupportedCriticalOptions = supported
+	auth.verifier.SupportedCriticalOptions = supported
+	perms, err = auth.PublicKeyCallback(userConn{user: "testuser"}, verified)
+	require.NoError(t, err)
+	assert.Contains(t, perms.CriticalOptions, cert.VerifyRequired)
+}
+
+// userConn is the connection metadata PublicKeyCallback reads
+type userConn struct {
+	ssh.ConnMetadata
+	user string
+}
+
+func (c userConn) User() string {
+	return c.user
+}
@@ -0,0 +1,146 @@
+// Package verify validates SSH user certificates against SSHTrust CAs,
+// for Go SSH servers that want the same checks as the /CA/:id/verify API.
+package verify
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	ErrNotCertificate   = errors.New("key is not a certificate")
+	ErrNotUserCert      = errors.New("certificate is not a user certificate")
+	ErrUnknownAuthority = errors.New("certificate signed by unknown authority")
+	ErrNoPrincipals     = errors.New("certificate has no principals")
+	ErrPrincipalDenied  = errors.New("principal not permitted by CA")
+	ErrSignatureAlgo    = errors.New("certificate signature algorithm not accepted")
+)
+
+// DefaultSignatureAlgorithms are the CA signature algorithms accepted by
+// default, as OpenSSH's CASignatureAlgorithms: everything but ssh-rsa (SHA-1)
+var DefaultSignatureAlgorithms = []string{
+	ssh.KeyAlgoED25519,
+	ssh.KeyAlgoECDSA256, ssh.KeyAlgoECDSA384, ssh.KeyAlgoECDSA521,
+	ssh.KeyAlgoSKED25519, ssh.KeyAlgoSKECDSA256,
+	ssh.KeyAlgoRSASHA512, ssh.KeyAlgoRSASHA256,
+}
+
+// Authority is a CA trusted by the Verifier
+type Authority struct {
+	// Name of the CA in SSHTrust
+	Name string
+	// CA public key
+	Key ssh.PublicKey
+	// Principals the CA may issue, empty allows any principal
+	ValidPrincipals []string
+}
+
+// Authorities resolves the CA that owns a signing key
+type Authorities interface {
+	// LookupAuthority returns the CA owning key, or false when it is not trusted
+	LookupAuthority(key ssh.PublicKey) (*Authority, bool)
+}
+
+// AuthorityList is a fixed set of trusted CAs
+type AuthorityList []Authority
+
+func (l AuthorityList) LookupAuthority(key ssh.PublicKey) (*Authority, bool) {
+	for i := range l {
+		if string(l[i].Key.Marshal()) == string(key.Marshal()) {
+			return &l[i], true
+		}
+	}
+	return nil, false
+}
+
+// RevocationChecker reports whether a certificate issued by a CA is revoked
+type RevocationChecker interface {
+	IsRevoked(caName string, cert *ssh.Certificate) (bool, error)
+}
+
+// Verifier checks user certificates with ssh.CertChecker, adding CA
+// lookup, principal policy and revocation.
+type Verifier struct {
+	Authorities Authorities
+	// Revocation status, nil skips the check
+	Revocations RevocationChecker
+	// Critical options the caller knows how to enforce, others are rejected
+	SupportedCriticalOptions []string
+	// CA signature algorithms accepted, DefaultSignatureAlgorithms when empty
+	SignatureAlgorithms []string
+	// Clock used for validity checks, defaults to time.Now
+	Clock func() time.Time
+}
+
+// VerifyUser checks cert is a valid, unrevoked user certificate for
+// principal signed by a trusted CA, and returns that CA.
+func (v *Verifier) VerifyUser(cert *ssh.Certificate, principal string) (*Authority, error) {
+	if cert.CertType != ssh.UserCert {
+		return nil, ErrNotUserCert
+	}
+	authority, ok := v.Authorities.LookupAuthority(cert.SignatureKey)
+	if !ok {
+		return nil, ErrUnknownAuthority
+	}
+	algorithms := v.SignatureAlgorithms
+	if len(algorithms) == 0 {
+		algorithms = DefaultSignatureAlgorithms
+	}
+	if cert.Signature == nil || !contains(algorithms, cert.Signature.Format) {
+		return authority, ErrSignatureAlgo
+	}
+	// ssh.CertChecker treats an empty list as valid for everyone
+	if len(cert.ValidPrincipals) == 0 {
+		return authority, ErrNoPrincipals
+	}
+	if len(authority.ValidPrincipals) > 0 && !contains(authority.ValidPrincipals, principal) {
+		return authority, fmt.Errorf("%w: %s", ErrPrincipalDenied, principal)
+	}
+
+	var revocationErr error
+	checker := &ssh.CertChecker{
+		SupportedCriticalOptions: v.SupportedCriticalOptions,
+		Clock:                    v.Clock,
+		IsRevoked: func(cert *ssh.Certificate) bool {
+			if v.Revocations == nil {
+				return false
+			}
+			revoked, err := v.Revocations.IsRevoked(authority.Name, cert)
+			if err != nil {
+				// Fail closed when revocation status is unknown
+				revocationErr = err
+				return true
+			}
+			return revoked
+		},
+	}
+	if err := checker.CheckCert(principal, cert); err != nil {
+		if revocationErr != nil {
+			return authority, fmt.Errorf("could not check revocation status: %w", revocationErr)
+		}
+		return authority, err
+	}
+	return authority, nil
+}
+
+// Verify parses key as a certificate and verifies it with VerifyUser
+func (v *Verifier) Verify(key ssh.PublicKey, principal string) (*ssh.Certificate, *Authority, error) {
+	cert, ok := key.(*ssh.Certificate)
+	if !ok {
+		return nil, nil, ErrNotCertificate
+	}
+	authority, err := v.VerifyUser(cert, principal)
+	return cert, authority, err
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package verify
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lukegriffith/SSHTrust/pkg/cert"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+type revokedSerials map[uint64]bool
+
+func (r revokedSerials) IsRevoked(caName string, c *ssh.Certificate) (bool, error) {
+	return r[c.Serial], nil
+}
+
+type failingRevocations struct{}
+
+func (failingRevocations) IsRevoked(caName string, c *ssh.Certificate) (bool, error) {
+	return false, errors.New("revocation store unavailable")
+}
+
+func TestVerifyUser(t *testing.T) {
+	caSigner, err := cert.GenerateSSHKey(cert.ED25519, 0)
+	assert.NoError(t, err)
+	otherSigner, err := cert.GenerateSSHKey(cert.ED25519, 0)
+	assert.NoError(t, err)
+	userSigner, err := cert.GenerateSSHKey(cert.ED25519, 0)
+	assert.NoError(t, err)
+
+	authorities := AuthorityList{{Name: "test-ca", Key: caSigner.PublicKey(), ValidPrincipals: []string{"alice", "bob"}}}
+
+	valid, _ := cert.SignUserKey(caSigner, userSigner.PublicKey(), []string{"alice"}, 30)
+	revoked, _ := cert.SignUserKey(caSigner, userSigner.PublicKey(), []string{"alice"}, 30)
+	unknown, _ := cert.SignUserKey(otherSigner, userSigner.PublicKey(), []string{"alice"}, 30)
+	outsidePolicy, _ := cert.SignUserKey(caSigner, userSigner.PublicKey(), []string{"root"}, 30)
+
+	hostCert := &ssh.Certificate{Key: userSigner.PublicKey(), CertType: ssh.HostCert, ValidPrincipals: []string{"alice"}, ValidBefore: ssh.CertTimeInfinity}
+	assert.NoError(t, hostCert.SignCert(rand.Reader, caSigner))
+
+	noPrincipals := &ssh.Certificate{Key: userSigner.PublicKey(), CertType: ssh.UserCert, ValidBefore: ssh.CertTimeInfinity}
+	assert.NoError(t, noPrincipals.SignCert(rand.Reader, caSigner))
+
+	forceCommand := &ssh.Certificate{
+		Key: userSigner.PublicKey(), CertType: ssh.UserCert, ValidPrincipals: []string{"alice"}, ValidBefore: ssh.CertTimeInfinity,
+		Permissions: ssh.Permissions{CriticalOptions: map[string]string{"force-command": "/bin/true"}},
+	}
+	assert.NoError(t, forceCommand.SignCert(rand.Reader, caSigner))
+
+	tests := []struct {
+		name      string
+		cert      *ssh.Certificate
+		principal string
+		clock     func() time.Time
+		errText   string
+		errIs     error
+	}{
+		{name: "Valid", cert: valid, principal: "alice"},
+		{name: "Wrong principal", cert: valid, principal: "bob", errText: "not in the set of valid principals"},
+		{name: "Principal outside CA policy", cert: outsidePolicy, principal: "root", errIs: ErrPrincipalDenied},
+		{name: "Unknown CA", cert: unknown, principal: "alice", errIs: ErrUnknownAuthority},
+		{name: "Revoked", cert: revoked, principal: "alice", errText: "revoked"},
+		{name: "Expired", cert: valid, principal: "alice", clock: func() time.Time { return time.Now().Add(time.Hour) }, errText: "expired"},
+		{name: "Host certificate", cert: hostCert, principal: "alice", errIs: ErrNotUserCert},
+		{name: "No principals", cert: noPrincipals, principal: "alice", errIs: ErrNoPrincipals},
+		{name: "Unsupported critical option", cert: forceCommand, principal: "alice", errText: "unsupported critical option"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			verifier := &Verifier{
+				Authorities: authorities,
+				Revocations: revokedSerials{revoked.Serial: true},
+				Clock:       tt.clock,
+			}
+			authority, err := verifier.VerifyUser(tt.cert, tt.principal)
+			switch {
+			case tt.errIs != nil:
+				assert.ErrorIs(t, err, tt.errIs)
+			case tt.errText != "":
+				assert.ErrorContains(t, err, tt.errText)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, "test-ca", authority.Name)
+			}
+		})
+	}
+}
+
+func TestVerifyFailsClosedOnRevocationError(t *testing.T) {
+	caSigner, _ := cert.GenerateSSHKey(cert.ED25519, 0)
+	userSigner, _ := cert.GenerateSSHKey(cert.ED25519, 0)
+	signed, _ := cert.SignUserKey(caSigner, userSigner.PublicKey(), []string{"alice"}, 30)
+
+	verifier := &Verifier{
+		Authorities: AuthorityList{{Name: "test-ca", Key: caSigner.PublicKey()}},
+		Revocations: failingRevocations{},
+	}
+	_, err := verifier.VerifyUser(signed, "alice")
+	assert.ErrorContains(t, err, "revocation store unavailable")
+}
+
+func TestVerifyRejectsPlainKeys(t *testing.T) {
+	userSigner, _ := cert.GenerateSSHKey(cert.ED25519, 0)
+
+	verifier := &Verifier{Authorities: AuthorityList{}}
+	_, _, err := verifier.Verify(userSigner.PublicKey(), "alice")
+	assert.ErrorIs(t, err, ErrNotCertificate)
+}
+
+func TestVerifyRejectsSHA1Signatures(t *testing.T) {
+	key, _ := cert.GenerateSSHKey(cert.RSAKey, 2048)
+	userSigner, _ := cert.GenerateSSHKey(cert.ED25519, 0)
+	sha1Signer, err := ssh.NewSignerWithAlgorithms(key.(ssh.AlgorithmSigner), []string{ssh.KeyAlgoRSA})
+	assert.NoError(t, err)
+	sha1Cert := &ssh.Certificate{Key: userSigner.PublicKey(), CertType: ssh.UserCert, ValidPrincipals: []string{"alice"}, ValidBefore: ssh.CertTimeInfinity}
+	assert.NoError(t, sha1Cert.SignCert(rand.Reader, sha1Signer))
+
+	verifier := &Verifier{Authorities: AuthorityList{{Name: "test-ca", Key: key.PublicKey()}}}
+	_, err = verifier.VerifyUser(sha1Cert, "alice")
+	assert.ErrorIs(t, err, ErrSignatureAlgo)
+
+	// SHA-2 signatures from the same key are accepted
+	caSigner, err := cert.NewCASigner(key, ssh.KeyAlgoRSASHA256)
+	assert.NoError(t, err)
+	signed, err := cert.SignUserKey(caSigner, userSigner.PublicKey(), []string{"alice"}, 30)
+	assert.NoError(t, err)
+	_, err = verifier.VerifyUser(signed, "alice")
+	assert.NoError(t, err)
+
+	// Verifiers can narrow the accepted algorithms
+	verifier.SignatureAlgorithms = []string{ssh.KeyAlgoRSASHA512}
+	_, err = verifier.VerifyUser(signed, "alice")
+	assert.ErrorIs(t, err, ErrSignatureAlgo)
+}
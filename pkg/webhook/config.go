@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the webhooks file read by the server
+type Config struct {
+	Hooks []Hook `yaml:"hooks"`
+}
+
+// LoadHooks reads the hooks from the webhooks file at path. The file holds
+// the signing secrets, keep it readable only by the server.
+func LoadHooks(path string) ([]Hook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read webhooks file: %w", err)
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse webhooks file: %w", err)
+	}
+	seen := map[string]bool{}
+	for _, hook := range config.Hooks {
+		parsed, err := url.Parse(hook.URL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, fmt.Errorf("webhook url %q must be an http or https URL", hook.URL)
+		}
+		if seen[hook.URL] {
+			return nil, fmt.Errorf("webhook url %s is listed twice", hook.URL)
+		}
+		seen[hook.URL] = true
+		if hook.Secret == "" {
+			return nil, fmt.Errorf("webhook %s needs a secret to sign its payloads", hook.URL)
+		}
+		for _, eventType := range hook.Events {
+			if !isEventType(eventType) {
+				return nil, fmt.Errorf("webhook %s: unknown event type %s", hook.URL, eventType)
+			}
+		}
+	}
+	return config.Hooks, nil
+}
+
+// EventTypes are the events hooks can ask for
+var EventTypes = []string{CACreated, CARotated, CertIssued, CertRevoked, CertBreakGlass, LoginFailed, RequestCreated, RequestApproved, RequestDenied}
+
+func isEventType(eventType string) bool {
+	for _, known := range EventTypes {
+		if known == eventType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Queue holds deliveries until their receiver accepts them
+type Queue interface {
+	// Put adds the delivery, or replaces it when it is already queued
+	Put(delivery Delivery) error
+	// List returns the queued deliveries, the soonest due first
+	List() ([]Delivery, error)
+	Remove(id string) error
+}
+
+// MemoryQueue is a Queue that is lost when the server stops
+type MemoryQueue struct {
+	sync.Mutex
+	deliveries map[string]Delivery
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{deliveries: map[string]Delivery{}}
+}
+
+func (q *MemoryQueue) Put(delivery Delivery) error {
+	q.Lock()
+	defer q.Unlock()
+	q.deliveries[delivery.ID] = delivery
+	return nil
+}
+
+func (q *MemoryQueue) List() ([]Delivery, error) {
+	q.Lock()
+	defer q.Unlock()
+	deliveries := make([]Delivery, 0, len(q.deliveries))
+	for _, delivery := range q.deliveries {
+		deliveries = append(deliveries, delivery)
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (q *MemoryQueue) Remove(id string) error {
+	q.Lock()
+	defer q.Unlock()
+	delete(q.deliveries, id)
+	return nil
+}
+
+// FileQueue is a Queue kept in a directory, one file per delivery, so
+// deliveries survive a restart
+type FileQueue struct {
+	sync.Mutex
+	dir string
+}
+
+// NewFileQueue returns a queue in dir, creating it if needed
+func NewFileQueue(dir string) (*FileQueue, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create webhook queue: %w", err)
+	}
+	return &FileQueue{dir: dir}, nil
+}
+
+func (q *FileQueue) path(id string) string {
+	return filepath.Join(q.dir, id+".json")
+}
+
+func (q *FileQueue) Put(delivery Delivery) error {
+	q.Lock()
+	defer q.Unlock()
+	encoded, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+	// Written aside and renamed, so a crash never leaves half a delivery
+	tmp := q.path(delivery.ID) + ".tmp"
+	if err := os.WriteFile(tmp, encoded, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, q.path(delivery.ID))
+}
+
+func (q *FileQueue) List() ([]Delivery, error) {
+	q.Lock()
+	defer q.Unlock()
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []Delivery{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		encoded, err := os.ReadFile(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var delivery Delivery
+		if err := json.Unmarshal(encoded, &delivery); err != nil {
+			return nil, fmt.Errorf("invalid webhook delivery %s: %w", entry.Name(), err)
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sortDeliveries(deliveries)
+	return deliveries, nil
+}
+
+func (q *FileQueue) Remove(id string) error {
+	q.Lock()
+	defer q.Unlock()
+	if err := os.Remove(q.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func sortDeliveries(deliveries []Delivery) {
+	sort.Slice(deliveries, func(i, j int) bool {
+		if deliveries[i].NextAttempt.Equal(deliveries[j].NextAttempt) {
+			return deliveries[i].ID < deliveries[j].ID
+		}
+		return deliveries[i].NextAttempt.Before(deliveries[j].NextAttempt)
+	})
+}
@@ -0,0 +1,376 @@
+// Package webhook delivers SSHTrust events to HTTP receivers. Payloads are
+// signed with HMAC-SHA256, queued before they are sent and retried with
+// exponential backoff until the receiver accepts them.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Event types
+const (
+	CACreated       = "ca.created"
+	CARotated       = "ca.rotated"
+	CertIssued      = "cert.issued"
+	CertRevoked     = "cert.revoked"
+	CertBreakGlass  = "cert.breakglass"
+	LoginFailed     = "login.failed"
+	RequestCreated  = "request.created"
+	RequestApproved = "request.approved"
+	RequestDenied   = "request.denied"
+	WebhookTest     = "webhook.test"
+)
+
+// Headers sent with each delivery. The signature is HMAC-SHA256 of
+// "<timestamp>.<body>" with the hook's secret, as "sha256=<hex>".
+const (
+	SignatureHeader  = "X-SSHTrust-Signature"
+	TimestampHeader  = "X-SSHTrust-Timestamp"
+	EventHeader      = "X-SSHTrust-Event"
+	DeliveryIDHeader = "X-SSHTrust-Delivery"
+)
+
+const (
+	// MaxAttempts is how often a delivery is tried before it is dropped
+	MaxAttempts = 10
+	// MaxBackoff is the longest wait between attempts
+	MaxBackoff = 30 * time.Minute
+)
+
+// Hook is a receiver of events
+type Hook struct {
+	URL string `json:"url" yaml:"url"`
+	// Key for the payload signature
+	Secret string `json:"-" yaml:"secret"`
+	// Event types sent to the hook, all when empty
+	Events []string `json:"events,omitempty" yaml:"events,omitempty"`
+}
+
+// Wants reports whether the hook receives events of eventType
+func (h Hook) Wants(eventType string) bool {
+	if len(h.Events) == 0 || eventType == WebhookTest {
+		return true
+	}
+	for _, wanted := range h.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is the payload sent to receivers
+type Event struct {
+	ID   string    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	Data any       `json:"data"`
+}
+
+// Delivery is an event waiting to be sent to one hook
+type Delivery struct {
+	ID          string          `json:"id"`
+	URL         string          `json:"url"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Attempts    int             `json:"attempts"`
+	NextAttempt time.Time       `json:"next_attempt"`
+	LastError   string          `json:"last_error,omitempty"`
+}
+
+// Result is the outcome of sending one delivery
+type Result struct {
+	URL    string `json:"url"`
+	Status int    `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Sign returns the signature header value for body sent at timestamp. The
+// signature covers "<timestamp>.<body>" so payloads can not be replayed
+// with a fresh timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a received payload's signature header, for
+// receivers written in Go. Payloads older than maxAge are refused.
+func VerifySignature(secret string, header http.Header, body []byte, maxAge time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(TimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s header", TimestampHeader)
+	}
+	if time.Since(time.Unix(timestamp, 0)).Abs() > maxAge {
+		return fmt.Errorf("webhook payload is too old")
+	}
+	if !hmac.Equal([]byte(header.Get(SignatureHeader)), []byte(Sign(secret, timestamp, body))) {
+		return fmt.Errorf("invalid webhook signature")
+	}
+	return nil
+}
+
+// Dispatcher queues events for the hooks that want them and delivers them
+// in the background. A nil Dispatcher drops events.
+type Dispatcher struct {
+	hooks  []Hook
+	queue  Queue
+	client *http.Client
+	logger *log.Logger
+	// First retry waits this long, doubling for each attempt after
+	retryBase time.Duration
+	now       func() time.Time
+	wake      chan struct{}
+	// Hooks being delivered to, one round at a time for each
+	mu   sync.Mutex
+	busy map[string]bool
+	// Delivery rounds still running
+	rounds sync.WaitGroup
+}
+
+// NewDispatcher returns a dispatcher for hooks, queueing deliveries on queue
+func NewDispatcher(hooks []Hook, queue Queue) *Dispatcher {
+	d := &Dispatcher{
+		hooks:     hooks,
+		queue:     queue,
+		client:    &http.Client{Timeout: 10 * time.Second},
+		logger:    log.Default(),
+		retryBase: 5 * time.Second,
+		now:       time.Now,
+		wake:      make(chan struct{}, 1),
+		busy:      map[string]bool{},
+	}
+	return d
+}
+
+// Hooks returns the configured hooks
+func (d *Dispatcher) Hooks() []Hook {
+	if d == nil {
+		return nil
+	}
+	return d.hooks
+}
+
+// hook returns the configured hook for url
+func (d *Dispatcher) hook(url string) (Hook, bool) {
+	for _, hook := range d.hooks {
+		if hook.URL == url {
+			return hook, true
+		}
+	}
+	return Hook{}, false
+}
+
+// Publish queues an event of eventType for every hook that wants it
+func (d *Dispatcher) Publish(eventType string, data any) error {
+	if d == nil {
+		return nil
+	}
+	deliveries, err := d.deliveries(eventType, data)
+	if err != nil {
+		return err
+	}
+	for _, delivery := range deliveries {
+		if err := d.queue.Put(delivery); err != nil {
+			return fmt.Errorf("failed to queue webhook delivery: %w", err)
+		}
+	}
+	if len(deliveries) > 0 {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Test sends a webhook.test event to every hook straight away, without
+// queueing or retrying it
+func (d *Dispatcher) Test(ctx context.Context) ([]Result, error) {
+	if d == nil {
+		return []Result{}, nil
+	}
+	deliveries, err := d.deliveries(WebhookTest, map[string]string{"message": "SSHTrust webhook test"})
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		results = append(results, d.send(ctx, delivery))
+	}
+	return results, nil
+}
+
+func (d *Dispatcher) deliveries(eventType string, data any) ([]Delivery, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := json.Marshal(Event{ID: id, Type: eventType, Time: d.now().UTC(), Data: data})
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []Delivery{}
+	for _, hook := range d.hooks {
+		if !hook.Wants(eventType) {
+			continue
+		}
+		deliveryID, err := newID()
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, Delivery{ID: deliveryID, URL: hook.URL, Type: eventType, Payload: payload, NextAttempt: d.now()})
+	}
+	return deliveries, nil
+}
+
+// Run delivers queued events until ctx is done, checking the queue every
+// interval and whenever an event is published
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			d.rounds.Wait()
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// deliverDue starts a round for each hook with deliveries due, unless its
+// last round is still running. Hooks are delivered to in parallel, so a
+// receiver that is down does not hold up the others; wait for the rounds
+// with d.rounds.
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	deliveries, err := d.queue.List()
+	if err != nil {
+		d.logger.Printf("webhook: failed to read queue: %v", err)
+		return
+	}
+	due := map[string][]Delivery{}
+	for _, delivery := range deliveries {
+		if delivery.NextAttempt.After(d.now()) {
+			continue
+		}
+		if _, ok := d.hook(delivery.URL); !ok {
+			d.logger.Printf("webhook: dropping %s delivery %s, %s is no longer configured", delivery.Type, delivery.ID, delivery.URL)
+			d.remove(delivery)
+			continue
+		}
+		due[delivery.URL] = append(due[delivery.URL], delivery)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for url, deliveries := range due {
+		if d.busy[url] {
+			continue
+		}
+		d.busy[url] = true
+		d.rounds.Add(1)
+		go func() {
+			defer d.rounds.Done()
+			d.deliverRound(ctx, deliveries)
+			d.mu.Lock()
+			delete(d.busy, url)
+			d.mu.Unlock()
+		}()
+	}
+}
+
+// deliverRound sends one hook's due deliveries in order, rescheduling the
+// failures with backoff and dropping those out of attempts
+func (d *Dispatcher) deliverRound(ctx context.Context, deliveries []Delivery) {
+	for _, delivery := range deliveries {
+		if ctx.Err() != nil {
+			return
+		}
+		result := d.send(ctx, delivery)
+		if result.Error == "" {
+			d.remove(delivery)
+			continue
+		}
+		delivery.Attempts++
+		delivery.LastError = result.Error
+		if delivery.Attempts >= MaxAttempts {
+			d.logger.Printf("webhook: giving up on %s delivery %s to %s after %d attempts: %s", delivery.Type, delivery.ID, delivery.URL, delivery.Attempts, result.Error)
+			d.remove(delivery)
+			continue
+		}
+		delivery.NextAttempt = d.now().Add(d.backoff(delivery.Attempts))
+		if err := d.queue.Put(delivery); err != nil {
+			d.logger.Printf("webhook: failed to reschedule delivery %s: %v", delivery.ID, err)
+		}
+	}
+}
+
+// backoff returns the wait after attempts failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.retryBase
+	for i := 1; i < attempts && wait < MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, MaxBackoff)
+}
+
+func (d *Dispatcher) remove(delivery Delivery) {
+	if err := d.queue.Remove(delivery.ID); err != nil {
+		d.logger.Printf("webhook: failed to remove delivery %s: %v", delivery.ID, err)
+	}
+}
+
+// send posts one delivery, signed with its hook's secret. Receivers accept
+// it with any 2xx status.
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) Result {
+	result := Result{URL: delivery.URL}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	timestamp := d.now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "SSHTrust-Webhook")
+	req.Header.Set(EventHeader, delivery.Type)
+	req.Header.Set(DeliveryIDHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	hook, _ := d.hook(delivery.URL)
+	req.Header.Set(SignatureHeader, Sign(hook.Secret, timestamp, delivery.Payload))
+	resp, err := d.client.Do(req)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	result.Status = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		result.Error = "receiver returned " + resp.Status
+	}
+	return result
+}
+
+func newID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b[:]), nil
+}
@@ -0,0 +1,275 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// receiver is a local webhook receiver answering with the queued statuses,
+// then 200
+type receiver struct {
+	sync.Mutex
+	statuses []int
+	received []Event
+	headers  []http.Header
+	bodies   [][]byte
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.Lock()
+	defer r.Unlock()
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	if status == http.StatusOK {
+		body, _ := io.ReadAll(req.Body)
+		var event Event
+		_ = json.Unmarshal(body, &event)
+		r.received = append(r.received, event)
+		r.headers = append(r.headers, req.Header.Clone())
+		r.bodies = append(r.bodies, body)
+	}
+	w.WriteHeader(status)
+}
+
+// testDispatcher returns a dispatcher with a clock the test moves
+func testDispatcher(hooks []Hook, queue Queue) (*Dispatcher, *time.Time) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	d := NewDispatcher(hooks, queue)
+	d.now = func() time.Time { return now }
+	d.logger = log.New(io.Discard, "", 0)
+	return d, &now
+}
+
+// deliver runs a delivery round and waits for it
+func deliver(d *Dispatcher) {
+	d.deliverDue(context.Background())
+	d.rounds.Wait()
+}
+
+func TestDeliverSigned(t *testing.T) {
+	rec := &receiver{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+	other := &receiver{}
+	otherTS := httptest.NewServer(other)
+	defer otherTS.Close()
+
+	d, now := testDispatcher([]Hook{
+		{URL: ts.URL, Secret: "s3cret"},
+		{URL: otherTS.URL, Events: []string{CertRevoked}},
+	}, NewMemoryQueue())
+	assert.NoError(t, d.Publish(CertIssued, map[string]string{"ca": "prod-ca"}))
+	deliver(d)
+
+	if assert.Len(t, rec.received, 1) {
+		assert.Equal(t, CertIssued, rec.received[0].Type)
+		assert.Equal(t, map[string]any{"ca": "prod-ca"}, rec.received[0].Data)
+		assert.Equal(t, CertIssued, rec.headers[0].Get(EventHeader))
+		assert.Equal(t, "application/json", rec.headers[0].Get("Content-Type"))
+		// Signed over the timestamp and body
+		assert.Equal(t, Sign("s3cret", now.Unix(), rec.bodies[0]), rec.headers[0].Get(SignatureHeader))
+		assert.ErrorContains(t, VerifySignature("wrong", rec.headers[0], rec.bodies[0], time.Hour*24*365*10), "invalid webhook signature")
+	}
+	// Only sent the events the hook asked for
+	assert.Empty(t, other.received)
+	deliveries, _ := d.queue.List()
+	assert.Empty(t, deliveries)
+}
+
+func TestSlowReceiverDoesNotHoldUpOthers(t *testing.T) {
+	release := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer slow.Close()
+	rec := &receiver{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+	received := func(n int) func() bool {
+		return func() bool {
+			rec.Lock()
+			defer rec.Unlock()
+			return len(rec.received) == n
+		}
+	}
+
+	d, _ := testDispatcher([]Hook{{URL: slow.URL}, {URL: ts.URL}}, NewMemoryQueue())
+	assert.NoError(t, d.Publish(CertBreakGlass, nil))
+	d.deliverDue(context.Background())
+	assert.Eventually(t, received(1), 5*time.Second, 10*time.Millisecond)
+
+	// The slow hook's round is still running, later rounds leave it be
+	assert.NoError(t, d.Publish(CertBreakGlass, nil))
+	d.deliverDue(context.Background())
+	assert.Eventually(t, received(2), 5*time.Second, 10*time.Millisecond)
+
+	close(release)
+	d.rounds.Wait()
+	deliveries, _ := d.queue.List()
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, slow.URL, deliveries[0].URL)
+		assert.Zero(t, deliveries[0].Attempts)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"type":"cert.issued"}`)
+	header := http.Header{}
+	timestamp := time.Now().Unix()
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	header.Set(SignatureHeader, Sign("s3cret", timestamp, body))
+	assert.NoError(t, VerifySignature("s3cret", header, body, time.Minute))
+	assert.Error(t, VerifySignature("s3cret", header, []byte(`{"type":"cert.revoked"}`), time.Minute))
+
+	header.Set(TimestampHeader, strconv.FormatInt(timestamp-3600, 10))
+	header.Set(SignatureHeader, Sign("s3cret", timestamp-3600, body))
+	assert.ErrorContains(t, VerifySignature("s3cret", header, body, time.Minute), "too old")
+}
+
+func TestRetryWithBackoff(t *testing.T) {
+	rec := &receiver{statuses: []int{http.StatusInternalServerError, http.StatusBadGateway}}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+	d, now := testDispatcher([]Hook{{URL: ts.URL}}, NewMemoryQueue())
+	assert.NoError(t, d.Publish(CACreated, nil))
+
+	deliver(d)
+	deliveries, _ := d.queue.List()
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, "receiver returned 500 Internal Server Error", deliveries[0].LastError)
+		assert.Equal(t, now.Add(5*time.Second), deliveries[0].NextAttempt)
+	}
+	// Not due yet
+	deliver(d)
+	deliveries, _ = d.queue.List()
+	assert.Equal(t, 1, deliveries[0].Attempts)
+
+	*now = now.Add(5 * time.Second)
+	deliver(d)
+	deliveries, _ = d.queue.List()
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, 2, deliveries[0].Attempts)
+		assert.Equal(t, now.Add(10*time.Second), deliveries[0].NextAttempt)
+	}
+
+	*now = now.Add(10 * time.Second)
+	deliver(d)
+	deliveries, _ = d.queue.List()
+	assert.Empty(t, deliveries)
+	assert.Len(t, rec.received, 1)
+}
+
+func TestGiveUpAfterMaxAttempts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+	d, now := testDispatcher([]Hook{{URL: ts.URL}}, NewMemoryQueue())
+	assert.NoError(t, d.Publish(CACreated, nil))
+	for i := 0; i < MaxAttempts; i++ {
+		deliver(d)
+		*now = now.Add(MaxBackoff)
+	}
+	deliveries, _ := d.queue.List()
+	assert.Empty(t, deliveries)
+	assert.Equal(t, MaxBackoff, d.backoff(MaxAttempts))
+}
+
+func TestFileQueueSurvivesRestart(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "queue")
+	// The receiver is down when the event is published
+	rec := &receiver{statuses: []int{http.StatusServiceUnavailable}}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+
+	queue, err := NewFileQueue(dir)
+	assert.NoError(t, err)
+	d, now := testDispatcher([]Hook{{URL: ts.URL}}, queue)
+	assert.NoError(t, d.Publish(LoginFailed, map[string]string{"actor": "mallory"}))
+	deliver(d)
+
+	// The server restarts with a new queue on the same directory
+	queue, err = NewFileQueue(dir)
+	assert.NoError(t, err)
+	deliveries, err := queue.List()
+	assert.NoError(t, err)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, 1, deliveries[0].Attempts)
+	}
+	d, restartedNow := testDispatcher([]Hook{{URL: ts.URL}}, queue)
+	*restartedNow = now.Add(time.Minute)
+	deliver(d)
+	if assert.Len(t, rec.received, 1) {
+		assert.Equal(t, LoginFailed, rec.received[0].Type)
+	}
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestTestSendsToEveryHook(t *testing.T) {
+	rec := &receiver{}
+	ts := httptest.NewServer(rec)
+	defer ts.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer failing.Close()
+
+	d, _ := testDispatcher([]Hook{{URL: ts.URL, Events: []string{CertRevoked}}, {URL: failing.URL}}, NewMemoryQueue())
+	results, err := d.Test(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []Result{
+		{URL: ts.URL, Status: http.StatusOK},
+		{URL: failing.URL, Status: http.StatusForbidden, Error: "receiver returned 403 Forbidden"},
+	}, results)
+	if assert.Len(t, rec.received, 1) {
+		assert.Equal(t, WebhookTest, rec.received[0].Type)
+	}
+	// Tests are not queued for retry
+	deliveries, _ := d.queue.List()
+	assert.Empty(t, deliveries)
+}
+
+func TestLoadHooks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "webhooks.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`hooks:
+  - url: https://chat.example.com/hooks/sshtrust
+    secret: s3cret
+    events: [cert.breakglass, login.failed]
+  - url: https://siem.example.com/ingest
+    secret: s3cret-too
+`), 0o600))
+	hooks, err := LoadHooks(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []Hook{
+		{URL: "https://chat.example.com/hooks/sshtrust", Secret: "s3cret", Events: []string{CertBreakGlass, LoginFailed}},
+		{URL: "https://siem.example.com/ingest", Secret: "s3cret-too"},
+	}, hooks)
+
+	for _, bad := range []string{
+		"hooks:\n  - url: ftp://example.com\n    secret: s3cret\n",
+		"hooks:\n  - url: https://example.com\n    secret: s3cret\n    events: [cert.stolen]\n",
+		"hooks:\n  - url: https://example.com\n    secret: s3cret\n  - url: https://example.com\n    secret: s3cret\n",
+		"hooks:\n  - url: https://example.com\n",
+		"hooks:\n  - url: https://example.com\n    secret: s3cret\n    events: [ca.deleted]\n",
+	} {
+		assert.NoError(t, os.WriteFile(path, []byte(bad), 0o600))
+		_, err := LoadHooks(path)
+		assert.Error(t, err, bad)
+	}
+}